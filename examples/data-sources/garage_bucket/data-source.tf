@@ -0,0 +1,3 @@
+data "garage_bucket" "bucket" {
+  global_alias = "bucket"
+}
@@ -0,0 +1,8 @@
+data "garage_bucket_object" "manifest" {
+  bucket = "releases"
+  key    = "manifest.json"
+}
+
+locals {
+  release = jsondecode(data.garage_bucket_object.manifest.body)
+}
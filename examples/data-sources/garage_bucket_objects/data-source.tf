@@ -0,0 +1,11 @@
+data "garage_bucket_objects" "manifests" {
+  bucket = "releases"
+  prefix = "manifests/"
+}
+
+data "garage_bucket_object" "manifest" {
+  for_each = toset(data.garage_bucket_objects.manifests.keys)
+
+  bucket = "releases"
+  key    = each.key
+}
@@ -0,0 +1 @@
+data "garage_cluster_health" "health" {}
@@ -0,0 +1,9 @@
+data "garage_k2v_index" "tenants" {
+  bucket        = "app"
+  access_key_id = garage_key.reader.access_key_id
+  prefix        = "tenant/"
+}
+
+output "tenant_item_counts" {
+  value = { for partition in data.garage_k2v_index.tenants.partitions : partition.partition_key => partition.entries }
+}
@@ -0,0 +1,10 @@
+data "garage_k2v_item" "feature_flags" {
+  bucket        = "app"
+  access_key_id = garage_key.reader.access_key_id
+  partition_key = "config"
+  sort_key      = "feature-flags"
+}
+
+output "new_dashboard" {
+  value = jsondecode(data.garage_k2v_item.feature_flags.value).new_dashboard
+}
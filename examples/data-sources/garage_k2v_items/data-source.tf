@@ -0,0 +1,9 @@
+# The 10 latest reports of 2024
+data "garage_k2v_items" "reports" {
+  bucket        = "app"
+  access_key_id = garage_key.reader.access_key_id
+  partition_key = "reports"
+  prefix        = "2024-"
+  reverse       = true
+  limit         = 10
+}
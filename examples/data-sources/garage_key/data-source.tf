@@ -0,0 +1,3 @@
+data "garage_key" "key" {
+  search = "key"
+}
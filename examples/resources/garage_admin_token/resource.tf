@@ -0,0 +1,5 @@
+resource "garage_admin_token" "monitoring" {
+  name       = "monitoring"
+  scope      = ["GetClusterHealth", "GetClusterStatus"]
+  expiration = "2027-01-01T00:00:00Z"
+}
@@ -0,0 +1,36 @@
+resource "garage_bucket" "assets" {}
+
+resource "garage_bucket_global_alias" "assets" {
+  bucket_id = garage_bucket.assets.id
+  alias     = "assets"
+}
+
+resource "garage_key" "admin" {
+  name = "assets-admin"
+}
+
+resource "garage_bucket_key" "admin" {
+  bucket_id     = garage_bucket.assets.id
+  access_key_id = garage_key.admin.access_key_id
+  read          = true
+  owner         = true
+}
+
+resource "garage_bucket_cors" "assets" {
+  bucket        = garage_bucket_global_alias.assets.alias
+  access_key_id = garage_bucket_key.admin.access_key_id
+
+  cors_rule {
+    id              = "app"
+    allowed_origins = ["https://app.example.com"]
+    allowed_methods = ["GET", "PUT"]
+    allowed_headers = ["*"]
+    expose_headers  = ["ETag"]
+    max_age_seconds = 3600
+  }
+
+  cors_rule {
+    allowed_origins = ["*"]
+    allowed_methods = ["GET", "HEAD"]
+  }
+}
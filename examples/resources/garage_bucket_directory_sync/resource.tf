@@ -0,0 +1,31 @@
+resource "garage_bucket" "website" {
+  website_access_enabled        = true
+  website_config_index_document = "index.html"
+}
+
+resource "garage_bucket_global_alias" "website" {
+  bucket_id = garage_bucket.website.id
+  alias     = "website"
+}
+
+resource "garage_key" "deploy" {
+  name = "deploy"
+}
+
+resource "garage_bucket_key" "deploy" {
+  bucket_id     = garage_bucket.website.id
+  access_key_id = garage_key.deploy.access_key_id
+  read          = true
+  write         = true
+}
+
+resource "garage_bucket_directory_sync" "website" {
+  bucket         = garage_bucket_global_alias.website.alias
+  access_key_id  = garage_bucket_key.deploy.access_key_id
+  source_dir     = "${path.module}/public"
+  delete_orphans = true
+  cache_control  = "max-age=300"
+  content_types = {
+    ".webmanifest" = "application/manifest+json"
+  }
+}
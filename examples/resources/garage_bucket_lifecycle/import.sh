@@ -0,0 +1,3 @@
+# The lifecycle configuration of a bucket is imported by its bucket ID or global alias.
+# Unless s3_access_key_id is set on the provider, it is read with a key granted on the bucket.
+terraform import garage_bucket_lifecycle.logs logs
@@ -0,0 +1,40 @@
+resource "garage_bucket" "logs" {}
+
+resource "garage_bucket_global_alias" "logs" {
+  bucket_id = garage_bucket.logs.id
+  alias     = "logs"
+}
+
+resource "garage_key" "admin" {
+  name = "logs-admin"
+}
+
+resource "garage_bucket_key" "admin" {
+  bucket_id     = garage_bucket.logs.id
+  access_key_id = garage_key.admin.access_key_id
+  read          = true
+  owner         = true
+}
+
+resource "garage_bucket_lifecycle" "logs" {
+  bucket        = garage_bucket_global_alias.logs.alias
+  access_key_id = garage_bucket_key.admin.access_key_id
+
+  rule {
+    id              = "expire-access-logs"
+    prefix          = "access/"
+    expiration_days = 90
+  }
+
+  rule {
+    id                       = "expire-large-debug-dumps"
+    prefix                   = "debug/"
+    object_size_greater_than = 104857600
+    expiration_days          = 7
+  }
+
+  rule {
+    id                                     = "abort-unfinished-uploads"
+    abort_incomplete_multipart_upload_days = 1
+  }
+}
@@ -0,0 +1,3 @@
+# Objects are imported by the bucket ID or global alias and the key, separated by a slash.
+# Unless s3_access_key_id is set on the provider, they are read with a key granted on the bucket.
+terraform import garage_bucket_object.robots website/robots.txt
@@ -0,0 +1,47 @@
+resource "garage_bucket" "site" {
+  manage_website = false
+}
+
+resource "garage_bucket_global_alias" "site" {
+  bucket_id = garage_bucket.site.id
+  alias     = "example.com"
+}
+
+resource "garage_key" "admin" {
+  name = "site-admin"
+}
+
+resource "garage_bucket_key" "admin" {
+  bucket_id     = garage_bucket.site.id
+  access_key_id = garage_key.admin.access_key_id
+  read          = true
+  owner         = true
+}
+
+resource "garage_bucket_website" "site" {
+  bucket         = garage_bucket_global_alias.site.alias
+  access_key_id  = garage_bucket_key.admin.access_key_id
+  index_document = "index.html"
+  error_document = "404.html"
+
+  # Moved pages
+  routing_rule {
+    condition {
+      key_prefix_equals = "blog/"
+    }
+    redirect {
+      replace_key_prefix_with = "posts/"
+    }
+  }
+
+  # Single-page application routes
+  routing_rule {
+    condition {
+      key_prefix_equals = "app/"
+    }
+    redirect {
+      replace_key_with   = "app/index.html"
+      http_redirect_code = 200
+    }
+  }
+}
@@ -0,0 +1,8 @@
+resource "garage_cluster_layout" "layout" {
+  role {
+    node_id  = "563e1ac825ee3323aa441e72c26d1030d6d4414aeb3dd25287c531e7fc2bc95d"
+    zone     = "dc1"
+    capacity = 1000000000 // bytes, omit for gateway nodes
+    tags     = ["node1"]
+  }
+}
@@ -0,0 +1,3 @@
+# K2V items are imported by bucket ID or global alias, partition key and sort key, joined by slashes.
+# The sort key may contain slashes, but not the partition key. Requests are signed with a key granted on the bucket.
+terraform import garage_k2v_item.feature_flags app/config/feature-flags
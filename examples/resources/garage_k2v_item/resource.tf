@@ -0,0 +1,28 @@
+resource "garage_bucket" "app" {}
+
+resource "garage_bucket_global_alias" "app" {
+  bucket_id = garage_bucket.app.id
+  alias     = "app"
+}
+
+resource "garage_key" "bootstrap" {
+  name = "app-bootstrap"
+}
+
+resource "garage_bucket_key" "bootstrap" {
+  bucket_id     = garage_bucket.app.id
+  access_key_id = garage_key.bootstrap.access_key_id
+  read          = true
+  write         = true
+}
+
+resource "garage_k2v_item" "feature_flags" {
+  bucket        = garage_bucket_global_alias.app.alias
+  access_key_id = garage_bucket_key.bootstrap.access_key_id
+  partition_key = "config"
+  sort_key      = "feature-flags"
+  value = jsonencode({
+    new_dashboard = true
+    beta_api      = false
+  })
+}
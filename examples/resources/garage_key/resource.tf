@@ -4,3 +4,9 @@ resource "garage_key" "key" {
     create_bucket = true // defaults to false
   }
 }
+
+// Short-lived key for CI, which stops working on its own
+resource "garage_key" "ci" {
+  name       = "ci"
+  expiration = "2027-01-01T00:00:00Z"
+}
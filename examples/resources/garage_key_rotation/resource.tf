@@ -0,0 +1,15 @@
+resource "garage_bucket" "artifacts" {}
+
+// A new key replaces the current one every 30 days, and the previous one
+// keeps working for a week after that.
+resource "garage_key_rotation" "ci" {
+  name            = "ci"
+  rotation_period = "720h"
+  overlap_period  = "168h"
+
+  bucket {
+    bucket_id = garage_bucket.artifacts.id
+    read      = true
+    write     = true
+  }
+}
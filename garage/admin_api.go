@@ -0,0 +1,71 @@
+package garage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Admin API versions the provider speaks, from the most recent one.
+var adminAPIVersions = []int{2, 1, 0}
+
+// parseAPIVersion parses admin API versions written as "v1".
+func parseAPIVersion(apiVersion string) int {
+	parsed, _ := strconv.Atoi(strings.TrimPrefix(apiVersion, "v"))
+	return parsed
+}
+
+// clusterStatus is the part of the node status the provider uses.
+type clusterStatus struct {
+	Node          string `json:"node"`
+	GarageVersion string `json:"garageVersion"`
+}
+
+// nodeInfoResponse is the answer of the v2 GetNodeInfo endpoint, by node ID.
+type nodeInfoResponse struct {
+	Success map[string]clusterStatus `json:"success"`
+	Error   map[string]string        `json:"error"`
+}
+
+type updateClusterLayoutRequest struct {
+	Roles []nodeRoleChange `json:"roles"`
+}
+
+type applyClusterLayoutRequest struct {
+	Version int64 `json:"version"`
+}
+
+type applyClusterLayoutResponse struct {
+	Message []string      `json:"message"`
+	Layout  clusterLayout `json:"layout"`
+}
+
+// isUnknownEndpoint reports whether the admin API answered that it doesn't
+// serve the requested endpoint, as nodes do for API versions they don't speak.
+func isUnknownEndpoint(httpResp *http.Response) bool {
+	return httpResp != nil && (httpResp.StatusCode == http.StatusNotFound || httpResp.StatusCode == http.StatusBadRequest)
+}
+
+// getClusterStatus fetches the status of the node answering the admin API,
+// used to check connectivity before the provider picks its client.
+func getClusterStatus(ctx context.Context, admin *adminClient) (*clusterStatus, *http.Response, error) {
+	if admin.apiVersion < 2 {
+		status := &clusterStatus{}
+		httpResp, err := admin.call(ctx, http.MethodGet, "/status", nil, nil, status)
+		return status, httpResp, err
+	}
+
+	nodeInfo := &nodeInfoResponse{}
+	query := url.Values{
+		"node": {"self"},
+	}
+	httpResp, err := admin.call(ctx, http.MethodGet, "/GetNodeInfo", query, nil, nodeInfo)
+	status := &clusterStatus{}
+	for node, info := range nodeInfo.Success {
+		status.Node = node
+		status.GarageVersion = info.GarageVersion
+	}
+	return status, httpResp, err
+}
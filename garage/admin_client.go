@@ -0,0 +1,102 @@
+package garage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// adminClient calls the admin API endpoints the SDK, generated for the v0
+// API, doesn't know about.
+type adminClient struct {
+	endpoint   string
+	apiVersion int
+	httpClient *http.Client
+	headers    map[string]string
+	token      string
+}
+
+// adminError is an error answered by the admin API.
+type adminError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *adminError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("admin API answered %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("admin API answered %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// withAPIVersion returns a copy of c calling the given version of the API.
+func (c *adminClient) withAPIVersion(apiVersion int) *adminClient {
+	client := *c
+	client.apiVersion = apiVersion
+	return &client
+}
+
+// call sends body, if any, as JSON to path of the API and decodes the answer
+// into out, if any.
+func (c *adminClient) call(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) (*http.Response, error) {
+	requestURL := fmt.Sprintf("%s/v%d%s", c.endpoint, c.apiVersion, path)
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	var requestBody io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		requestBody = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	content, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return httpResp, err
+	}
+
+	if httpResp.StatusCode >= 300 {
+		apiErr := &adminError{}
+		_ = json.Unmarshal(content, apiErr)
+		apiErr.StatusCode = httpResp.StatusCode
+		if apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(content))
+		}
+		return httpResp, apiErr
+	}
+
+	if out != nil && len(bytes.TrimSpace(content)) > 0 {
+		if err := json.Unmarshal(content, out); err != nil {
+			return httpResp, fmt.Errorf("failed to decode admin API answer: %w", err)
+		}
+	}
+
+	return httpResp, nil
+}
@@ -0,0 +1,217 @@
+package garage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// garageClient is the part of the Garage admin API the resources use, whatever
+// the API version the provider speaks. Implementations return *adminError for
+// errors answered by the API.
+type garageClient interface {
+	ListBuckets(ctx context.Context) ([]bucketListItem, error)
+	GetBucket(ctx context.Context, bucketID string) (*bucket, error)
+	FindBucket(ctx context.Context, globalAlias string) (*bucket, error)
+	CreateBucket(ctx context.Context) (*bucket, error)
+	SetBucketWebsite(ctx context.Context, bucketID string, website bucketWebsite) (*bucket, error)
+	SetBucketQuotas(ctx context.Context, bucketID string, quotas bucketQuotas) (*bucket, error)
+	DeleteBucket(ctx context.Context, bucketID string) error
+
+	AddBucketGlobalAlias(ctx context.Context, bucketID string, alias string) error
+	RemoveBucketGlobalAlias(ctx context.Context, bucketID string, alias string) error
+	AddBucketLocalAlias(ctx context.Context, bucketID string, accessKeyID string, alias string) error
+	RemoveBucketLocalAlias(ctx context.Context, bucketID string, accessKeyID string, alias string) error
+
+	// GrantKey and RevokeKey only change the permissions set in permissions.
+	GrantKey(ctx context.Context, bucketID string, accessKeyID string, permissions bucketKeyPermissions) error
+	RevokeKey(ctx context.Context, bucketID string, accessKeyID string, permissions bucketKeyPermissions) error
+
+	ListKeys(ctx context.Context) ([]keyListItem, error)
+	GetKey(ctx context.Context, accessKeyID string) (*accessKey, error)
+	SearchKey(ctx context.Context, pattern string) (*accessKey, error)
+	CreateKey(ctx context.Context, name string) (*accessKey, error)
+	ImportKey(ctx context.Context, name string, accessKeyID string, secretAccessKey string) (*accessKey, error)
+	UpdateKey(ctx context.Context, accessKeyID string, update keyUpdate) (*accessKey, error)
+	DeleteKey(ctx context.Context, accessKeyID string) error
+
+	GetClusterHealth(ctx context.Context) (*clusterHealth, error)
+	GetClusterLayout(ctx context.Context) (*clusterLayout, error)
+	StageClusterLayoutChanges(ctx context.Context, changes []nodeRoleChange) (*clusterLayout, error)
+	// ApplyClusterLayout applies the staged role changes as the given layout
+	// version, which must be the current one plus one.
+	ApplyClusterLayout(ctx context.Context, version int64) (*clusterLayout, error)
+
+	GetAdminToken(ctx context.Context, id string) (*adminToken, error)
+	CreateAdminToken(ctx context.Context, update adminTokenUpdate) (*adminToken, error)
+	UpdateAdminToken(ctx context.Context, id string, update adminTokenUpdate) (*adminToken, error)
+	DeleteAdminToken(ctx context.Context, id string) error
+}
+
+// newGarageClient returns the client speaking the API version of admin.
+func newGarageClient(admin *adminClient) garageClient {
+	if admin.apiVersion >= 2 {
+		return &v2Client{admin: admin}
+	}
+	return newSDKClient(admin)
+}
+
+// isNotFound reports whether err is the admin API answering that the object
+// doesn't exist.
+func isNotFound(err error) bool {
+	var apiErr *adminError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// The types below are named after the JSON documents of the v1 and v2 APIs,
+// which the v2 client decodes directly.
+
+type bucket struct {
+	ID            string               `json:"id"`
+	GlobalAliases []string             `json:"globalAliases"`
+	WebsiteAccess bool                 `json:"websiteAccess"`
+	WebsiteConfig *bucketWebsiteConfig `json:"websiteConfig"`
+	Keys          []bucketKey          `json:"keys"`
+	Objects       int64                `json:"objects"`
+	Bytes         int64                `json:"bytes"`
+	// UnfinishedUploads counts unfinished uploads of any kind.
+	UnfinishedUploads int64        `json:"unfinishedUploads"`
+	Quotas            bucketQuotas `json:"quotas"`
+
+	// Only reported by the v2 API.
+	Created                        string `json:"created"`
+	UnfinishedMultipartUploads     int64  `json:"unfinishedMultipartUploads"`
+	UnfinishedMultipartUploadParts int64  `json:"unfinishedMultipartUploadParts"`
+	UnfinishedMultipartUploadBytes int64  `json:"unfinishedMultipartUploadBytes"`
+}
+
+type bucketWebsiteConfig struct {
+	IndexDocument string  `json:"indexDocument"`
+	ErrorDocument *string `json:"errorDocument"`
+}
+
+// bucketWebsite is the website configuration to set on a bucket.
+type bucketWebsite struct {
+	Enabled       bool    `json:"enabled"`
+	IndexDocument *string `json:"indexDocument,omitempty"`
+	ErrorDocument *string `json:"errorDocument,omitempty"`
+}
+
+// bucketQuotas are the quotas of a bucket, nil when unlimited.
+type bucketQuotas struct {
+	MaxSize    *int64 `json:"maxSize"`
+	MaxObjects *int64 `json:"maxObjects"`
+}
+
+// bucketKey is a key allowed on a bucket.
+type bucketKey struct {
+	AccessKeyID        string               `json:"accessKeyId"`
+	Name               string               `json:"name"`
+	Permissions        bucketKeyPermissions `json:"permissions"`
+	BucketLocalAliases []string             `json:"bucketLocalAliases"`
+}
+
+type bucketKeyPermissions struct {
+	Read  bool `json:"read"`
+	Write bool `json:"write"`
+	Owner bool `json:"owner"`
+}
+
+type bucketListItem struct {
+	ID            string             `json:"id"`
+	GlobalAliases []string           `json:"globalAliases"`
+	LocalAliases  []bucketLocalAlias `json:"localAliases"`
+}
+
+type bucketLocalAlias struct {
+	AccessKeyID string `json:"accessKeyId"`
+	Alias       string `json:"alias"`
+}
+
+// accessKey is a key of the admin API. Expiration, only known to the v2 API,
+// is nil for keys which never expire.
+type accessKey struct {
+	AccessKeyID     string         `json:"accessKeyId"`
+	Name            string         `json:"name"`
+	SecretAccessKey string         `json:"secretAccessKey"`
+	Permissions     keyPermissions `json:"permissions"`
+	Expiration      *string        `json:"expiration"`
+	Expired         bool           `json:"expired"`
+}
+
+type keyPermissions struct {
+	CreateBucket bool `json:"createBucket"`
+}
+
+// keyUpdate changes the name and expiration of a key when set, and the
+// permissions it allows or denies. Only the v2 API knows of expiration.
+type keyUpdate struct {
+	Name         *string         `json:"name,omitempty"`
+	Expiration   *string         `json:"expiration,omitempty"`
+	NeverExpires bool            `json:"neverExpires,omitempty"`
+	Allow        *keyPermissions `json:"allow,omitempty"`
+	Deny         *keyPermissions `json:"deny,omitempty"`
+}
+
+type keyListItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// clusterHealth is the health of the cluster. The v2 API renamed
+// storageNodesOk to storageNodesUp.
+type clusterHealth struct {
+	Status           string `json:"status"`
+	KnownNodes       int64  `json:"knownNodes"`
+	ConnectedNodes   int64  `json:"connectedNodes"`
+	StorageNodes     int64  `json:"storageNodes"`
+	StorageNodesOk   int64  `json:"storageNodesOk"`
+	StorageNodesUp   int64  `json:"storageNodesUp"`
+	Partitions       int64  `json:"partitions"`
+	PartitionsQuorum int64  `json:"partitionsQuorum"`
+	PartitionsAllOk  int64  `json:"partitionsAllOk"`
+}
+
+type clusterLayout struct {
+	Version           int64            `json:"version"`
+	Roles             []nodeRole       `json:"roles"`
+	StagedRoleChanges []nodeRoleChange `json:"stagedRoleChanges"`
+}
+
+// nodeRole is the role of a node in the cluster layout. Gateway nodes have no
+// capacity.
+type nodeRole struct {
+	ID       string   `json:"id"`
+	Zone     string   `json:"zone"`
+	Capacity *int64   `json:"capacity"`
+	Tags     []string `json:"tags"`
+}
+
+// nodeRoleChange is a staged change of the role of a node: either a new role
+// or its removal. Tags are required by new roles, even when empty.
+type nodeRoleChange struct {
+	ID       string   `json:"id"`
+	Remove   bool     `json:"remove,omitempty"`
+	Zone     string   `json:"zone,omitempty"`
+	Capacity *int64   `json:"capacity,omitempty"`
+	Tags     []string `json:"tags"`
+}
+
+// adminToken is an admin API token of the v2 API. The secret is only
+// returned on creation.
+type adminToken struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Created     string   `json:"created"`
+	Expiration  *string  `json:"expiration"`
+	Expired     bool     `json:"expired"`
+	Scope       []string `json:"scope"`
+	SecretToken string   `json:"secretToken,omitempty"`
+}
+
+type adminTokenUpdate struct {
+	Name         string   `json:"name"`
+	Expiration   *string  `json:"expiration,omitempty"`
+	NeverExpires bool     `json:"neverExpires,omitempty"`
+	Scope        []string `json:"scope,omitempty"`
+}
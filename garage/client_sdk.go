@@ -0,0 +1,438 @@
+package garage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+)
+
+// sdkClient is the garageClient of the REST-style v0 and v1 admin APIs, which
+// share their bucket and key endpoints. It is backed by the SDK generated for
+// them, and calls the endpoints the SDK doesn't know about through the admin
+// client.
+type sdkClient struct {
+	sdk   *garage.APIClient
+	admin *adminClient
+}
+
+func newSDKClient(admin *adminClient) *sdkClient {
+	configuration := garage.NewConfiguration()
+	configuration.Servers = garage.ServerConfigurations{
+		{
+			URL: fmt.Sprintf("%s/v%d", admin.endpoint, admin.apiVersion),
+		},
+	}
+	configuration.HTTPClient = admin.httpClient
+	configuration.DefaultHeader = admin.headers
+
+	return &sdkClient{
+		sdk:   garage.NewAPIClient(configuration),
+		admin: admin,
+	}
+}
+
+// context returns ctx carrying the token for the SDK.
+func (c *sdkClient) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, garage.ContextAccessToken, c.admin.token)
+}
+
+// sdkError turns an error of the SDK into an *adminError when the API answered
+// one.
+func sdkError(httpResp *http.Response, err error) error {
+	if err == nil || httpResp == nil || httpResp.StatusCode < 300 {
+		return err
+	}
+
+	apiErr := &adminError{}
+	var openAPIErr interface{ Body() []byte }
+	if errors.As(err, &openAPIErr) {
+		_ = json.Unmarshal(openAPIErr.Body(), apiErr)
+	}
+	apiErr.StatusCode = httpResp.StatusCode
+	return apiErr
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func boolValue(value *bool) bool {
+	return value != nil && *value
+}
+
+func toInt64Ptr(value *int32) *int64 {
+	if value == nil {
+		return nil
+	}
+	converted := int64(*value)
+	return &converted
+}
+
+// toInt32Ptr converts value for the SDK, failing instead of truncating
+// values out of its range.
+func toInt32Ptr(name string, value *int64) (*int32, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if *value > math.MaxInt32 || *value < math.MinInt32 {
+		return nil, fmt.Errorf("%s %d is out of the range of the v0 and v1 admin APIs, up to %d: set admin_api_version to v2", name, *value, math.MaxInt32)
+	}
+	converted := int32(*value)
+	return &converted, nil
+}
+
+func fromSDKBucket(bucketInfo *garage.BucketInfo) *bucket {
+	b := &bucket{
+		ID:            bucketInfo.GetId(),
+		GlobalAliases: bucketInfo.GetGlobalAliases(),
+		WebsiteAccess: bucketInfo.GetWebsiteAccess(),
+	}
+
+	if bucketInfo.HasWebsiteConfig() {
+		websiteConfig := bucketInfo.GetWebsiteConfig()
+		b.WebsiteConfig = &bucketWebsiteConfig{
+			IndexDocument: stringValue(websiteConfig.IndexDocument),
+			ErrorDocument: websiteConfig.ErrorDocument,
+		}
+	}
+
+	for _, bucketKeyInfo := range bucketInfo.GetKeys() {
+		k := bucketKey{
+			AccessKeyID:        stringValue(bucketKeyInfo.AccessKeyId),
+			Name:               stringValue(bucketKeyInfo.Name),
+			BucketLocalAliases: bucketKeyInfo.BucketLocalAliases,
+		}
+		if bucketKeyInfo.Permissions != nil {
+			k.Permissions = bucketKeyPermissions{
+				Read:  boolValue(bucketKeyInfo.Permissions.Read),
+				Write: boolValue(bucketKeyInfo.Permissions.Write),
+				Owner: boolValue(bucketKeyInfo.Permissions.Owner),
+			}
+		}
+		b.Keys = append(b.Keys, k)
+	}
+
+	if bucketInfo.Objects != nil {
+		b.Objects = int64(*bucketInfo.Objects)
+	}
+	if bucketInfo.Bytes != nil {
+		b.Bytes = *bucketInfo.Bytes
+	}
+	if bucketInfo.UnfinishedUploads != nil {
+		b.UnfinishedUploads = int64(*bucketInfo.UnfinishedUploads)
+	}
+
+	if bucketInfo.HasQuotas() {
+		quotas := bucketInfo.GetQuotas()
+		b.Quotas = bucketQuotas{
+			MaxSize:    toInt64Ptr(quotas.MaxSize.Get()),
+			MaxObjects: toInt64Ptr(quotas.MaxObjects.Get()),
+		}
+	}
+
+	return b
+}
+
+func fromSDKKey(keyInfo *garage.KeyInfo) *accessKey {
+	k := &accessKey{
+		AccessKeyID:     keyInfo.GetAccessKeyId(),
+		Name:            keyInfo.GetName(),
+		SecretAccessKey: keyInfo.GetSecretAccessKey(),
+	}
+	if keyInfo.Permissions != nil {
+		k.Permissions.CreateBucket = boolValue(keyInfo.Permissions.CreateBucket)
+	}
+	return k
+}
+
+func (c *sdkClient) ListBuckets(ctx context.Context) ([]bucketListItem, error) {
+	resp, httpResp, err := c.sdk.BucketApi.ListBuckets(c.context(ctx)).Execute()
+	if err != nil {
+		return nil, sdkError(httpResp, err)
+	}
+
+	buckets := make([]bucketListItem, len(resp))
+	for i, item := range resp {
+		buckets[i] = bucketListItem{
+			ID:            item.Id,
+			GlobalAliases: item.GlobalAliases,
+		}
+		for _, localAlias := range item.LocalAliases {
+			buckets[i].LocalAliases = append(buckets[i].LocalAliases, bucketLocalAlias{
+				AccessKeyID: localAlias.AccessKeyId,
+				Alias:       localAlias.Alias,
+			})
+		}
+	}
+	return buckets, nil
+}
+
+func (c *sdkClient) GetBucket(ctx context.Context, bucketID string) (*bucket, error) {
+	bucketInfo, httpResp, err := c.sdk.BucketApi.GetBucketInfo(c.context(ctx), bucketID).Execute()
+	if err != nil {
+		return nil, sdkError(httpResp, err)
+	}
+	return fromSDKBucket(bucketInfo), nil
+}
+
+func (c *sdkClient) FindBucket(ctx context.Context, globalAlias string) (*bucket, error) {
+	bucketInfo := &bucket{}
+	query := url.Values{
+		"globalAlias": {globalAlias},
+	}
+	_, err := c.admin.call(ctx, http.MethodGet, "/bucket", query, nil, bucketInfo)
+	return bucketInfo, err
+}
+
+func (c *sdkClient) CreateBucket(ctx context.Context) (*bucket, error) {
+	bucketInfo, httpResp, err := c.sdk.BucketApi.CreateBucket(c.context(ctx)).CreateBucketRequest(garage.CreateBucketRequest{}).Execute()
+	if err != nil {
+		return nil, sdkError(httpResp, err)
+	}
+	return fromSDKBucket(bucketInfo), nil
+}
+
+func (c *sdkClient) SetBucketWebsite(ctx context.Context, bucketID string, website bucketWebsite) (*bucket, error) {
+	enabled := website.Enabled
+	return c.updateBucket(ctx, bucketID, garage.UpdateBucketRequest{
+		WebsiteAccess: &garage.UpdateBucketRequestWebsiteAccess{
+			Enabled:       &enabled,
+			IndexDocument: website.IndexDocument,
+			ErrorDocument: website.ErrorDocument,
+		},
+	})
+}
+
+// SetBucketQuotas sets quotas through the SDK, which only handles quotas up
+// to 2^31-1.
+func (c *sdkClient) SetBucketQuotas(ctx context.Context, bucketID string, quotas bucketQuotas) (*bucket, error) {
+	maxSize, err := toInt32Ptr("quota_max_size", quotas.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	maxObjects, err := toInt32Ptr("quota_max_objects", quotas.MaxObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.updateBucket(ctx, bucketID, garage.UpdateBucketRequest{
+		Quotas: &garage.UpdateBucketRequestQuotas{
+			MaxSize:    *garage.NewNullableInt32(maxSize),
+			MaxObjects: *garage.NewNullableInt32(maxObjects),
+		},
+	})
+}
+
+func (c *sdkClient) updateBucket(ctx context.Context, bucketID string, updateBucketRequest garage.UpdateBucketRequest) (*bucket, error) {
+	bucketInfo, httpResp, err := c.sdk.BucketApi.UpdateBucket(c.context(ctx), bucketID).UpdateBucketRequest(updateBucketRequest).Execute()
+	if err != nil {
+		return nil, sdkError(httpResp, err)
+	}
+	return fromSDKBucket(bucketInfo), nil
+}
+
+func (c *sdkClient) DeleteBucket(ctx context.Context, bucketID string) error {
+	httpResp, err := c.sdk.BucketApi.DeleteBucket(c.context(ctx), bucketID).Execute()
+	return sdkError(httpResp, err)
+}
+
+func (c *sdkClient) AddBucketGlobalAlias(ctx context.Context, bucketID string, alias string) error {
+	_, httpResp, err := c.sdk.BucketApi.PutBucketGlobalAlias(c.context(ctx)).Id(bucketID).Alias(alias).Execute()
+	return sdkError(httpResp, err)
+}
+
+func (c *sdkClient) RemoveBucketGlobalAlias(ctx context.Context, bucketID string, alias string) error {
+	_, httpResp, err := c.sdk.BucketApi.DeleteBucketGlobalAlias(c.context(ctx)).Id(bucketID).Alias(alias).Execute()
+	return sdkError(httpResp, err)
+}
+
+func (c *sdkClient) AddBucketLocalAlias(ctx context.Context, bucketID string, accessKeyID string, alias string) error {
+	_, httpResp, err := c.sdk.BucketApi.PutBucketLocalAlias(c.context(ctx)).Id(bucketID).AccessKeyId(accessKeyID).Alias(alias).Execute()
+	return sdkError(httpResp, err)
+}
+
+func (c *sdkClient) RemoveBucketLocalAlias(ctx context.Context, bucketID string, accessKeyID string, alias string) error {
+	_, httpResp, err := c.sdk.BucketApi.DeleteBucketLocalAlias(c.context(ctx)).Id(bucketID).AccessKeyId(accessKeyID).Alias(alias).Execute()
+	return sdkError(httpResp, err)
+}
+
+func allowBucketKeyRequest(bucketID string, accessKeyID string, permissions bucketKeyPermissions) garage.AllowBucketKeyRequest {
+	return garage.AllowBucketKeyRequest{
+		BucketId:    bucketID,
+		AccessKeyId: accessKeyID,
+		Permissions: garage.AllowBucketKeyRequestPermissions{
+			Read:  permissions.Read,
+			Write: permissions.Write,
+			Owner: permissions.Owner,
+		},
+	}
+}
+
+func (c *sdkClient) GrantKey(ctx context.Context, bucketID string, accessKeyID string, permissions bucketKeyPermissions) error {
+	_, httpResp, err := c.sdk.BucketApi.AllowBucketKey(c.context(ctx)).AllowBucketKeyRequest(allowBucketKeyRequest(bucketID, accessKeyID, permissions)).Execute()
+	return sdkError(httpResp, err)
+}
+
+func (c *sdkClient) RevokeKey(ctx context.Context, bucketID string, accessKeyID string, permissions bucketKeyPermissions) error {
+	_, httpResp, err := c.sdk.BucketApi.DenyBucketKey(c.context(ctx)).AllowBucketKeyRequest(allowBucketKeyRequest(bucketID, accessKeyID, permissions)).Execute()
+	return sdkError(httpResp, err)
+}
+
+func (c *sdkClient) ListKeys(ctx context.Context) ([]keyListItem, error) {
+	resp, httpResp, err := c.sdk.KeyApi.ListKeys(c.context(ctx)).Execute()
+	if err != nil {
+		return nil, sdkError(httpResp, err)
+	}
+
+	keys := make([]keyListItem, len(resp))
+	for i, item := range resp {
+		keys[i] = keyListItem{
+			ID:   stringValue(item.Id),
+			Name: stringValue(item.Name),
+		}
+	}
+	return keys, nil
+}
+
+// GetKey fetches a key along with its secret, which the v1 API only returns
+// on request.
+func (c *sdkClient) GetKey(ctx context.Context, accessKeyID string) (*accessKey, error) {
+	if c.admin.apiVersion == 0 {
+		keyInfo, httpResp, err := c.sdk.KeyApi.GetKey(c.context(ctx), accessKeyID).Execute()
+		if err != nil {
+			return nil, sdkError(httpResp, err)
+		}
+		return fromSDKKey(keyInfo), nil
+	}
+
+	query := url.Values{
+		"id":            {accessKeyID},
+		"showSecretKey": {"true"},
+	}
+	return c.getKey(ctx, query)
+}
+
+func (c *sdkClient) SearchKey(ctx context.Context, pattern string) (*accessKey, error) {
+	query := url.Values{
+		"search":        {pattern},
+		"showSecretKey": {"true"},
+	}
+	return c.getKey(ctx, query)
+}
+
+func (c *sdkClient) getKey(ctx context.Context, query url.Values) (*accessKey, error) {
+	keyInfo := &accessKey{}
+	_, err := c.admin.call(ctx, http.MethodGet, "/key", query, nil, keyInfo)
+	return keyInfo, err
+}
+
+func (c *sdkClient) CreateKey(ctx context.Context, name string) (*accessKey, error) {
+	addKeyRequest := *garage.NewAddKeyRequest()
+	if name != "" {
+		addKeyRequest.Name = &name
+	}
+
+	keyInfo, httpResp, err := c.sdk.KeyApi.AddKey(c.context(ctx)).AddKeyRequest(addKeyRequest).Execute()
+	if err != nil {
+		return nil, sdkError(httpResp, err)
+	}
+	return fromSDKKey(keyInfo), nil
+}
+
+func (c *sdkClient) ImportKey(ctx context.Context, name string, accessKeyID string, secretAccessKey string) (*accessKey, error) {
+	importKeyRequest := *garage.NewImportKeyRequest(name, accessKeyID, secretAccessKey)
+
+	keyInfo, httpResp, err := c.sdk.KeyApi.ImportKey(c.context(ctx)).ImportKeyRequest(importKeyRequest).Execute()
+	if err != nil {
+		return nil, sdkError(httpResp, err)
+	}
+	return fromSDKKey(keyInfo), nil
+}
+
+var errKeyExpirationUnsupported = errors.New("key expiration requires the v2 admin API")
+
+func (c *sdkClient) UpdateKey(ctx context.Context, accessKeyID string, update keyUpdate) (*accessKey, error) {
+	if update.Expiration != nil || update.NeverExpires {
+		return nil, errKeyExpirationUnsupported
+	}
+
+	updateKeyRequest := garage.UpdateKeyRequest{
+		Name: update.Name,
+	}
+	if update.Allow != nil {
+		updateKeyRequest.Allow = &garage.UpdateKeyRequestAllow{
+			CreateBucket: &update.Allow.CreateBucket,
+		}
+	}
+	if update.Deny != nil {
+		updateKeyRequest.Deny = &garage.UpdateKeyRequestDeny{
+			CreateBucket: &update.Deny.CreateBucket,
+		}
+	}
+
+	keyInfo, httpResp, err := c.sdk.KeyApi.UpdateKey(c.context(ctx), accessKeyID).UpdateKeyRequest(updateKeyRequest).Execute()
+	if err != nil {
+		return nil, sdkError(httpResp, err)
+	}
+	return fromSDKKey(keyInfo), nil
+}
+
+func (c *sdkClient) DeleteKey(ctx context.Context, accessKeyID string) error {
+	httpResp, err := c.sdk.KeyApi.DeleteKey(c.context(ctx), accessKeyID).Execute()
+	return sdkError(httpResp, err)
+}
+
+func (c *sdkClient) GetClusterHealth(ctx context.Context) (*clusterHealth, error) {
+	health := &clusterHealth{}
+	_, err := c.admin.call(ctx, http.MethodGet, "/health", nil, nil, health)
+	health.StorageNodesUp = health.StorageNodesOk
+	return health, err
+}
+
+// The layout endpoints below follow the v1 API: the v0 one, which the
+// resources don't support, differs.
+
+func (c *sdkClient) GetClusterLayout(ctx context.Context) (*clusterLayout, error) {
+	layout := &clusterLayout{}
+	_, err := c.admin.call(ctx, http.MethodGet, "/layout", nil, nil, layout)
+	return layout, err
+}
+
+func (c *sdkClient) StageClusterLayoutChanges(ctx context.Context, changes []nodeRoleChange) (*clusterLayout, error) {
+	layout := &clusterLayout{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/layout", nil, changes, layout)
+	return layout, err
+}
+
+func (c *sdkClient) ApplyClusterLayout(ctx context.Context, version int64) (*clusterLayout, error) {
+	resp := &applyClusterLayoutResponse{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/layout/apply", nil, applyClusterLayoutRequest{Version: version}, resp)
+	return &resp.Layout, err
+}
+
+var errAdminTokensUnsupported = errors.New("admin tokens require the v2 admin API")
+
+func (c *sdkClient) GetAdminToken(ctx context.Context, id string) (*adminToken, error) {
+	return nil, errAdminTokensUnsupported
+}
+
+func (c *sdkClient) CreateAdminToken(ctx context.Context, update adminTokenUpdate) (*adminToken, error) {
+	return nil, errAdminTokensUnsupported
+}
+
+func (c *sdkClient) UpdateAdminToken(ctx context.Context, id string, update adminTokenUpdate) (*adminToken, error) {
+	return nil, errAdminTokensUnsupported
+}
+
+func (c *sdkClient) DeleteAdminToken(ctx context.Context, id string) error {
+	return errAdminTokensUnsupported
+}
@@ -0,0 +1,315 @@
+package garage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// forEachAPIVersion runs test against a fake node of each tested admin API
+// version, through the client the provider would pick for it.
+func forEachAPIVersion(t *testing.T, test func(t *testing.T, fake *fakeGarage, client garageClient)) {
+	for _, apiVersion := range testAPIVersions {
+		apiVersion := apiVersion
+		t.Run(fmt.Sprintf("v%d", apiVersion), func(t *testing.T) {
+			fake := newFakeGarage(t, apiVersion)
+			test(t, fake, fake.client())
+		})
+	}
+}
+
+func requireStatus(t *testing.T, err error, statusCode int) {
+	t.Helper()
+
+	var apiErr *adminError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an admin API error answering %d, got %v", statusCode, err)
+	}
+	if apiErr.StatusCode != statusCode {
+		t.Fatalf("expected an admin API error answering %d, got %v", statusCode, err)
+	}
+}
+
+func TestClientBucket(t *testing.T) {
+	forEachAPIVersion(t, func(t *testing.T, fake *fakeGarage, client garageClient) {
+		ctx := context.Background()
+
+		bucketInfo, err := client.CreateBucket(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		indexDocument := "index.html"
+		_, err = client.SetBucketWebsite(ctx, bucketInfo.ID, bucketWebsite{Enabled: true})
+		requireStatus(t, err, http.StatusBadRequest)
+		_, err = client.SetBucketWebsite(ctx, bucketInfo.ID, bucketWebsite{Enabled: true, IndexDocument: &indexDocument})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The v0 and v1 APIs, through the SDK, don't take quotas above 2^31-1.
+		maxObjects := int64(100)
+		maxSize := int64(10 << 30)
+		_, err = client.SetBucketQuotas(ctx, bucketInfo.ID, bucketQuotas{MaxSize: &maxSize, MaxObjects: &maxObjects})
+		if fake.apiVersion >= 2 && err != nil {
+			t.Fatal(err)
+		}
+		if fake.apiVersion < 2 && (err == nil || !strings.Contains(err.Error(), "quota_max_size")) {
+			t.Fatalf("expected quota_max_size to be out of range, got %v", err)
+		}
+		_, err = client.SetBucketQuotas(ctx, bucketInfo.ID, bucketQuotas{MaxObjects: &maxObjects})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bucketInfo, err = client.GetBucket(ctx, bucketInfo.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bucketInfo.WebsiteAccess || bucketInfo.WebsiteConfig == nil || bucketInfo.WebsiteConfig.IndexDocument != indexDocument {
+			t.Errorf("website access wasn't enabled: %+v", bucketInfo)
+		}
+		if !equalInt64Ptr(bucketInfo.Quotas.MaxObjects, &maxObjects) || bucketInfo.Quotas.MaxSize != nil {
+			t.Errorf("unexpected quotas: %+v", bucketInfo.Quotas)
+		}
+
+		buckets, err := client.ListBuckets(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buckets) != 1 || buckets[0].ID != bucketInfo.ID {
+			t.Errorf("unexpected buckets: %+v", buckets)
+		}
+
+		err = client.DeleteBucket(ctx, bucketInfo.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.GetBucket(ctx, bucketInfo.ID)
+		if !isNotFound(err) {
+			t.Fatalf("expected the bucket to be deleted, got %v", err)
+		}
+		if !isNotFound(client.DeleteBucket(ctx, bucketInfo.ID)) {
+			t.Fatalf("expected deleting a deleted bucket to fail")
+		}
+	})
+}
+
+func TestClientBucketAliases(t *testing.T) {
+	forEachAPIVersion(t, func(t *testing.T, fake *fakeGarage, client garageClient) {
+		ctx := context.Background()
+
+		first, err := client.CreateBucket(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := client.CreateBucket(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := client.CreateKey(ctx, "aliases")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := client.AddBucketGlobalAlias(ctx, first.ID, "website"); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.AddBucketGlobalAlias(ctx, first.ID, "website"); err != nil {
+			t.Fatalf("expected adding an alias twice to succeed, got %v", err)
+		}
+		requireStatus(t, client.AddBucketGlobalAlias(ctx, second.ID, "website"), http.StatusConflict)
+		requireStatus(t, client.AddBucketGlobalAlias(ctx, second.ID, "Not_A_Bucket"), http.StatusBadRequest)
+
+		found, err := client.FindBucket(ctx, "website")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.ID != first.ID {
+			t.Errorf("expected the alias to point to %s, got %s", first.ID, found.ID)
+		}
+
+		if err := client.AddBucketLocalAlias(ctx, first.ID, key.AccessKeyID, "mine"); err != nil {
+			t.Fatal(err)
+		}
+		requireStatus(t, client.AddBucketLocalAlias(ctx, second.ID, key.AccessKeyID, "mine"), http.StatusConflict)
+		if !isNotFound(client.AddBucketLocalAlias(ctx, second.ID, "GK000000000000000000000000", "mine")) {
+			t.Errorf("expected a local alias of a missing key to fail")
+		}
+
+		if err := client.RemoveBucketGlobalAlias(ctx, first.ID, "website"); err != nil {
+			t.Fatal(err)
+		}
+		requireStatus(t, client.RemoveBucketGlobalAlias(ctx, first.ID, "website"), http.StatusBadRequest)
+		if _, err := client.FindBucket(ctx, "website"); !isNotFound(err) {
+			t.Errorf("expected the alias to be removed, got %v", err)
+		}
+		if err := client.RemoveBucketLocalAlias(ctx, first.ID, key.AccessKeyID, "mine"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestClientBucketPermissions(t *testing.T) {
+	forEachAPIVersion(t, func(t *testing.T, fake *fakeGarage, client garageClient) {
+		ctx := context.Background()
+
+		bucketInfo, err := client.CreateBucket(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := client.CreateKey(ctx, "permissions")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = client.GrantKey(ctx, bucketInfo.ID, key.AccessKeyID, bucketKeyPermissions{Read: true, Write: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.RevokeKey(ctx, bucketInfo.ID, key.AccessKeyID, bucketKeyPermissions{Write: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bucketInfo, err = client.GetBucket(ctx, bucketInfo.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bucketInfo.Keys) != 1 || bucketInfo.Keys[0].Permissions != (bucketKeyPermissions{Read: true}) {
+			t.Errorf("unexpected bucket keys: %+v", bucketInfo.Keys)
+		}
+
+		if !isNotFound(client.GrantKey(ctx, "missing", key.AccessKeyID, bucketKeyPermissions{Read: true})) {
+			t.Errorf("expected granting a missing bucket to fail")
+		}
+		if !isNotFound(client.GrantKey(ctx, bucketInfo.ID, "GK000000000000000000000000", bucketKeyPermissions{Read: true})) {
+			t.Errorf("expected granting a missing key to fail")
+		}
+
+		if err := client.DeleteKey(ctx, key.AccessKeyID); err != nil {
+			t.Fatal(err)
+		}
+		bucketInfo, err = client.GetBucket(ctx, bucketInfo.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bucketInfo.Keys) != 0 {
+			t.Errorf("expected the permissions of the deleted key to be removed: %+v", bucketInfo.Keys)
+		}
+	})
+}
+
+func TestClientKey(t *testing.T) {
+	forEachAPIVersion(t, func(t *testing.T, fake *fakeGarage, client garageClient) {
+		ctx := context.Background()
+
+		key, err := client.CreateKey(ctx, "created")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.SecretAccessKey == "" {
+			t.Errorf("expected the secret of the created key")
+		}
+
+		name := "renamed"
+		_, err = client.UpdateKey(ctx, key.AccessKeyID, keyUpdate{Name: &name, Allow: &keyPermissions{CreateBucket: true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := client.SearchKey(ctx, "renamed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.AccessKeyID != key.AccessKeyID || !found.Permissions.CreateBucket || found.SecretAccessKey != key.SecretAccessKey {
+			t.Errorf("unexpected key: %+v", found)
+		}
+
+		accessKeyID := "GK" + randomHex(24)
+		secretAccessKey := randomHex(64)
+		imported, err := client.ImportKey(ctx, "imported", accessKeyID, secretAccessKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imported.AccessKeyID != accessKeyID || imported.SecretAccessKey != secretAccessKey {
+			t.Errorf("unexpected imported key: %+v", imported)
+		}
+		_, err = client.ImportKey(ctx, "imported", accessKeyID, secretAccessKey)
+		requireStatus(t, err, http.StatusConflict)
+		_, err = client.ImportKey(ctx, "invalid", "GKinvalid", secretAccessKey)
+		requireStatus(t, err, http.StatusBadRequest)
+
+		keys, err := client.ListKeys(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 2 {
+			t.Errorf("unexpected keys: %+v", keys)
+		}
+
+		if err := client.DeleteKey(ctx, accessKeyID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.GetKey(ctx, accessKeyID); !isNotFound(err) {
+			t.Errorf("expected the key to be deleted, got %v", err)
+		}
+		_, err = client.ImportKey(ctx, "imported", accessKeyID, secretAccessKey)
+		requireStatus(t, err, http.StatusConflict)
+	})
+}
+
+func TestClientClusterLayout(t *testing.T) {
+	forEachAPIVersion(t, func(t *testing.T, fake *fakeGarage, client garageClient) {
+		ctx := context.Background()
+
+		layout, err := client.GetClusterLayout(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		capacity := int64(2 << 30)
+		_, err = client.StageClusterLayoutChanges(ctx, []nodeRoleChange{{ID: fake.nodeID, Zone: "dc2", Capacity: &capacity, Tags: []string{}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.ApplyClusterLayout(ctx, layout.Version)
+		requireStatus(t, err, http.StatusBadRequest)
+
+		layout, err = client.ApplyClusterLayout(ctx, layout.Version+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(layout.Roles) != 1 || layout.Roles[0].Zone != "dc2" || len(layout.StagedRoleChanges) != 0 {
+			t.Errorf("unexpected layout: %+v", layout)
+		}
+	})
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	for _, apiVersion := range testAPIVersions {
+		fake := newFakeGarage(t, apiVersion)
+		admin := &adminClient{
+			endpoint:   fake.URL,
+			httpClient: fake.Client(),
+			token:      fakeAdminToken,
+		}
+
+		detected, garageVersion, diags := checkConnectivity(context.Background(), admin, adminAPIVersions)
+		if diags.HasError() {
+			t.Fatalf("v%d: %v", apiVersion, diags)
+		}
+		if detected != apiVersion || garageVersion != fake.garageVersion {
+			t.Errorf("expected v%d and %s, got v%d and %s", apiVersion, fake.garageVersion, detected, garageVersion)
+		}
+
+		admin.token = "wrong"
+		_, _, diags = checkConnectivity(context.Background(), admin, adminAPIVersions)
+		if !diags.HasError() || diags[0].Summary != "Garage admin API rejected the token" {
+			t.Errorf("v%d: expected the token to be rejected, got %v", apiVersion, diags)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package garage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// v2Client is the garageClient of the RPC-style v2 admin API of Garage 2.x,
+// which has one endpoint per operation.
+type v2Client struct {
+	admin *adminClient
+}
+
+// bucketAliasRequest adds or removes either a global alias, or a local alias
+// of a key.
+type bucketAliasRequest struct {
+	BucketID    string `json:"bucketId"`
+	GlobalAlias string `json:"globalAlias,omitempty"`
+	AccessKeyID string `json:"accessKeyId,omitempty"`
+	LocalAlias  string `json:"localAlias,omitempty"`
+}
+
+type bucketKeyRequest struct {
+	BucketID    string               `json:"bucketId"`
+	AccessKeyID string               `json:"accessKeyId"`
+	Permissions bucketKeyPermissions `json:"permissions"`
+}
+
+type updateBucketRequest struct {
+	WebsiteAccess *bucketWebsite `json:"websiteAccess,omitempty"`
+	Quotas        *bucketQuotas  `json:"quotas,omitempty"`
+}
+
+type createKeyRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+type importKeyRequest struct {
+	Name            string `json:"name"`
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+}
+
+func byID(id string) url.Values {
+	return url.Values{
+		"id": {id},
+	}
+}
+
+func (c *v2Client) ListBuckets(ctx context.Context) ([]bucketListItem, error) {
+	var buckets []bucketListItem
+	_, err := c.admin.call(ctx, http.MethodGet, "/ListBuckets", nil, nil, &buckets)
+	return buckets, err
+}
+
+func (c *v2Client) GetBucket(ctx context.Context, bucketID string) (*bucket, error) {
+	bucketInfo := &bucket{}
+	_, err := c.admin.call(ctx, http.MethodGet, "/GetBucketInfo", byID(bucketID), nil, bucketInfo)
+	return bucketInfo, err
+}
+
+func (c *v2Client) FindBucket(ctx context.Context, globalAlias string) (*bucket, error) {
+	bucketInfo := &bucket{}
+	query := url.Values{
+		"globalAlias": {globalAlias},
+	}
+	_, err := c.admin.call(ctx, http.MethodGet, "/GetBucketInfo", query, nil, bucketInfo)
+	return bucketInfo, err
+}
+
+func (c *v2Client) CreateBucket(ctx context.Context) (*bucket, error) {
+	bucketInfo := &bucket{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/CreateBucket", nil, struct{}{}, bucketInfo)
+	return bucketInfo, err
+}
+
+func (c *v2Client) SetBucketWebsite(ctx context.Context, bucketID string, website bucketWebsite) (*bucket, error) {
+	return c.updateBucket(ctx, bucketID, updateBucketRequest{WebsiteAccess: &website})
+}
+
+func (c *v2Client) SetBucketQuotas(ctx context.Context, bucketID string, quotas bucketQuotas) (*bucket, error) {
+	return c.updateBucket(ctx, bucketID, updateBucketRequest{Quotas: &quotas})
+}
+
+func (c *v2Client) updateBucket(ctx context.Context, bucketID string, update updateBucketRequest) (*bucket, error) {
+	bucketInfo := &bucket{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/UpdateBucket", byID(bucketID), update, bucketInfo)
+	return bucketInfo, err
+}
+
+func (c *v2Client) DeleteBucket(ctx context.Context, bucketID string) error {
+	_, err := c.admin.call(ctx, http.MethodPost, "/DeleteBucket", byID(bucketID), nil, nil)
+	return err
+}
+
+func (c *v2Client) AddBucketGlobalAlias(ctx context.Context, bucketID string, alias string) error {
+	aliasRequest := bucketAliasRequest{
+		BucketID:    bucketID,
+		GlobalAlias: alias,
+	}
+	_, err := c.admin.call(ctx, http.MethodPost, "/AddBucketAlias", nil, aliasRequest, nil)
+	return err
+}
+
+func (c *v2Client) RemoveBucketGlobalAlias(ctx context.Context, bucketID string, alias string) error {
+	aliasRequest := bucketAliasRequest{
+		BucketID:    bucketID,
+		GlobalAlias: alias,
+	}
+	_, err := c.admin.call(ctx, http.MethodPost, "/RemoveBucketAlias", nil, aliasRequest, nil)
+	return err
+}
+
+func (c *v2Client) AddBucketLocalAlias(ctx context.Context, bucketID string, accessKeyID string, alias string) error {
+	aliasRequest := bucketAliasRequest{
+		BucketID:    bucketID,
+		AccessKeyID: accessKeyID,
+		LocalAlias:  alias,
+	}
+	_, err := c.admin.call(ctx, http.MethodPost, "/AddBucketAlias", nil, aliasRequest, nil)
+	return err
+}
+
+func (c *v2Client) RemoveBucketLocalAlias(ctx context.Context, bucketID string, accessKeyID string, alias string) error {
+	aliasRequest := bucketAliasRequest{
+		BucketID:    bucketID,
+		AccessKeyID: accessKeyID,
+		LocalAlias:  alias,
+	}
+	_, err := c.admin.call(ctx, http.MethodPost, "/RemoveBucketAlias", nil, aliasRequest, nil)
+	return err
+}
+
+func (c *v2Client) GrantKey(ctx context.Context, bucketID string, accessKeyID string, permissions bucketKeyPermissions) error {
+	keyRequest := bucketKeyRequest{
+		BucketID:    bucketID,
+		AccessKeyID: accessKeyID,
+		Permissions: permissions,
+	}
+	_, err := c.admin.call(ctx, http.MethodPost, "/AllowBucketKey", nil, keyRequest, nil)
+	return err
+}
+
+func (c *v2Client) RevokeKey(ctx context.Context, bucketID string, accessKeyID string, permissions bucketKeyPermissions) error {
+	keyRequest := bucketKeyRequest{
+		BucketID:    bucketID,
+		AccessKeyID: accessKeyID,
+		Permissions: permissions,
+	}
+	_, err := c.admin.call(ctx, http.MethodPost, "/DenyBucketKey", nil, keyRequest, nil)
+	return err
+}
+
+func (c *v2Client) ListKeys(ctx context.Context) ([]keyListItem, error) {
+	var keys []keyListItem
+	_, err := c.admin.call(ctx, http.MethodGet, "/ListKeys", nil, nil, &keys)
+	return keys, err
+}
+
+func (c *v2Client) GetKey(ctx context.Context, accessKeyID string) (*accessKey, error) {
+	query := byID(accessKeyID)
+	query.Set("showSecretKey", "true")
+	return c.getKey(ctx, query)
+}
+
+func (c *v2Client) SearchKey(ctx context.Context, pattern string) (*accessKey, error) {
+	query := url.Values{
+		"search":        {pattern},
+		"showSecretKey": {"true"},
+	}
+	return c.getKey(ctx, query)
+}
+
+func (c *v2Client) getKey(ctx context.Context, query url.Values) (*accessKey, error) {
+	keyInfo := &accessKey{}
+	_, err := c.admin.call(ctx, http.MethodGet, "/GetKeyInfo", query, nil, keyInfo)
+	return keyInfo, err
+}
+
+func (c *v2Client) CreateKey(ctx context.Context, name string) (*accessKey, error) {
+	keyInfo := &accessKey{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/CreateKey", nil, createKeyRequest{Name: name}, keyInfo)
+	return keyInfo, err
+}
+
+func (c *v2Client) ImportKey(ctx context.Context, name string, accessKeyID string, secretAccessKey string) (*accessKey, error) {
+	keyRequest := importKeyRequest{
+		Name:            name,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+	}
+	keyInfo := &accessKey{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/ImportKey", nil, keyRequest, keyInfo)
+	return keyInfo, err
+}
+
+func (c *v2Client) UpdateKey(ctx context.Context, accessKeyID string, update keyUpdate) (*accessKey, error) {
+	keyInfo := &accessKey{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/UpdateKey", byID(accessKeyID), update, keyInfo)
+	return keyInfo, err
+}
+
+func (c *v2Client) DeleteKey(ctx context.Context, accessKeyID string) error {
+	_, err := c.admin.call(ctx, http.MethodPost, "/DeleteKey", byID(accessKeyID), nil, nil)
+	return err
+}
+
+func (c *v2Client) GetClusterHealth(ctx context.Context) (*clusterHealth, error) {
+	health := &clusterHealth{}
+	_, err := c.admin.call(ctx, http.MethodGet, "/GetClusterHealth", nil, nil, health)
+	return health, err
+}
+
+func (c *v2Client) GetClusterLayout(ctx context.Context) (*clusterLayout, error) {
+	layout := &clusterLayout{}
+	_, err := c.admin.call(ctx, http.MethodGet, "/GetClusterLayout", nil, nil, layout)
+	return layout, err
+}
+
+func (c *v2Client) StageClusterLayoutChanges(ctx context.Context, changes []nodeRoleChange) (*clusterLayout, error) {
+	layout := &clusterLayout{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/UpdateClusterLayout", nil, updateClusterLayoutRequest{Roles: changes}, layout)
+	return layout, err
+}
+
+func (c *v2Client) ApplyClusterLayout(ctx context.Context, version int64) (*clusterLayout, error) {
+	resp := &applyClusterLayoutResponse{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/ApplyClusterLayout", nil, applyClusterLayoutRequest{Version: version}, resp)
+	return &resp.Layout, err
+}
+
+func (c *v2Client) GetAdminToken(ctx context.Context, id string) (*adminToken, error) {
+	token := &adminToken{}
+	_, err := c.admin.call(ctx, http.MethodGet, "/GetAdminTokenInfo", byID(id), nil, token)
+	return token, err
+}
+
+func (c *v2Client) CreateAdminToken(ctx context.Context, update adminTokenUpdate) (*adminToken, error) {
+	token := &adminToken{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/CreateAdminToken", nil, update, token)
+	return token, err
+}
+
+func (c *v2Client) UpdateAdminToken(ctx context.Context, id string, update adminTokenUpdate) (*adminToken, error) {
+	token := &adminToken{}
+	_, err := c.admin.call(ctx, http.MethodPost, "/UpdateAdminToken", byID(id), update, token)
+	return token, err
+}
+
+func (c *v2Client) DeleteAdminToken(ctx context.Context, id string) error {
+	_, err := c.admin.call(ctx, http.MethodPost, "/DeleteAdminToken", byID(id), nil, nil)
+	return err
+}
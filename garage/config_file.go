@@ -0,0 +1,117 @@
+package garage
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// garageConfig holds the parts of a Garage node configuration file
+// (garage.toml) the provider can take its settings from.
+type garageConfig struct {
+	Admin struct {
+		APIBindAddr    string `toml:"api_bind_addr"`
+		AdminToken     string `toml:"admin_token"`
+		AdminTokenFile string `toml:"admin_token_file"`
+	} `toml:"admin"`
+	S3API struct {
+		S3Region    string `toml:"s3_region"`
+		APIBindAddr string `toml:"api_bind_addr"`
+	} `toml:"s3_api"`
+	K2VAPI struct {
+		APIBindAddr string `toml:"api_bind_addr"`
+	} `toml:"k2v_api"`
+}
+
+func readGarageConfig(path string) (*garageConfig, error) {
+	config := &garageConfig{}
+	if _, err := toml.DecodeFile(path, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// adminEndpoint returns the URL to reach the admin API bound as configured,
+// using the loopback address when it listens on every interface.
+func (c *garageConfig) adminEndpoint() (string, error) {
+	return localEndpoint("admin", c.Admin.APIBindAddr)
+}
+
+// s3Endpoint returns the URL to reach the S3 API bound as configured, like
+// adminEndpoint.
+func (c *garageConfig) s3Endpoint() (string, error) {
+	return localEndpoint("s3_api", c.S3API.APIBindAddr)
+}
+
+// k2vEndpoint returns the URL to reach the K2V API bound as configured, like
+// adminEndpoint.
+func (c *garageConfig) k2vEndpoint() (string, error) {
+	return localEndpoint("k2v_api", c.K2VAPI.APIBindAddr)
+}
+
+// localEndpoint returns the URL to reach bindAddr, the api_bind_addr of the
+// given section, from the node itself.
+func localEndpoint(section string, bindAddr string) (string, error) {
+	if bindAddr == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(bindAddr, "/") {
+		return "", fmt.Errorf("%s api_bind_addr %q is a unix socket, which is not supported", section, bindAddr)
+	}
+
+	host, port, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s api_bind_addr %q: %w", section, bindAddr, err)
+	}
+	// Unspecified addresses, IPv6 ones included as they usually accept IPv4
+	// too, are reached through the IPv4 loopback, which is always available.
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+// adminToken returns the admin token, reading it from admin_token_file when
+// it is not set inline.
+func (c *garageConfig) adminToken() (string, error) {
+	if c.Admin.AdminToken != "" {
+		return c.Admin.AdminToken, nil
+	}
+	if c.Admin.AdminTokenFile == "" {
+		return "", nil
+	}
+
+	content, err := os.ReadFile(c.Admin.AdminTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read admin_token_file: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// withGarageConfig completes endpoint and token, when they are empty, with
+// the values of the Garage configuration file at path.
+func withGarageConfig(path string, endpoint string, token string) (string, string, error) {
+	config, err := readGarageConfig(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	if endpoint == "" {
+		endpoint, err = config.adminEndpoint()
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if token == "" {
+		token, err = config.adminToken()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return endpoint, token, nil
+}
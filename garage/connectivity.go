@@ -0,0 +1,99 @@
+package garage
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+const connectivityCheckTimeout = 30 * time.Second
+
+// checkConnectivity makes a cheap admin API call to make sure the endpoint
+// can be reached with the configured token. It returns the most recent of
+// apiVersions the node speaks, and the version of Garage it runs.
+func checkConnectivity(ctx context.Context, admin *adminClient, apiVersions []int) (int, string, diag.Diagnostics) {
+	ctx, cancel := context.WithTimeout(ctx, connectivityCheckTimeout)
+	defer cancel()
+
+	var httpResp *http.Response
+	var err error
+	for _, apiVersion := range apiVersions {
+		var status *clusterStatus
+		status, httpResp, err = getClusterStatus(ctx, admin.withAPIVersion(apiVersion))
+		if err == nil {
+			return apiVersion, status.GarageVersion, nil
+		}
+		if !isUnknownEndpoint(httpResp) {
+			return 0, "", diag.Diagnostics{connectivityDiagnostic(admin.endpoint, httpResp, err)}
+		}
+	}
+
+	return 0, "", diag.Diagnostics{
+		{
+			Severity: diag.Error,
+			Summary:  "Unsupported Garage admin API",
+			Detail:   fmt.Sprintf("%s doesn't serve any of the admin API versions the provider speaks (%s). Check that endpoint points to the admin API of a supported Garage node.", admin.endpoint, formatAPIVersions(apiVersions)),
+		},
+	}
+}
+
+func formatAPIVersions(apiVersions []int) string {
+	formatted := make([]string, len(apiVersions))
+	for i, apiVersion := range apiVersions {
+		formatted[i] = fmt.Sprintf("v%d", apiVersion)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func connectivityDiagnostic(endpoint string, httpResp *http.Response, err error) diag.Diagnostic {
+	summary := "Unable to reach the Garage admin API"
+	detail := fmt.Sprintf("Request to %s failed: %s", endpoint, err)
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+
+	switch {
+	case httpResp != nil && (httpResp.StatusCode == http.StatusUnauthorized || httpResp.StatusCode == http.StatusForbidden):
+		summary = "Garage admin API rejected the token"
+		detail = fmt.Sprintf("%s answered %s. Check token, token_file or token_command.", endpoint, httpResp.Status)
+	case httpResp != nil:
+		summary = "Unexpected answer from the Garage admin API"
+		detail = fmt.Sprintf("%s answered %s. Check that endpoint points to the admin API of a Garage node.", endpoint, httpResp.Status)
+	case errors.As(err, &dnsErr):
+		summary = "Unable to resolve the Garage admin API host"
+		detail = fmt.Sprintf("DNS lookup of %s failed: %s", dnsErr.Name, dnsErr.Err)
+	case errors.As(err, &unknownAuthorityErr):
+		summary = "Garage admin API certificate is not trusted"
+		detail = fmt.Sprintf("%s. Set ca_cert_file or ca_cert_pem to the CA that signed it.", err)
+	case errors.As(err, &hostnameErr):
+		summary = "Garage admin API certificate does not match its host"
+		detail = fmt.Sprintf("%s. Set tls_server_name to the name the certificate was issued for.", err)
+	case errors.As(err, &certificateInvalidErr):
+		summary = "Garage admin API certificate is invalid"
+	case errors.As(err, &recordHeaderErr) || strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		summary = "TLS handshake with the Garage admin API failed"
+		detail = fmt.Sprintf("%s. The admin API may not be served over HTTPS: check scheme or endpoint.", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		summary = "Timed out reaching the Garage admin API"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		summary = "Unable to connect to the Garage admin API"
+	}
+
+	return diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  summary,
+		Detail:   detail + "\n\nSet skip_connectivity_check to configure the provider without reaching the admin API.",
+	}
+}
@@ -0,0 +1,54 @@
+package garage
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func schemaDataSourceBucket() map[string]*schema.Schema {
+	s := computedSchema(schemaBucket())
+	delete(s, "manage_website")
+	s["id"] = &schema.Schema{
+		Description:  "The ID of the bucket.",
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ExactlyOneOf: []string{"id", "global_alias"},
+	}
+	s["global_alias"] = &schema.Schema{
+		Description:  "A global alias of the bucket.",
+		Type:         schema.TypeString,
+		Optional:     true,
+		ExactlyOneOf: []string{"id", "global_alias"},
+	}
+	return s
+}
+
+func dataSourceBucket() *schema.Resource {
+	return &schema.Resource{
+		Description: "This data source can be used to read a Garage bucket, by ID or global alias.",
+		ReadContext: dataSourceBucketRead,
+		Schema:      schemaDataSourceBucket(),
+	}
+}
+
+func dataSourceBucketRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	var bucketInfo *bucket
+	var err error
+	if bucketID, ok := d.GetOk("id"); ok {
+		bucketInfo, err = p.client.GetBucket(ctx, bucketID.(string))
+	} else {
+		bucketInfo, err = p.client.FindBucket(ctx, d.Get("global_alias").(string))
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(bucketInfo.ID)
+
+	return setBucketInfo(d, bucketInfo)
+}
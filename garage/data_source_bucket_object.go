@@ -0,0 +1,111 @@
+package garage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func schemaDataSourceBucketObject() map[string]*schema.Schema {
+	s := withS3Bucket(map[string]*schema.Schema{
+		"key": {
+			Description: "Key of the object in the bucket.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		// Computed
+		"body": {
+			Description: "Content of the object, empty when it is not valid UTF-8 text.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"body_base64": {
+			Description: "Content of the object as stored, base64-encoded. Objects with a `content_encoding`, such as `gzip`, are not decoded.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"content_type": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"cache_control": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"content_encoding": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"content_length": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"last_modified": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"metadata": {
+			Description: "User metadata of the object, with lowercase names.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"etag": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+	})
+	s["bucket"].ForceNew = false
+	return s
+}
+
+func dataSourceBucketObject() *schema.Resource {
+	return &schema.Resource{
+		Description: "This data source can be used to read an object of a Garage bucket, such as a small JSON manifest, through the S3 API. The whole object is read, so it is not meant for large ones.",
+		ReadContext: dataSourceBucketObjectRead,
+		Schema:      schemaDataSourceBucketObject(),
+	}
+}
+
+func dataSourceBucketObjectRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	bucket := d.Get("bucket").(string)
+	key := d.Get("key").(string)
+
+	client, bucketName, err := p.s3Bucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	object, content, err := client.GetObject(ctx, bucketName, key)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", bucket, key))
+
+	values := flattenObject(object).(map[string]interface{})
+	values["content_length"] = object.ContentLength
+	values["last_modified"] = object.LastModified
+	values["body_base64"] = base64.StdEncoding.EncodeToString(content)
+	values["body"] = ""
+	if utf8.Valid(content) {
+		values["body"] = string(content)
+	}
+	for key, value := range values {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
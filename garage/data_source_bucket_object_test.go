@@ -0,0 +1,93 @@
+package garage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestUnitDataSourceBucketObject(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketObjectConfig(fake.providerConfig(), "manifests", `
+resource "garage_bucket_object" "test" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  key           = "manifest.json"
+  content       = "{\"release\":\"1.2.0\"}"
+  content_type  = "application/json"
+  metadata = {
+    channel = "stable"
+  }
+}
+
+data "garage_bucket_object" "by_alias" {
+  bucket        = garage_bucket_object.test.bucket
+  access_key_id = garage_bucket_key.test.access_key_id
+  key           = garage_bucket_object.test.key
+}
+
+data "garage_bucket_object" "by_id" {
+  bucket        = garage_bucket.test.id
+  access_key_id = garage_bucket_key.test.access_key_id
+  key           = garage_bucket_object.test.key
+}
+`),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.garage_bucket_object.by_alias", "body", `{"release":"1.2.0"}`),
+						resource.TestCheckResourceAttr("data.garage_bucket_object.by_alias", "body_base64", base64.StdEncoding.EncodeToString([]byte(`{"release":"1.2.0"}`))),
+						resource.TestCheckResourceAttr("data.garage_bucket_object.by_alias", "content_type", "application/json"),
+						resource.TestCheckResourceAttr("data.garage_bucket_object.by_alias", "content_length", "19"),
+						resource.TestCheckResourceAttr("data.garage_bucket_object.by_alias", "metadata.channel", "stable"),
+						resource.TestCheckResourceAttrPair("data.garage_bucket_object.by_alias", "etag", "garage_bucket_object.test", "etag"),
+						resource.TestCheckResourceAttr("data.garage_bucket_object.by_id", "body", `{"release":"1.2.0"}`),
+					),
+				},
+			},
+		}
+	})
+}
+
+func TestDataSourceBucketObjectGzip(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "assets")
+	ctx := context.Background()
+	endpoint, _ := url.Parse(fake.s3.URL)
+	p := &garageProvider{client: fake.client(), s3: &s3Config{endpoint: endpoint, region: defaultS3Region, pathStyle: true, httpClient: fake.s3.Client()}}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write([]byte(`{"release":"1.2.0"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.PutObject(ctx, "assets", "manifest.json", s3ObjectMetadata{ContentEncoding: "gzip"}, compressed.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Objects are read as stored, not decompressed.
+	d := schema.TestResourceDataRaw(t, schemaDataSourceBucketObject(), map[string]interface{}{
+		"bucket":        "assets",
+		"access_key_id": client.credentials.AccessKeyID,
+		"key":           "manifest.json",
+	})
+	if diags := dataSourceBucketObjectRead(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if d.Get("content_encoding") != "gzip" || d.Get("body_base64") != base64.StdEncoding.EncodeToString(compressed.Bytes()) {
+		t.Errorf("expected the gzip-encoded object, got %v", d.State())
+	}
+	if d.Get("content_length") != compressed.Len() {
+		t.Errorf("expected a length of %d, got %v", compressed.Len(), d.Get("content_length"))
+	}
+}
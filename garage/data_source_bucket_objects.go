@@ -0,0 +1,126 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/thoas/go-funk"
+)
+
+func schemaDataSourceBucketObjects() map[string]*schema.Schema {
+	s := withS3Bucket(map[string]*schema.Schema{
+		"prefix": {
+			Description: "Prefix of the keys of the listed objects.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"delimiter": {
+			Description: "Character, usually `/`, rolling up the keys containing it after `prefix` into `common_prefixes`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"max_keys": {
+			Description:  "Maximum number of keys and common prefixes listed. All of them are listed when it is not set.",
+			Type:         schema.TypeInt,
+			Optional:     true,
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+		// Computed
+		"keys": {
+			Description: "Keys of the listed objects, in lexicographic order.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"common_prefixes": {
+			Description: "Prefixes, ending with `delimiter`, of the keys rolled up.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"objects": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"key": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+					"size": {
+						Type:     schema.TypeInt,
+						Computed: true,
+					},
+					"etag": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+					"last_modified": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+				},
+			},
+		},
+	})
+	s["bucket"].ForceNew = false
+	return s
+}
+
+func dataSourceBucketObjects() *schema.Resource {
+	return &schema.Resource{
+		Description: "This data source can be used to list the objects of a Garage bucket under a prefix through the S3 API, for example to drive `for_each`.",
+		ReadContext: dataSourceBucketObjectsRead,
+		Schema:      schemaDataSourceBucketObjects(),
+	}
+}
+
+func flattenObjectListItem(object s3ObjectListItem) interface{} {
+	return map[string]interface{}{
+		"key":           object.Key,
+		"size":          object.Size,
+		"etag":          object.ETag,
+		"last_modified": object.LastModified,
+	}
+}
+
+func dataSourceBucketObjectsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	bucket := d.Get("bucket").(string)
+	prefix := d.Get("prefix").(string)
+
+	client, bucketName, err := p.s3Bucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	objects, commonPrefixes, err := client.ListObjects(ctx, bucketName, prefix, d.Get("delimiter").(string), d.Get("max_keys").(int))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", bucket, prefix))
+
+	values := map[string]interface{}{
+		"keys":            funk.Map(objects, func(object s3ObjectListItem) string { return object.Key }),
+		"common_prefixes": commonPrefixes,
+		"objects":         funk.Map(objects, flattenObjectListItem),
+	}
+	for key, value := range values {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
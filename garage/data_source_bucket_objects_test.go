@@ -0,0 +1,64 @@
+package garage
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestUnitDataSourceBucketObjects(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketObjectConfig(fake.providerConfig(), "sites", `
+resource "garage_bucket_object" "test" {
+  for_each = toset(["sites/a/index.html", "sites/b/index.html", "sites/readme.txt", "other.txt"])
+
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  key           = each.key
+  content       = each.key
+}
+
+data "garage_bucket_objects" "all" {
+  bucket        = garage_bucket.test.id
+  access_key_id = garage_bucket_key.test.access_key_id
+  prefix        = "sites/"
+
+  depends_on = [garage_bucket_object.test]
+}
+
+data "garage_bucket_objects" "rolled_up" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  prefix        = "sites/"
+  delimiter     = "/"
+
+  depends_on = [garage_bucket_object.test]
+}
+
+data "garage_bucket_objects" "first" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  max_keys      = 1
+
+  depends_on = [garage_bucket_object.test]
+}
+`),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.all", "keys.#", "3"),
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.all", "keys.0", "sites/a/index.html"),
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.all", "objects.2.key", "sites/readme.txt"),
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.all", "objects.2.size", "16"),
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.rolled_up", "keys.#", "1"),
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.rolled_up", "common_prefixes.#", "2"),
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.rolled_up", "common_prefixes.1", "sites/b/"),
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.first", "keys.#", "1"),
+						resource.TestCheckResourceAttr("data.garage_bucket_objects.first", "keys.0", "other.txt"),
+					),
+				},
+			},
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package garage
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestUnitDataSourceBucket(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+resource "garage_bucket" "test" {
+  quota_max_objects = 1000
+}
+
+resource "garage_bucket_global_alias" "test" {
+  bucket_id = garage_bucket.test.id
+  alias     = "website"
+}
+
+data "garage_bucket" "by_id" {
+  id = garage_bucket.test.id
+
+  depends_on = [garage_bucket_global_alias.test]
+}
+
+data "garage_bucket" "by_alias" {
+  global_alias = garage_bucket_global_alias.test.alias
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.garage_bucket.by_id", "quota_max_objects", "1000"),
+						resource.TestCheckResourceAttr("data.garage_bucket.by_id", "global_aliases.0", "website"),
+						resource.TestCheckResourceAttrPair("data.garage_bucket.by_alias", "id", "garage_bucket.test", "id"),
+					),
+				},
+			},
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package garage
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func schemaDataSourceClusterHealth() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"status": {
+			Description: "The health of the cluster: `healthy`, `degraded` or `unavailable`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"known_nodes": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"connected_nodes": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"storage_nodes": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"storage_nodes_up": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"partitions": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"partitions_quorum": {
+			Description: "The number of partitions with a quorum of connected nodes.",
+			Type:        schema.TypeInt,
+			Computed:    true,
+		},
+		"partitions_all_ok": {
+			Description: "The number of partitions with all their nodes connected.",
+			Type:        schema.TypeInt,
+			Computed:    true,
+		},
+	}
+}
+
+func dataSourceClusterHealth() *schema.Resource {
+	return &schema.Resource{
+		Description: "This data source can be used to read the health of the Garage cluster, through the v1 or v2 admin API.",
+		ReadContext: dataSourceClusterHealthRead,
+		Schema:      schemaDataSourceClusterHealth(),
+	}
+}
+
+func dataSourceClusterHealthRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	if p.apiVersion < 1 {
+		return diag.Errorf("garage_cluster_health requires the v1 or v2 admin API, but the provider uses v%d: set admin_api_version to v1", p.apiVersion)
+	}
+
+	health, err := p.client.GetClusterHealth(ctx)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("cluster_health")
+
+	h := map[string]interface{}{
+		"status":            health.Status,
+		"known_nodes":       health.KnownNodes,
+		"connected_nodes":   health.ConnectedNodes,
+		"storage_nodes":     health.StorageNodes,
+		"storage_nodes_up":  health.StorageNodesUp,
+		"partitions":        health.Partitions,
+		"partitions_quorum": health.PartitionsQuorum,
+		"partitions_all_ok": health.PartitionsAllOk,
+	}
+	for key, value := range h {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
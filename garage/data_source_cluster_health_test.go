@@ -0,0 +1,26 @@
+package garage
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestUnitDataSourceClusterHealth(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+data "garage_cluster_health" "test" {}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.garage_cluster_health.test", "status", "healthy"),
+						resource.TestCheckResourceAttr("data.garage_cluster_health.test", "storage_nodes_up", "1"),
+						resource.TestCheckResourceAttr("data.garage_cluster_health.test", "partitions_all_ok", "256"),
+					),
+				},
+			},
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package garage
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/thoas/go-funk"
+)
+
+func schemaDataSourceK2VIndex() map[string]*schema.Schema {
+	s := withK2VBucket(map[string]*schema.Schema{
+		"prefix": {
+			Description: "Prefix of the keys of the listed partitions.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"start": {
+			Description: "Partition key the listing starts from, included.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"end": {
+			Description: "Partition key the listing ends at, excluded.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"limit": {
+			Description:  "Maximum number of partitions listed. All of them are listed when it is not set.",
+			Type:         schema.TypeInt,
+			Optional:     true,
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+		// Computed
+		"partition_keys": {
+			Description: "Keys of the listed partitions, in lexicographic order.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"partitions": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"partition_key": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+					"entries": {
+						Description: "Number of items of the partition.",
+						Type:        schema.TypeInt,
+						Computed:    true,
+					},
+					"conflicts": {
+						Description: "Number of items with values written concurrently.",
+						Type:        schema.TypeInt,
+						Computed:    true,
+					},
+					"values": {
+						Description: "Number of values of the items, including concurrent ones.",
+						Type:        schema.TypeInt,
+						Computed:    true,
+					},
+					"bytes": {
+						Description: "Total size of the values.",
+						Type:        schema.TypeInt,
+						Computed:    true,
+					},
+				},
+			},
+		},
+	})
+	s["bucket"].ForceNew = false
+	return s
+}
+
+func dataSourceK2VIndex() *schema.Resource {
+	return &schema.Resource{
+		Description: "This data source can be used to list the non-empty partitions of a bucket of the K2V API of Garage, with counts of their items. The index is updated asynchronously, so counts may lag behind recent writes.",
+		ReadContext: dataSourceK2VIndexRead,
+		Schema:      schemaDataSourceK2VIndex(),
+	}
+}
+
+func flattenK2VPartition(partition k2vPartition) interface{} {
+	return map[string]interface{}{
+		"partition_key": partition.PartitionKey,
+		"entries":       partition.Entries,
+		"conflicts":     partition.Conflicts,
+		"values":        partition.Values,
+		"bytes":         partition.Bytes,
+	}
+}
+
+func dataSourceK2VIndexRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	bucket := d.Get("bucket").(string)
+
+	client, bucketName, err := p.k2vBucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	partitions, err := client.ReadIndex(ctx, bucketName, k2vIndexQuery{
+		Prefix: d.Get("prefix").(string),
+		Start:  d.Get("start").(string),
+		End:    d.Get("end").(string),
+		Limit:  d.Get("limit").(int),
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(bucket)
+
+	values := map[string]interface{}{
+		"partition_keys": funk.Map(partitions, func(partition k2vPartition) string { return partition.PartitionKey }),
+		"partitions":     funk.Map(partitions, flattenK2VPartition),
+	}
+	for key, value := range values {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
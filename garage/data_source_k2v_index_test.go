@@ -0,0 +1,59 @@
+package garage
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestUnitDataSourceK2VIndex(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: k2vBucketConfig(fake.providerConfig(), "app", `
+resource "garage_k2v_item" "test" {
+  for_each = {
+    "users/alice"    = "admin"
+    "users/bob"      = "viewer"
+    "sessions/s1"    = "alice"
+    "teams/platform" = "alice,bob"
+  }
+
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = split("/", each.key)[0]
+  sort_key      = split("/", each.key)[1]
+  value         = each.value
+}
+
+data "garage_k2v_index" "all" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+
+  depends_on = [garage_k2v_item.test]
+}
+
+data "garage_k2v_index" "from_t" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  start         = "t"
+  limit         = 1
+
+  depends_on = [garage_k2v_item.test]
+}
+`),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.garage_k2v_index.all", "partition_keys.#", "3"),
+						resource.TestCheckResourceAttr("data.garage_k2v_index.all", "partition_keys.0", "sessions"),
+						resource.TestCheckResourceAttr("data.garage_k2v_index.all", "partitions.2.partition_key", "users"),
+						resource.TestCheckResourceAttr("data.garage_k2v_index.all", "partitions.2.entries", "2"),
+						resource.TestCheckResourceAttr("data.garage_k2v_index.all", "partitions.2.bytes", "11"),
+						resource.TestCheckResourceAttr("data.garage_k2v_index.from_t", "partition_keys.#", "1"),
+						resource.TestCheckResourceAttr("data.garage_k2v_index.from_t", "partition_keys.0", "teams"),
+					),
+				},
+			},
+		}
+	})
+}
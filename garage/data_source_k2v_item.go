@@ -0,0 +1,112 @@
+package garage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// schemaK2VItemValue is the schema of the value of an item read by a data
+// source.
+func schemaK2VItemValue() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"value": {
+			Description: "Value of the item, empty when it is not valid UTF-8 text.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"value_base64": {
+			Description: "Value of the item, base64-encoded.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"causality_token": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+	}
+}
+
+func schemaDataSourceK2VItem() map[string]*schema.Schema {
+	s := withK2VBucket(schemaK2VItemValue())
+	s["partition_key"] = &schema.Schema{
+		Description: "Key of the partition of the item.",
+		Type:        schema.TypeString,
+		Required:    true,
+	}
+	s["sort_key"] = &schema.Schema{
+		Description: "Key of the item in its partition.",
+		Type:        schema.TypeString,
+		Required:    true,
+	}
+	s["bucket"].ForceNew = false
+	return s
+}
+
+func dataSourceK2VItem() *schema.Resource {
+	return &schema.Resource{
+		Description: "This data source can be used to read an item of the K2V API of Garage. Reading an item with values written concurrently fails.",
+		ReadContext: dataSourceK2VItemRead,
+		Schema:      schemaDataSourceK2VItem(),
+	}
+}
+
+// flattenK2VItem returns the value of item, which must have a single one, as
+// K2V answers reads of raw values.
+func flattenK2VItem(partitionKey string, item k2vItem) (map[string]interface{}, error) {
+	if len(item.Values) != 1 {
+		return nil, fmt.Errorf("item %q of partition %q has %d concurrent values: write it again to resolve them", item.SortKey, partitionKey, len(item.Values))
+	}
+
+	i := map[string]interface{}{
+		"value":           "",
+		"value_base64":    base64.StdEncoding.EncodeToString(item.Values[0]),
+		"causality_token": item.CausalityToken,
+	}
+	if utf8.Valid(item.Values[0]) {
+		i["value"] = string(item.Values[0])
+	}
+	return i, nil
+}
+
+func dataSourceK2VItemRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	bucket := d.Get("bucket").(string)
+	partitionKey := d.Get("partition_key").(string)
+	sortKey := d.Get("sort_key").(string)
+
+	client, bucketName, err := p.k2vBucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	item, err := client.ReadItem(ctx, bucketName, partitionKey, sortKey)
+	if err == nil && len(item.Values) == 0 {
+		err = fmt.Errorf("item %q of partition %q was deleted", sortKey, partitionKey)
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	values, err := flattenK2VItem(partitionKey, *item)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%s", bucket, partitionKey, sortKey))
+
+	for key, value := range values {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
@@ -0,0 +1,51 @@
+package garage
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestUnitDataSourceK2VItem(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: k2vBucketConfig(fake.providerConfig(), "app", `
+resource "garage_k2v_item" "test" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = "users"
+  sort_key      = "alice"
+  value_base64  = "/wA="
+}
+
+data "garage_k2v_item" "test" {
+  bucket        = garage_bucket.test.id
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = garage_k2v_item.test.partition_key
+  sort_key      = garage_k2v_item.test.sort_key
+}
+`),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.garage_k2v_item.test", "value", ""),
+						resource.TestCheckResourceAttr("data.garage_k2v_item.test", "value_base64", "/wA="),
+						resource.TestCheckResourceAttrPair("data.garage_k2v_item.test", "causality_token", "garage_k2v_item.test", "causality_token"),
+					),
+				},
+				{
+					Config: k2vBucketConfig(fake.providerConfig(), "app", `
+data "garage_k2v_item" "missing" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = "users"
+  sort_key      = "bob"
+}
+`),
+					ExpectError: regexp.MustCompile("NoSuchKey"),
+				},
+			},
+		}
+	})
+}
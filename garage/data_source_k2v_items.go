@@ -0,0 +1,116 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func schemaDataSourceK2VItems() map[string]*schema.Schema {
+	item := schemaK2VItemValue()
+	item["sort_key"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Computed: true,
+	}
+
+	s := withK2VBucket(map[string]*schema.Schema{
+		"partition_key": {
+			Description: "Key of the partition the items are read from.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"prefix": {
+			Description: "Prefix of the sort keys of the read items.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"start": {
+			Description: "Sort key the range starts from, included.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"end": {
+			Description: "Sort key the range ends at, excluded.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"limit": {
+			Description:  "Maximum number of items read. All of them are read when it is not set.",
+			Type:         schema.TypeInt,
+			Optional:     true,
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+		"reverse": {
+			Description: "Read the items in decreasing order of sort keys, from `start` down to `end`.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+		},
+		// Computed
+		"items": {
+			Description: "Items of the range, in the order of their sort keys.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Resource{
+				Schema: item,
+			},
+		},
+	})
+	s["bucket"].ForceNew = false
+	return s
+}
+
+func dataSourceK2VItems() *schema.Resource {
+	return &schema.Resource{
+		Description: "This data source can be used to read a range of the items of a partition of the K2V API of Garage. Reading items with values written concurrently fails.",
+		ReadContext: dataSourceK2VItemsRead,
+		Schema:      schemaDataSourceK2VItems(),
+	}
+}
+
+func dataSourceK2VItemsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	bucket := d.Get("bucket").(string)
+	partitionKey := d.Get("partition_key").(string)
+
+	client, bucketName, err := p.k2vBucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	query := k2vBatchQuery{
+		PartitionKey: partitionKey,
+		Prefix:       optionalString(d.Get("prefix").(string)),
+		Start:        optionalString(d.Get("start").(string)),
+		End:          optionalString(d.Get("end").(string)),
+		Limit:        optionalInt(d.Get("limit").(int)),
+		Reverse:      d.Get("reverse").(bool),
+	}
+	items, err := client.ReadItems(ctx, bucketName, query)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	flattened := make([]interface{}, len(items))
+	for i, item := range items {
+		values, err := flattenK2VItem(partitionKey, item)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		values["sort_key"] = item.SortKey
+		flattened[i] = values
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", bucket, partitionKey))
+
+	err = d.Set("items", flattened)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
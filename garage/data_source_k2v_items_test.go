@@ -0,0 +1,68 @@
+package garage
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestUnitDataSourceK2VItems(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: k2vBucketConfig(fake.providerConfig(), "app", `
+resource "garage_k2v_item" "test" {
+  for_each = toset(["2024-01", "2024-02", "2024-03", "2025-01"])
+
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = "reports"
+  sort_key      = each.key
+  value         = "report ${each.key}"
+}
+
+data "garage_k2v_items" "year" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = "reports"
+  prefix        = "2024-"
+
+  depends_on = [garage_k2v_item.test]
+}
+
+data "garage_k2v_items" "latest" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = "reports"
+  reverse       = true
+  limit         = 2
+
+  depends_on = [garage_k2v_item.test]
+}
+
+data "garage_k2v_items" "range" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = "reports"
+  start         = "2024-02"
+  end           = "2025"
+
+  depends_on = [garage_k2v_item.test]
+}
+`),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.garage_k2v_items.year", "items.#", "3"),
+						resource.TestCheckResourceAttr("data.garage_k2v_items.year", "items.0.sort_key", "2024-01"),
+						resource.TestCheckResourceAttr("data.garage_k2v_items.year", "items.0.value", "report 2024-01"),
+						resource.TestCheckResourceAttr("data.garage_k2v_items.latest", "items.#", "2"),
+						resource.TestCheckResourceAttr("data.garage_k2v_items.latest", "items.0.sort_key", "2025-01"),
+						resource.TestCheckResourceAttr("data.garage_k2v_items.latest", "items.1.sort_key", "2024-03"),
+						resource.TestCheckResourceAttr("data.garage_k2v_items.range", "items.#", "2"),
+						resource.TestCheckResourceAttr("data.garage_k2v_items.range", "items.1.sort_key", "2024-03"),
+					),
+				},
+			},
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package garage
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func schemaDataSourceKey() map[string]*schema.Schema {
+	s := computedSchema(schemaKey())
+	delete(s, "never_expires")
+	s["access_key_id"] = &schema.Schema{
+		Description:  "The access key ID of the key.",
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ExactlyOneOf: []string{"access_key_id", "search"},
+	}
+	s["search"] = &schema.Schema{
+		Description:  "Beginning of the access key ID, or part of the name, of the key. It must match a single key.",
+		Type:         schema.TypeString,
+		Optional:     true,
+		ExactlyOneOf: []string{"access_key_id", "search"},
+	}
+	return s
+}
+
+func dataSourceKey() *schema.Resource {
+	return &schema.Resource{
+		Description: "This data source can be used to read a Garage key, by access key ID or by searching it.",
+		ReadContext: dataSourceKeyRead,
+		Schema:      schemaDataSourceKey(),
+	}
+}
+
+func dataSourceKeyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	var keyInfo *accessKey
+	var err error
+	if accessKeyID, ok := d.GetOk("access_key_id"); ok {
+		keyInfo, err = p.client.GetKey(ctx, accessKeyID.(string))
+	} else {
+		keyInfo, err = p.client.SearchKey(ctx, d.Get("search").(string))
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(keyInfo.AccessKeyID)
+
+	return setKeyInfo(d, keyInfo)
+}
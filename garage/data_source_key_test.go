@@ -0,0 +1,38 @@
+package garage
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestUnitDataSourceKey(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name = "deploy-key"
+}
+
+data "garage_key" "by_id" {
+  access_key_id = garage_key.test.access_key_id
+}
+
+data "garage_key" "by_search" {
+  search = "deploy"
+
+  depends_on = [garage_key.test]
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.garage_key.by_id", "name", "deploy-key"),
+						resource.TestCheckResourceAttrPair("data.garage_key.by_id", "secret_access_key", "garage_key.test", "secret_access_key"),
+						resource.TestCheckResourceAttrPair("data.garage_key.by_search", "access_key_id", "garage_key.test", "access_key_id"),
+					),
+				},
+			},
+		}
+	})
+}
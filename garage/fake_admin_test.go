@@ -0,0 +1,935 @@
+package garage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const fakeAdminToken = "fake-admin-token"
+
+var (
+	fakeBucketNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	fakeAccessKeyID      = regexp.MustCompile(`^GK[0-9a-f]{24}$`)
+	fakeSecretAccessKey  = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// fakeGarage is an in-memory stand-in for the admin API of a single Garage
+// node, speaking either the v1 or the v2 API. It answers the errors Garage
+// answers for missing objects, taken aliases and invalid requests, so that
+// resources can be tested offline.
+type fakeGarage struct {
+	*httptest.Server
+	// s3 serves the S3 API of the node, see fake_s3_test.go.
+	s3 *httptest.Server
+	// k2v serves the K2V API of the node, see fake_k2v_test.go.
+	k2v *httptest.Server
+
+	apiVersion    int
+	garageVersion string
+	nodeID        string
+
+	mu          sync.Mutex
+	buckets     map[string]*fakeBucket
+	keys        map[string]*accessKey
+	deletedKeys map[string]bool
+	tokens      map[string]*adminToken
+	layout      clusterLayout
+	// uploads are the unfinished multipart uploads by upload ID.
+	uploads map[string]*fakeUpload
+	// failures are the errors operations, such as deleteKey, fail with
+	// until they are removed, to test failures Garage doesn't make easy.
+	failures map[string]*adminError
+}
+
+type fakeBucket struct {
+	id            string
+	created       string
+	globalAliases []string
+	// localAliases are the aliases of the bucket by access key ID.
+	localAliases map[string][]string
+	website      *bucketWebsiteConfig
+	quotas       bucketQuotas
+	permissions  map[string]bucketKeyPermissions
+	objects      map[string]*fakeObject
+	cors         *corsConfiguration
+	lifecycle    *lifecycleConfiguration
+	// websiteRouting are the redirects and routing rules of the website,
+	// only set through the S3 API.
+	websiteRouting *websiteConfiguration
+	// k2v are the K2V items by partition and sort key.
+	k2v map[string]map[string]*fakeK2VItem
+}
+
+// newFakeGarage starts a fake node serving the given admin API version, which
+// is stopped at the end of the test.
+func newFakeGarage(t *testing.T, apiVersion int) *fakeGarage {
+	t.Helper()
+
+	f := &fakeGarage{
+		apiVersion:    apiVersion,
+		garageVersion: fmt.Sprintf("v%d.0.0", apiVersion),
+		nodeID:        randomHex(32),
+		buckets:       map[string]*fakeBucket{},
+		keys:          map[string]*accessKey{},
+		deletedKeys:   map[string]bool{},
+		tokens:        map[string]*adminToken{},
+		uploads:       map[string]*fakeUpload{},
+		failures:      map[string]*adminError{},
+	}
+	f.layout = clusterLayout{Version: 1}
+	capacity := int64(1 << 30)
+	f.layout.Roles = []nodeRole{{ID: f.nodeID, Zone: "dc1", Capacity: &capacity, Tags: []string{}}}
+
+	f.Server = httptest.NewServer(f)
+	t.Cleanup(f.Close)
+	f.s3 = httptest.NewServer(http.HandlerFunc(f.serveS3))
+	t.Cleanup(f.s3.Close)
+	f.k2v = httptest.NewServer(http.HandlerFunc(f.serveK2V))
+	t.Cleanup(f.k2v.Close)
+
+	return f
+}
+
+// providerConfig is the provider block pointing to the fake node.
+func (f *fakeGarage) providerConfig() string {
+	return fmt.Sprintf(`
+provider "garage" {
+  endpoint     = %q
+  token        = %q
+  s3_endpoint  = %q
+  k2v_endpoint = %q
+}
+`, f.URL, fakeAdminToken, f.s3.URL, f.k2v.URL)
+}
+
+// client returns a garageClient of the API version the fake node speaks.
+func (f *fakeGarage) client() garageClient {
+	return newGarageClient(&adminClient{
+		endpoint:   f.URL,
+		apiVersion: f.apiVersion,
+		httpClient: f.Client(),
+		token:      fakeAdminToken,
+	})
+}
+
+// checkDestroyed fails when buckets, keys or admin tokens are left.
+func (f *fakeGarage) checkDestroyed(*terraform.State) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.buckets) > 0 || len(f.keys) > 0 || len(f.tokens) > 0 {
+		return fmt.Errorf("%d buckets, %d keys and %d admin tokens left", len(f.buckets), len(f.keys), len(f.tokens))
+	}
+	return nil
+}
+
+// withState runs check with the lock of the fake held.
+func (f *fakeGarage) withState(check func() error) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return check()
+	}
+}
+
+func randomHex(n int) string {
+	content := make([]byte, n/2)
+	_, _ = rand.Read(content)
+	return hex.EncodeToString(content)
+}
+
+func errNoSuchBucket(bucket string) *adminError {
+	return &adminError{StatusCode: http.StatusNotFound, Code: "NoSuchBucket", Message: fmt.Sprintf("Bucket not found: %s", bucket)}
+}
+
+func errNoSuchAccessKey(accessKeyID string) *adminError {
+	return &adminError{StatusCode: http.StatusNotFound, Code: "NoSuchAccessKey", Message: fmt.Sprintf("Access key not found: %s", accessKeyID)}
+}
+
+func errBucketAlreadyExists(alias string) *adminError {
+	return &adminError{StatusCode: http.StatusConflict, Code: "BucketAlreadyExists", Message: fmt.Sprintf("Bucket %s already exists", alias)}
+}
+
+func errKeyAlreadyExists(accessKeyID string) *adminError {
+	return &adminError{StatusCode: http.StatusConflict, Code: "KeyAlreadyExists", Message: fmt.Sprintf("Key %s already exists in data store. Even if it is deleted, we can't let you create a new key with the same ID.", accessKeyID)}
+}
+
+func errNoSuchAdminToken(id string) *adminError {
+	return &adminError{StatusCode: http.StatusNotFound, Code: "NoSuchAdminToken", Message: fmt.Sprintf("Admin token not found: %s", id)}
+}
+
+func errBadRequest(format string, args ...interface{}) *adminError {
+	return &adminError{StatusCode: http.StatusBadRequest, Code: "InvalidRequest", Message: fmt.Sprintf(format, args...)}
+}
+
+func errInvalidBucketName(alias string) *adminError {
+	return &adminError{StatusCode: http.StatusBadRequest, Code: "InvalidBucketName", Message: fmt.Sprintf("Invalid bucket name: %s", alias)}
+}
+
+func errInternal(message string) *adminError {
+	return &adminError{StatusCode: http.StatusInternalServerError, Code: "InternalError", Message: message}
+}
+
+func errNoSuchEndpoint(r *http.Request) *adminError {
+	return &adminError{StatusCode: http.StatusNotFound, Code: "NoSuchEndpoint", Message: fmt.Sprintf("Unknown API endpoint: %s %s", r.Method, r.URL.Path)}
+}
+
+func (f *fakeGarage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+fakeAdminToken {
+		writeFakeAnswer(w, nil, &adminError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: "Forbidden: Invalid authorization token"})
+		return
+	}
+
+	prefix := fmt.Sprintf("/v%d/", f.apiVersion)
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeFakeAnswer(w, nil, errNoSuchEndpoint(r))
+		return
+	}
+	endpoint := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var answer interface{}
+	var apiErr *adminError
+	if f.apiVersion >= 2 {
+		answer, apiErr = f.serveV2(r, endpoint)
+	} else {
+		answer, apiErr = f.serveV1(r, endpoint)
+	}
+	writeFakeAnswer(w, answer, apiErr)
+}
+
+func writeFakeAnswer(w http.ResponseWriter, answer interface{}, apiErr *adminError) {
+	w.Header().Set("Content-Type", "application/json")
+	if apiErr != nil {
+		w.WriteHeader(apiErr.StatusCode)
+		_ = json.NewEncoder(w).Encode(apiErr)
+		return
+	}
+	if answer == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(answer)
+}
+
+func decodeFakeRequest(r *http.Request, request interface{}) *adminError {
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil && !errors.Is(err, io.EOF) {
+		return errBadRequest("Invalid request body: %s", err)
+	}
+	return nil
+}
+
+// serveV1 answers the REST-style endpoints of the v1 API, which the SDK also
+// calls for the v0 API.
+func (f *fakeGarage) serveV1(r *http.Request, endpoint string) (interface{}, *adminError) {
+	query := r.URL.Query()
+
+	switch {
+	case endpoint == "status" && r.Method == http.MethodGet:
+		return clusterStatus{Node: f.nodeID, GarageVersion: f.garageVersion}, nil
+	case endpoint == "health" && r.Method == http.MethodGet:
+		return f.health(), nil
+	case endpoint == "layout" && r.Method == http.MethodGet:
+		return f.layout, nil
+	case endpoint == "layout" && r.Method == http.MethodPost:
+		var changes []nodeRoleChange
+		if apiErr := decodeFakeRequest(r, &changes); apiErr != nil {
+			return nil, apiErr
+		}
+		return f.stageLayoutChanges(changes)
+	case endpoint == "layout/apply" && r.Method == http.MethodPost:
+		return f.applyLayout(r)
+
+	case endpoint == "bucket" && r.Method == http.MethodGet:
+		if !query.Has("id") && !query.Has("globalAlias") {
+			return f.listBuckets(), nil
+		}
+		return f.getBucketInfo(query.Get("id"), query.Get("globalAlias"))
+	case endpoint == "bucket" && r.Method == http.MethodPost:
+		return f.createBucket(r)
+	case endpoint == "bucket" && r.Method == http.MethodPut:
+		return f.updateBucket(r, query.Get("id"))
+	case endpoint == "bucket" && r.Method == http.MethodDelete:
+		return nil, f.deleteBucket(query.Get("id"))
+	case endpoint == "bucket/allow" && r.Method == http.MethodPost:
+		return f.setBucketKey(r, true)
+	case endpoint == "bucket/deny" && r.Method == http.MethodPost:
+		return f.setBucketKey(r, false)
+	case endpoint == "bucket/alias/global" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		return f.setBucketAlias(r.Method == http.MethodPut, bucketAliasRequest{
+			BucketID:    query.Get("id"),
+			GlobalAlias: query.Get("alias"),
+		})
+	case endpoint == "bucket/alias/local" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		return f.setBucketAlias(r.Method == http.MethodPut, bucketAliasRequest{
+			BucketID:    query.Get("id"),
+			AccessKeyID: query.Get("accessKeyId"),
+			LocalAlias:  query.Get("alias"),
+		})
+
+	case endpoint == "key" && r.Method == http.MethodGet:
+		if !query.Has("id") && !query.Has("search") {
+			return f.listKeys(), nil
+		}
+		return f.getKeyInfo(query.Get("id"), query.Get("search"), query.Get("showSecretKey") == "true")
+	case endpoint == "key" && r.Method == http.MethodPost && query.Has("id"):
+		return f.updateKey(r, query.Get("id"))
+	case endpoint == "key" && r.Method == http.MethodPost:
+		return f.createKey(r)
+	case endpoint == "key/import" && r.Method == http.MethodPost:
+		return f.importKey(r)
+	case endpoint == "key" && r.Method == http.MethodDelete:
+		return nil, f.deleteKey(query.Get("id"))
+	}
+
+	return nil, errNoSuchEndpoint(r)
+}
+
+// serveV2 answers the RPC-style endpoints of the v2 API.
+func (f *fakeGarage) serveV2(r *http.Request, endpoint string) (interface{}, *adminError) {
+	query := r.URL.Query()
+
+	switch endpoint {
+	case "GetNodeInfo":
+		return nodeInfoResponse{
+			Success: map[string]clusterStatus{
+				f.nodeID: {GarageVersion: f.garageVersion},
+			},
+		}, nil
+	case "GetClusterHealth":
+		return f.health(), nil
+	case "GetClusterLayout":
+		return f.layout, nil
+	case "UpdateClusterLayout":
+		request := updateClusterLayoutRequest{}
+		if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+			return nil, apiErr
+		}
+		return f.stageLayoutChanges(request.Roles)
+	case "ApplyClusterLayout":
+		return f.applyLayout(r)
+
+	case "ListBuckets":
+		return f.listBuckets(), nil
+	case "GetBucketInfo":
+		return f.getBucketInfo(query.Get("id"), query.Get("globalAlias"))
+	case "CreateBucket":
+		return f.createBucket(r)
+	case "UpdateBucket":
+		return f.updateBucket(r, query.Get("id"))
+	case "DeleteBucket":
+		return nil, f.deleteBucket(query.Get("id"))
+	case "AllowBucketKey":
+		return f.setBucketKey(r, true)
+	case "DenyBucketKey":
+		return f.setBucketKey(r, false)
+	case "AddBucketAlias", "RemoveBucketAlias":
+		request := bucketAliasRequest{}
+		if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+			return nil, apiErr
+		}
+		return f.setBucketAlias(endpoint == "AddBucketAlias", request)
+
+	case "ListKeys":
+		return f.listKeys(), nil
+	case "GetKeyInfo":
+		return f.getKeyInfo(query.Get("id"), query.Get("search"), query.Get("showSecretKey") == "true")
+	case "CreateKey":
+		return f.createKey(r)
+	case "ImportKey":
+		return f.importKey(r)
+	case "UpdateKey":
+		return f.updateKey(r, query.Get("id"))
+	case "DeleteKey":
+		return nil, f.deleteKey(query.Get("id"))
+
+	case "GetAdminTokenInfo":
+		return f.getAdminToken(query.Get("id"))
+	case "CreateAdminToken":
+		return f.createAdminToken(r)
+	case "UpdateAdminToken":
+		return f.updateAdminToken(r, query.Get("id"))
+	case "DeleteAdminToken":
+		if _, ok := f.tokens[query.Get("id")]; !ok {
+			return nil, errNoSuchAdminToken(query.Get("id"))
+		}
+		delete(f.tokens, query.Get("id"))
+		return nil, nil
+	}
+
+	return nil, errNoSuchEndpoint(r)
+}
+
+func (f *fakeGarage) health() clusterHealth {
+	return clusterHealth{
+		Status:           "healthy",
+		KnownNodes:       1,
+		ConnectedNodes:   1,
+		StorageNodes:     1,
+		StorageNodesOk:   1,
+		StorageNodesUp:   1,
+		Partitions:       256,
+		PartitionsQuorum: 256,
+		PartitionsAllOk:  256,
+	}
+}
+
+func (f *fakeGarage) stageLayoutChanges(changes []nodeRoleChange) (interface{}, *adminError) {
+	for _, change := range changes {
+		staged := f.layout.StagedRoleChanges[:0]
+		for _, stagedChange := range f.layout.StagedRoleChanges {
+			if stagedChange.ID != change.ID {
+				staged = append(staged, stagedChange)
+			}
+		}
+		f.layout.StagedRoleChanges = append(staged, change)
+	}
+	return f.layout, nil
+}
+
+func (f *fakeGarage) applyLayout(r *http.Request) (interface{}, *adminError) {
+	request := applyClusterLayoutRequest{}
+	if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+		return nil, apiErr
+	}
+	if request.Version != f.layout.Version+1 {
+		return nil, errBadRequest("Invalid new layout version: expected %d, got %d", f.layout.Version+1, request.Version)
+	}
+
+	roles := map[string]nodeRole{}
+	for _, role := range f.layout.Roles {
+		roles[role.ID] = role
+	}
+	for _, change := range f.layout.StagedRoleChanges {
+		if change.Remove {
+			delete(roles, change.ID)
+			continue
+		}
+		roles[change.ID] = nodeRole{ID: change.ID, Zone: change.Zone, Capacity: change.Capacity, Tags: change.Tags}
+	}
+
+	f.layout.Roles = []nodeRole{}
+	for _, role := range roles {
+		f.layout.Roles = append(f.layout.Roles, role)
+	}
+	sort.Slice(f.layout.Roles, func(i, j int) bool {
+		return f.layout.Roles[i].ID < f.layout.Roles[j].ID
+	})
+	f.layout.StagedRoleChanges = nil
+	f.layout.Version = request.Version
+
+	return applyClusterLayoutResponse{
+		Message: []string{"Layout applied"},
+		Layout:  f.layout,
+	}, nil
+}
+
+// bucketByAlias returns the ID of the bucket with the given global alias.
+func (f *fakeGarage) bucketByAlias(alias string) (string, bool) {
+	for id, b := range f.buckets {
+		for _, globalAlias := range b.globalAliases {
+			if globalAlias == alias {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (f *fakeGarage) bucketInfo(b *fakeBucket) *bucket {
+	bucketInfo := &bucket{
+		ID:            b.id,
+		GlobalAliases: append([]string{}, b.globalAliases...),
+		WebsiteAccess: b.website != nil,
+		WebsiteConfig: b.website,
+		Keys:          []bucketKey{},
+		Quotas:        b.quotas,
+	}
+	if f.apiVersion >= 2 {
+		bucketInfo.Created = b.created
+	}
+
+	accessKeyIDs := map[string]bool{}
+	for accessKeyID := range b.permissions {
+		accessKeyIDs[accessKeyID] = true
+	}
+	for accessKeyID := range b.localAliases {
+		accessKeyIDs[accessKeyID] = true
+	}
+	for accessKeyID := range accessKeyIDs {
+		bucketInfo.Keys = append(bucketInfo.Keys, bucketKey{
+			AccessKeyID:        accessKeyID,
+			Name:               f.keys[accessKeyID].Name,
+			Permissions:        b.permissions[accessKeyID],
+			BucketLocalAliases: append([]string{}, b.localAliases[accessKeyID]...),
+		})
+	}
+	sort.Slice(bucketInfo.Keys, func(i, j int) bool {
+		return bucketInfo.Keys[i].AccessKeyID < bucketInfo.Keys[j].AccessKeyID
+	})
+
+	return bucketInfo
+}
+
+func (f *fakeGarage) listBuckets() []bucketListItem {
+	buckets := []bucketListItem{}
+	for _, b := range f.buckets {
+		item := bucketListItem{
+			ID:            b.id,
+			GlobalAliases: append([]string{}, b.globalAliases...),
+			LocalAliases:  []bucketLocalAlias{},
+		}
+		for accessKeyID, aliases := range b.localAliases {
+			for _, alias := range aliases {
+				item.LocalAliases = append(item.LocalAliases, bucketLocalAlias{AccessKeyID: accessKeyID, Alias: alias})
+			}
+		}
+		buckets = append(buckets, item)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].ID < buckets[j].ID
+	})
+	return buckets
+}
+
+func (f *fakeGarage) getBucketInfo(id string, globalAlias string) (interface{}, *adminError) {
+	if id == "" && globalAlias != "" {
+		aliasedID, ok := f.bucketByAlias(globalAlias)
+		if !ok {
+			return nil, errNoSuchBucket(globalAlias)
+		}
+		id = aliasedID
+	}
+
+	b, ok := f.buckets[id]
+	if !ok {
+		return nil, errNoSuchBucket(id)
+	}
+	return f.bucketInfo(b), nil
+}
+
+func (f *fakeGarage) createBucket(r *http.Request) (interface{}, *adminError) {
+	request := struct {
+		GlobalAlias string `json:"globalAlias"`
+	}{}
+	if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+		return nil, apiErr
+	}
+
+	b := &fakeBucket{
+		id:           randomHex(64),
+		created:      time.Now().UTC().Format(time.RFC3339),
+		localAliases: map[string][]string{},
+		permissions:  map[string]bucketKeyPermissions{},
+		objects:      map[string]*fakeObject{},
+	}
+	if request.GlobalAlias != "" {
+		if !fakeBucketNameRegexp.MatchString(request.GlobalAlias) {
+			return nil, errInvalidBucketName(request.GlobalAlias)
+		}
+		if _, taken := f.bucketByAlias(request.GlobalAlias); taken {
+			return nil, errBucketAlreadyExists(request.GlobalAlias)
+		}
+		b.globalAliases = []string{request.GlobalAlias}
+	}
+
+	f.buckets[b.id] = b
+	return f.bucketInfo(b), nil
+}
+
+func (f *fakeGarage) updateBucket(r *http.Request, id string) (interface{}, *adminError) {
+	b, ok := f.buckets[id]
+	if !ok {
+		return nil, errNoSuchBucket(id)
+	}
+
+	request := updateBucketRequest{}
+	if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+		return nil, apiErr
+	}
+
+	if website := request.WebsiteAccess; website != nil {
+		b.websiteRouting = nil
+		if !website.Enabled {
+			if website.IndexDocument != nil || website.ErrorDocument != nil {
+				return nil, errBadRequest("Cannot specify indexDocument or errorDocument when disabling website access")
+			}
+			b.website = nil
+		} else {
+			if website.IndexDocument == nil {
+				return nil, errBadRequest("Please specify indexDocument when enabling website access.")
+			}
+			b.website = &bucketWebsiteConfig{
+				IndexDocument: *website.IndexDocument,
+				ErrorDocument: website.ErrorDocument,
+			}
+		}
+	}
+	if request.Quotas != nil {
+		b.quotas = *request.Quotas
+	}
+
+	return f.bucketInfo(b), nil
+}
+
+func (f *fakeGarage) deleteBucket(id string) *adminError {
+	b, ok := f.buckets[id]
+	if !ok {
+		return errNoSuchBucket(id)
+	}
+	if len(b.objects) > 0 {
+		return &adminError{StatusCode: http.StatusConflict, Code: "BucketNotEmpty", Message: "Tried to delete a non-empty bucket"}
+	}
+	delete(f.buckets, id)
+	return nil
+}
+
+func (f *fakeGarage) setBucketKey(r *http.Request, allow bool) (interface{}, *adminError) {
+	request := bucketKeyRequest{}
+	if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+		return nil, apiErr
+	}
+
+	b, ok := f.buckets[request.BucketID]
+	if !ok {
+		return nil, errNoSuchBucket(request.BucketID)
+	}
+	if _, ok := f.keys[request.AccessKeyID]; !ok {
+		return nil, errNoSuchAccessKey(request.AccessKeyID)
+	}
+
+	permissions := b.permissions[request.AccessKeyID]
+	if allow {
+		permissions.Read = permissions.Read || request.Permissions.Read
+		permissions.Write = permissions.Write || request.Permissions.Write
+		permissions.Owner = permissions.Owner || request.Permissions.Owner
+	} else {
+		permissions.Read = permissions.Read && !request.Permissions.Read
+		permissions.Write = permissions.Write && !request.Permissions.Write
+		permissions.Owner = permissions.Owner && !request.Permissions.Owner
+	}
+
+	if permissions == (bucketKeyPermissions{}) {
+		delete(b.permissions, request.AccessKeyID)
+	} else {
+		b.permissions[request.AccessKeyID] = permissions
+	}
+
+	return f.bucketInfo(b), nil
+}
+
+// setBucketAlias adds or removes the global or local alias of request.
+func (f *fakeGarage) setBucketAlias(add bool, request bucketAliasRequest) (interface{}, *adminError) {
+	b, ok := f.buckets[request.BucketID]
+	if !ok {
+		return nil, errNoSuchBucket(request.BucketID)
+	}
+
+	if request.AccessKeyID == "" {
+		alias := request.GlobalAlias
+		if add {
+			if !fakeBucketNameRegexp.MatchString(alias) {
+				return nil, errInvalidBucketName(alias)
+			}
+			if aliasedID, taken := f.bucketByAlias(alias); taken {
+				if aliasedID != b.id {
+					return nil, errBucketAlreadyExists(alias)
+				}
+				return f.bucketInfo(b), nil
+			}
+			b.globalAliases = append(b.globalAliases, alias)
+			return f.bucketInfo(b), nil
+		}
+
+		aliases, removed := removeString(b.globalAliases, alias)
+		if !removed {
+			return nil, errBadRequest("Bucket %s doesn't have global alias %s", b.id, alias)
+		}
+		b.globalAliases = aliases
+		return f.bucketInfo(b), nil
+	}
+
+	if _, ok := f.keys[request.AccessKeyID]; !ok {
+		return nil, errNoSuchAccessKey(request.AccessKeyID)
+	}
+	alias := request.LocalAlias
+	if add {
+		if !fakeBucketNameRegexp.MatchString(alias) {
+			return nil, errInvalidBucketName(alias)
+		}
+		for id, other := range f.buckets {
+			for _, localAlias := range other.localAliases[request.AccessKeyID] {
+				if localAlias != alias {
+					continue
+				}
+				if id != b.id {
+					return nil, errBucketAlreadyExists(alias)
+				}
+				return f.bucketInfo(b), nil
+			}
+		}
+		b.localAliases[request.AccessKeyID] = append(b.localAliases[request.AccessKeyID], alias)
+		return f.bucketInfo(b), nil
+	}
+
+	aliases, removed := removeString(b.localAliases[request.AccessKeyID], alias)
+	if !removed {
+		return nil, errBadRequest("Bucket %s doesn't have local alias %s for key %s", b.id, alias, request.AccessKeyID)
+	}
+	if len(aliases) == 0 {
+		delete(b.localAliases, request.AccessKeyID)
+	} else {
+		b.localAliases[request.AccessKeyID] = aliases
+	}
+	return f.bucketInfo(b), nil
+}
+
+func removeString(values []string, value string) ([]string, bool) {
+	for i, v := range values {
+		if v == value {
+			return append(values[:i:i], values[i+1:]...), true
+		}
+	}
+	return values, false
+}
+
+func (f *fakeGarage) listKeys() []keyListItem {
+	keys := []keyListItem{}
+	for _, key := range f.keys {
+		keys = append(keys, keyListItem{ID: key.AccessKeyID, Name: key.Name})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// keyInfo answers key, whose expiration only the v2 API shows.
+func (f *fakeGarage) keyInfo(key *accessKey, showSecretKey bool) *accessKey {
+	keyInfo := *key
+	if !showSecretKey {
+		keyInfo.SecretAccessKey = ""
+	}
+	if f.apiVersion < 2 {
+		keyInfo.Expiration = nil
+	}
+	if keyInfo.Expiration != nil {
+		expiration, _ := time.Parse(time.RFC3339, *keyInfo.Expiration)
+		keyInfo.Expired = expiration.Before(time.Now())
+	}
+	return &keyInfo
+}
+
+// getKeyInfo looks a key up by ID, or searches for the single key whose ID
+// starts with search or whose name contains it, as Garage does.
+func (f *fakeGarage) getKeyInfo(id string, search string, showSecretKey bool) (interface{}, *adminError) {
+	if id != "" {
+		key, ok := f.keys[id]
+		if !ok {
+			return nil, errNoSuchAccessKey(id)
+		}
+		return f.keyInfo(key, showSecretKey), nil
+	}
+
+	var matches []*accessKey
+	for _, key := range f.keys {
+		if strings.HasPrefix(key.AccessKeyID, search) || strings.Contains(strings.ToLower(key.Name), strings.ToLower(search)) {
+			matches = append(matches, key)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, errNoSuchAccessKey(search)
+	case 1:
+		return f.keyInfo(matches[0], showSecretKey), nil
+	}
+	return nil, errBadRequest("%d matching keys", len(matches))
+}
+
+func (f *fakeGarage) createKey(r *http.Request) (interface{}, *adminError) {
+	request := createKeyRequest{}
+	if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+		return nil, apiErr
+	}
+
+	key := &accessKey{
+		AccessKeyID:     "GK" + randomHex(24),
+		Name:            request.Name,
+		SecretAccessKey: randomHex(64),
+	}
+	f.keys[key.AccessKeyID] = key
+	return f.keyInfo(key, true), nil
+}
+
+func (f *fakeGarage) importKey(r *http.Request) (interface{}, *adminError) {
+	request := importKeyRequest{}
+	if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+		return nil, apiErr
+	}
+
+	if !fakeAccessKeyID.MatchString(request.AccessKeyID) {
+		return nil, errBadRequest("The specified key ID is not a valid Garage key ID (starts with `GK`, followed by 12 hex-encoded bytes)")
+	}
+	if !fakeSecretAccessKey.MatchString(request.SecretAccessKey) {
+		return nil, errBadRequest("The specified secret key is not a valid Garage secret key (composed of 32 hex-encoded bytes)")
+	}
+	if _, ok := f.keys[request.AccessKeyID]; ok || f.deletedKeys[request.AccessKeyID] {
+		return nil, errKeyAlreadyExists(request.AccessKeyID)
+	}
+
+	key := &accessKey{
+		AccessKeyID:     request.AccessKeyID,
+		Name:            request.Name,
+		SecretAccessKey: request.SecretAccessKey,
+	}
+	f.keys[key.AccessKeyID] = key
+	return f.keyInfo(key, true), nil
+}
+
+func (f *fakeGarage) updateKey(r *http.Request, id string) (interface{}, *adminError) {
+	key, ok := f.keys[id]
+	if !ok {
+		return nil, errNoSuchAccessKey(id)
+	}
+
+	request := keyUpdate{}
+	if apiErr := decodeFakeRequest(r, &request); apiErr != nil {
+		return nil, apiErr
+	}
+
+	if request.Expiration != nil && request.NeverExpires {
+		return nil, errBadRequest("cannot specify `expiration` and `neverExpires` at the same time")
+	}
+
+	if request.Name != nil {
+		key.Name = *request.Name
+	}
+	if request.Expiration != nil {
+		expiration, err := time.Parse(time.RFC3339, *request.Expiration)
+		if err != nil {
+			return nil, errBadRequest("invalid expiration: %s", err)
+		}
+		// Garage answers dates in UTC.
+		utc := expiration.UTC().Format(time.RFC3339)
+		key.Expiration = &utc
+	}
+	if request.NeverExpires {
+		key.Expiration = nil
+	}
+	if request.Allow != nil && request.Allow.CreateBucket {
+		key.Permissions.CreateBucket = true
+	}
+	if request.Deny != nil && request.Deny.CreateBucket {
+		key.Permissions.CreateBucket = false
+	}
+
+	return f.keyInfo(key, true), nil
+}
+
+// deleteKey deletes a key along with its permissions and local aliases. Its
+// ID can't be imported again.
+func (f *fakeGarage) deleteKey(id string) *adminError {
+	if apiErr := f.failures["deleteKey"]; apiErr != nil {
+		return apiErr
+	}
+	if _, ok := f.keys[id]; !ok {
+		return errNoSuchAccessKey(id)
+	}
+
+	for _, b := range f.buckets {
+		delete(b.permissions, id)
+		delete(b.localAliases, id)
+	}
+	delete(f.keys, id)
+	f.deletedKeys[id] = true
+	return nil
+}
+
+func (f *fakeGarage) getAdminToken(id string) (interface{}, *adminError) {
+	token, ok := f.tokens[id]
+	if !ok {
+		return nil, errNoSuchAdminToken(id)
+	}
+	tokenInfo := *token
+	tokenInfo.SecretToken = ""
+	return &tokenInfo, nil
+}
+
+func (f *fakeGarage) setAdminToken(token *adminToken, update adminTokenUpdate) *adminError {
+	if update.Expiration != nil && update.NeverExpires {
+		return errBadRequest("cannot specify `expiration` and `neverExpires` at the same time")
+	}
+
+	token.Name = update.Name
+	if update.Expiration != nil {
+		expiration, err := time.Parse(time.RFC3339, *update.Expiration)
+		if err != nil {
+			return errBadRequest("invalid expiration: %s", err)
+		}
+		token.Expiration = update.Expiration
+		token.Expired = expiration.Before(time.Now())
+	}
+	if update.NeverExpires {
+		token.Expiration = nil
+		token.Expired = false
+	}
+	if update.Scope != nil {
+		token.Scope = update.Scope
+	}
+	return nil
+}
+
+func (f *fakeGarage) createAdminToken(r *http.Request) (interface{}, *adminError) {
+	update := adminTokenUpdate{}
+	if apiErr := decodeFakeRequest(r, &update); apiErr != nil {
+		return nil, apiErr
+	}
+
+	token := &adminToken{
+		ID:      randomHex(24),
+		Created: time.Now().UTC().Format(time.RFC3339),
+		Scope:   []string{"*"},
+	}
+	if apiErr := f.setAdminToken(token, update); apiErr != nil {
+		return nil, apiErr
+	}
+	f.tokens[token.ID] = token
+
+	tokenInfo := *token
+	tokenInfo.SecretToken = randomHex(64)
+	return &tokenInfo, nil
+}
+
+func (f *fakeGarage) updateAdminToken(r *http.Request, id string) (interface{}, *adminError) {
+	token, ok := f.tokens[id]
+	if !ok {
+		return nil, errNoSuchAdminToken(id)
+	}
+
+	update := adminTokenUpdate{}
+	if apiErr := decodeFakeRequest(r, &update); apiErr != nil {
+		return nil, apiErr
+	}
+	if apiErr := f.setAdminToken(token, update); apiErr != nil {
+		return nil, apiErr
+	}
+
+	return f.getAdminToken(id)
+}
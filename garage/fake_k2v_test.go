@@ -0,0 +1,298 @@
+package garage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fakeK2VValue is a value of an item of the fake node, nil for tombstones,
+// along with the clock of the item when it was written.
+type fakeK2VValue struct {
+	clock uint64
+	value []byte
+}
+
+// fakeK2VItem is an item of the fake node. Its causality tokens are its
+// clock when read, and writes supersede the values written before it.
+type fakeK2VItem struct {
+	clock  uint64
+	values []fakeK2VValue
+}
+
+func (item *fakeK2VItem) causalityToken() string {
+	return base64.StdEncoding.EncodeToString([]byte(strconv.FormatUint(item.clock, 10)))
+}
+
+// write supersedes the values known by causalityToken, if any, with value.
+func (item *fakeK2VItem) write(causalityToken string, value []byte) *k2vError {
+	known := uint64(0)
+	if causalityToken != "" {
+		decoded, err := base64.StdEncoding.DecodeString(causalityToken)
+		if err == nil {
+			known, err = strconv.ParseUint(string(decoded), 10, 64)
+		}
+		if err != nil {
+			return errK2V(http.StatusBadRequest, "InvalidCausalityToken", "Invalid causality token")
+		}
+	}
+
+	values := []fakeK2VValue{}
+	for _, v := range item.values {
+		if v.clock > known {
+			values = append(values, v)
+		}
+	}
+	item.clock++
+	item.values = append(values, fakeK2VValue{clock: item.clock, value: value})
+	return nil
+}
+
+func (item *fakeK2VItem) encodedValues() []*string {
+	encoded := make([]*string, len(item.values))
+	for i, v := range item.values {
+		if v.value != nil {
+			value := base64.StdEncoding.EncodeToString(v.value)
+			encoded[i] = &value
+		}
+	}
+	return encoded
+}
+
+func (item *fakeK2VItem) isTombstone() bool {
+	for _, v := range item.values {
+		if v.value != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// k2vError answered by the fake, written by writeFakeK2VError.
+func errK2V(statusCode int, code string, format string, args ...interface{}) *k2vError {
+	return &k2vError{StatusCode: statusCode, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func writeFakeK2VError(w http.ResponseWriter, apiErr *k2vError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.StatusCode)
+	_ = json.NewEncoder(w).Encode(apiErr)
+}
+
+func writeFakeK2VAnswer(w http.ResponseWriter, header http.Header, answer interface{}) {
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(answer)
+}
+
+// serveK2V answers the K2V requests of the resources and data sources,
+// signed and checked against permissions like serveS3.
+func (f *fakeGarage) serveK2V(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	accessKeyID, err := verifySigV4(r, "k2v", func(accessKeyID string) (string, bool) {
+		key, ok := f.keys[accessKeyID]
+		if !ok {
+			return "", false
+		}
+		return key.SecretAccessKey, true
+	})
+	if err != nil {
+		writeFakeK2VError(w, errK2V(http.StatusForbidden, "AccessDenied", "Forbidden: %s", err))
+		return
+	}
+
+	name, partitionKey, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	b := f.bucketByS3Name(name, accessKeyID)
+	if b == nil {
+		writeFakeK2VError(w, errK2V(http.StatusNotFound, "NoSuchBucket", "Bucket not found: %s", name))
+		return
+	}
+	permissions := b.permissions[accessKeyID]
+	if (r.Method == http.MethodGet || (r.Method == http.MethodPost && r.URL.Query().Has("search"))) && !permissions.Read ||
+		(r.Method == http.MethodPut || r.Method == http.MethodDelete) && !permissions.Write {
+		writeFakeK2VError(w, errK2V(http.StatusForbidden, "AccessDenied", "Forbidden: Operation is not allowed for this key."))
+		return
+	}
+	if b.k2v == nil {
+		b.k2v = map[string]map[string]*fakeK2VItem{}
+	}
+
+	var apiErr *k2vError
+	switch {
+	case partitionKey != "":
+		apiErr = f.serveK2VItem(w, r, b, partitionKey)
+	case r.Method == http.MethodGet:
+		apiErr = f.serveK2VIndex(w, r, b)
+	case r.Method == http.MethodPost && r.URL.Query().Has("search"):
+		apiErr = f.serveK2VBatch(w, r, b)
+	default:
+		apiErr = errK2V(http.StatusNotImplemented, "NotImplemented", "Unsupported request %s %s", r.Method, r.URL)
+	}
+	if apiErr != nil {
+		writeFakeK2VError(w, apiErr)
+	}
+}
+
+func (f *fakeGarage) serveK2VItem(w http.ResponseWriter, r *http.Request, b *fakeBucket, partitionKey string) *k2vError {
+	query := r.URL.Query()
+	if !query.Has("sort_key") {
+		return errK2V(http.StatusBadRequest, "InvalidRequest", "Missing sort_key")
+	}
+	sortKey := query.Get("sort_key")
+	item := b.k2v[partitionKey][sortKey]
+	causalityToken := r.Header.Get(k2vCausalityTokenHeader)
+
+	switch r.Method {
+	case http.MethodGet:
+		if item == nil {
+			return errK2V(http.StatusNotFound, "NoSuchKey", "Key not found")
+		}
+		if r.Header.Get("Accept") != "application/json" {
+			return errK2V(http.StatusBadRequest, "InvalidRequest", "Only JSON answers are supported by the fake")
+		}
+		writeFakeK2VAnswer(w, http.Header{k2vCausalityTokenHeader: {item.causalityToken()}}, item.encodedValues())
+	case http.MethodPut:
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			return errK2V(http.StatusBadRequest, "InvalidRequest", "Failed to read the value")
+		}
+		if item == nil {
+			item = &fakeK2VItem{}
+		}
+		if apiErr := item.write(causalityToken, value); apiErr != nil {
+			return apiErr
+		}
+		if b.k2v[partitionKey] == nil {
+			b.k2v[partitionKey] = map[string]*fakeK2VItem{}
+		}
+		b.k2v[partitionKey][sortKey] = item
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if causalityToken == "" {
+			return errK2V(http.StatusBadRequest, "InvalidRequest", "Causality token required to delete an item")
+		}
+		if item == nil {
+			return errK2V(http.StatusNotFound, "NoSuchKey", "Key not found")
+		}
+		if apiErr := item.write(causalityToken, nil); apiErr != nil {
+			return apiErr
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		return errK2V(http.StatusNotImplemented, "NotImplemented", "Unsupported request %s %s", r.Method, r.URL)
+	}
+	return nil
+}
+
+// serveK2VIndex answers ReadIndex, counting the items of each partition.
+func (f *fakeGarage) serveK2VIndex(w http.ResponseWriter, r *http.Request, b *fakeBucket) *k2vError {
+	query := r.URL.Query()
+	limit := -1
+	if query.Has("limit") {
+		var err error
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 0 {
+			return errK2V(http.StatusBadRequest, "InvalidRequest", "Invalid limit")
+		}
+	}
+
+	partitionKeys := make([]string, 0, len(b.k2v))
+	for partitionKey := range b.k2v {
+		partitionKeys = append(partitionKeys, partitionKey)
+	}
+	sort.Strings(partitionKeys)
+
+	index := k2vIndex{PartitionKeys: []k2vPartition{}}
+	for _, partitionKey := range partitionKeys {
+		if !strings.HasPrefix(partitionKey, query.Get("prefix")) || partitionKey < query.Get("start") || (query.Has("end") && partitionKey >= query.Get("end")) {
+			continue
+		}
+		partition := k2vPartition{PartitionKey: partitionKey}
+		for _, item := range b.k2v[partitionKey] {
+			if item.isTombstone() {
+				continue
+			}
+			partition.Entries++
+			if len(item.values) > 1 {
+				partition.Conflicts++
+			}
+			for _, v := range item.values {
+				if v.value != nil {
+					partition.Values++
+					partition.Bytes += int64(len(v.value))
+				}
+			}
+		}
+		if partition.Entries == 0 {
+			continue
+		}
+		if len(index.PartitionKeys) == limit {
+			index.More = true
+			index.NextStart = &partition.PartitionKey
+			break
+		}
+		index.PartitionKeys = append(index.PartitionKeys, partition)
+	}
+
+	writeFakeK2VAnswer(w, nil, index)
+	return nil
+}
+
+// serveK2VBatch answers ReadBatch.
+func (f *fakeGarage) serveK2VBatch(w http.ResponseWriter, r *http.Request, b *fakeBucket) *k2vError {
+	var queries []k2vBatchQuery
+	if err := json.NewDecoder(r.Body).Decode(&queries); err != nil {
+		return errK2V(http.StatusBadRequest, "InvalidRequest", "Invalid JSON: %s", err)
+	}
+
+	results := make([]k2vBatchResult, len(queries))
+	for i, query := range queries {
+		results[i] = k2vBatchResult{PartitionKey: query.PartitionKey, Items: []k2vBatchItem{}}
+
+		sortKeys := make([]string, 0, len(b.k2v[query.PartitionKey]))
+		for sortKey := range b.k2v[query.PartitionKey] {
+			sortKeys = append(sortKeys, sortKey)
+		}
+		sort.Strings(sortKeys)
+		if query.Reverse {
+			sort.Sort(sort.Reverse(sort.StringSlice(sortKeys)))
+		}
+
+		for _, sortKey := range sortKeys {
+			item := b.k2v[query.PartitionKey][sortKey]
+			switch {
+			case query.SingleItem && (query.Start == nil || sortKey != *query.Start),
+				query.Prefix != nil && !strings.HasPrefix(sortKey, *query.Prefix),
+				query.Start != nil && !query.Reverse && sortKey < *query.Start,
+				query.Start != nil && query.Reverse && sortKey > *query.Start,
+				query.End != nil && !query.Reverse && sortKey >= *query.End,
+				query.End != nil && query.Reverse && sortKey <= *query.End,
+				!query.Tombstones && item.isTombstone():
+				continue
+			}
+			if query.Limit != nil && len(results[i].Items) == *query.Limit {
+				results[i].More = true
+				nextStart := sortKey
+				results[i].NextStart = &nextStart
+				break
+			}
+			results[i].Items = append(results[i].Items, k2vBatchItem{
+				SortKey:        sortKey,
+				CausalityToken: item.causalityToken(),
+				Values:         item.encodedValues(),
+			})
+		}
+	}
+
+	writeFakeK2VAnswer(w, nil, results)
+	return nil
+}
@@ -0,0 +1,505 @@
+package garage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fakeObject is an object stored in a bucket of the fake node.
+type fakeObject struct {
+	s3ObjectMetadata
+	content      []byte
+	etag         string
+	lastModified time.Time
+}
+
+// fakeUpload is an unfinished multipart upload.
+type fakeUpload struct {
+	bucketID string
+	key      string
+	metadata s3ObjectMetadata
+	parts    map[int][]byte
+}
+
+// s3Error answered by the fake, written by writeFakeS3Error.
+func errS3(statusCode int, code string, format string, args ...interface{}) *s3Error {
+	return &s3Error{StatusCode: statusCode, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func writeFakeS3Error(w http.ResponseWriter, r *http.Request, apiErr *s3Error) {
+	w.WriteHeader(apiErr.StatusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: apiErr.Code, Message: apiErr.Message})
+}
+
+func writeFakeS3Answer(w http.ResponseWriter, answer interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(answer)
+}
+
+// serveS3 answers the path-style S3 requests of the resources, signed with
+// the secret of a key of the fake and checked against its permissions on the
+// bucket, which is addressed by a global alias or a local alias of the key.
+func (f *fakeGarage) serveS3(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	accessKeyID, err := verifySigV4(r, "s3", func(accessKeyID string) (string, bool) {
+		key, ok := f.keys[accessKeyID]
+		if !ok {
+			return "", false
+		}
+		return key.SecretAccessKey, true
+	})
+	if err != nil {
+		writeFakeS3Error(w, r, errS3(http.StatusForbidden, "AccessDenied", "Forbidden: %s", err))
+		return
+	}
+
+	name, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if name == "" {
+		writeFakeS3Error(w, r, errS3(http.StatusNotImplemented, "NotImplemented", "Service requests are not implemented"))
+		return
+	}
+	b := f.bucketByS3Name(name, accessKeyID)
+	if b == nil {
+		writeFakeS3Error(w, r, errS3(http.StatusNotFound, "NoSuchBucket", "Bucket not found: %s", name))
+		return
+	}
+	permissions := b.permissions[accessKeyID]
+
+	var apiErr *s3Error
+	if key == "" {
+		apiErr = f.serveS3Bucket(w, r, b, permissions)
+	} else {
+		apiErr = f.serveS3Object(w, r, b, key, permissions)
+	}
+	if apiErr != nil {
+		writeFakeS3Error(w, r, apiErr)
+	}
+}
+
+func (f *fakeGarage) bucketByS3Name(name string, accessKeyID string) *fakeBucket {
+	if id, ok := f.bucketByAlias(name); ok {
+		return f.buckets[id]
+	}
+	for _, b := range f.buckets {
+		for _, alias := range b.localAliases[accessKeyID] {
+			if alias == name {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+func errS3AccessDenied() *s3Error {
+	return errS3(http.StatusForbidden, "AccessDenied", "Forbidden: Operation is not allowed for this key.")
+}
+
+func (f *fakeGarage) serveS3Object(w http.ResponseWriter, r *http.Request, b *fakeBucket, key string, permissions bucketKeyPermissions) *s3Error {
+	query := r.URL.Query()
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if !permissions.Read {
+			return errS3AccessDenied()
+		}
+	} else if !permissions.Write {
+		return errS3AccessDenied()
+	}
+
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := b.objects[key]
+		if !ok {
+			return errS3(http.StatusNotFound, "NoSuchKey", "Key not found")
+		}
+		header := object.header()
+		header.Set("ETag", strconv.Quote(object.etag))
+		header.Set("Content-Length", strconv.Itoa(len(object.content)))
+		header.Set("Last-Modified", object.lastModified.Format(http.TimeFormat))
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(object.content)
+		}
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		upload, apiErr := f.upload(query.Get("uploadId"), b, key)
+		if apiErr != nil {
+			return apiErr
+		}
+		partNumber, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil || partNumber < 1 || partNumber > 10000 {
+			return errS3(http.StatusBadRequest, "InvalidArgument", "Invalid part number")
+		}
+		content, _ := io.ReadAll(r.Body)
+		upload.parts[partNumber] = content
+		w.Header().Set("ETag", strconv.Quote(contentETag(content)))
+	case r.Method == http.MethodPut:
+		content, _ := io.ReadAll(r.Body)
+		object := f.storeObject(b, key, objectMetadataFromRequest(r), content, contentETag(content))
+		w.Header().Set("ETag", strconv.Quote(object.etag))
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		uploadID := randomHex(32)
+		f.uploads[uploadID] = &fakeUpload{
+			bucketID: b.id,
+			key:      key,
+			metadata: objectMetadataFromRequest(r),
+			parts:    map[int][]byte{},
+		}
+		writeFakeS3Answer(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Key      string   `xml:"Key"`
+			UploadID string   `xml:"UploadId"`
+		}{Key: key, UploadID: uploadID})
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		return f.completeUpload(w, r, b, key)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		if _, apiErr := f.upload(query.Get("uploadId"), b, key); apiErr != nil {
+			return apiErr
+		}
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		return errS3(http.StatusNotImplemented, "NotImplemented", "Unsupported request %s %s", r.Method, r.URL)
+	}
+	return nil
+}
+
+func (f *fakeGarage) serveS3Bucket(w http.ResponseWriter, r *http.Request, b *fakeBucket, permissions bucketKeyPermissions) *s3Error {
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		if !permissions.Read {
+			return errS3AccessDenied()
+		}
+		return f.listObjects(w, query, b)
+	case query.Has("cors"):
+		if !permissions.Owner {
+			return errS3AccessDenied()
+		}
+		return f.serveBucketCors(w, r, b)
+	case query.Has("lifecycle"):
+		if !permissions.Owner {
+			return errS3AccessDenied()
+		}
+		return f.serveBucketLifecycle(w, r, b)
+	case query.Has("website"):
+		if !permissions.Owner {
+			return errS3AccessDenied()
+		}
+		return f.serveBucketWebsite(w, r, b)
+	default:
+		return errS3(http.StatusNotImplemented, "NotImplemented", "Unsupported request %s %s", r.Method, r.URL)
+	}
+}
+
+// checkContentMD5 reads the body of r, which must match its Content-MD5
+// header as S3 requires for bucket configurations.
+func checkContentMD5(r *http.Request) ([]byte, *s3Error) {
+	body, _ := io.ReadAll(r.Body)
+	sum := md5.Sum(body)
+	if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return nil, errS3(http.StatusBadRequest, "InvalidDigest", "Missing or invalid Content-MD5")
+	}
+	return body, nil
+}
+
+func (f *fakeGarage) serveBucketCors(w http.ResponseWriter, r *http.Request, b *fakeBucket) *s3Error {
+	switch r.Method {
+	case http.MethodGet:
+		if b.cors == nil {
+			return errS3(http.StatusNotFound, "NoSuchCORSConfiguration", "The CORS configuration does not exist")
+		}
+		writeFakeS3Answer(w, b.cors)
+	case http.MethodPut:
+		body, apiErr := checkContentMD5(r)
+		if apiErr != nil {
+			return apiErr
+		}
+		var configuration corsConfiguration
+		if err := xml.Unmarshal(body, &configuration); err != nil || len(configuration.Rules) == 0 {
+			return errS3(http.StatusBadRequest, "MalformedXML", "Invalid CORS configuration")
+		}
+		for _, rule := range configuration.Rules {
+			if len(rule.AllowedOrigins) == 0 || len(rule.AllowedMethods) == 0 {
+				return errS3(http.StatusBadRequest, "MalformedXML", "CORS rules need origins and methods")
+			}
+		}
+		b.cors = &configuration
+	case http.MethodDelete:
+		b.cors = nil
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		return errS3(http.StatusNotImplemented, "NotImplemented", "Unsupported request %s %s", r.Method, r.URL)
+	}
+	return nil
+}
+
+// serveBucketLifecycle stores lifecycle configurations as Garage does, which
+// checks that rules have an action and filters at most one condition of each
+// kind.
+func (f *fakeGarage) serveBucketLifecycle(w http.ResponseWriter, r *http.Request, b *fakeBucket) *s3Error {
+	switch r.Method {
+	case http.MethodGet:
+		if b.lifecycle == nil {
+			return errS3(http.StatusNotFound, "NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist")
+		}
+		writeFakeS3Answer(w, b.lifecycle)
+	case http.MethodPut:
+		body, apiErr := checkContentMD5(r)
+		if apiErr != nil {
+			return apiErr
+		}
+		var configuration lifecycleConfiguration
+		if err := xml.Unmarshal(body, &configuration); err != nil || len(configuration.Rules) == 0 {
+			return errS3(http.StatusBadRequest, "MalformedXML", "Invalid lifecycle configuration")
+		}
+		for _, rule := range configuration.Rules {
+			if rule.Status != "Enabled" && rule.Status != "Disabled" {
+				return errS3(http.StatusBadRequest, "MalformedXML", "Invalid status %q", rule.Status)
+			}
+			if rule.Expiration == nil && rule.AbortIncompleteMultipartUpload == nil {
+				return errS3(http.StatusBadRequest, "MalformedXML", "Rule %s has no action", rule.ID)
+			}
+			if rule.Expiration != nil && (rule.Expiration.Days == nil) == (rule.Expiration.Date == nil) {
+				return errS3(http.StatusBadRequest, "MalformedXML", "Expiration needs either days or a date")
+			}
+			if rule.Expiration != nil && rule.Expiration.Date != nil {
+				if _, err := time.Parse("2006-01-02T15:04:05Z", *rule.Expiration.Date); err != nil || !strings.HasSuffix(*rule.Expiration.Date, "T00:00:00Z") {
+					return errS3(http.StatusBadRequest, "MalformedXML", "Invalid expiration date %q", *rule.Expiration.Date)
+				}
+			}
+			if filter := rule.Filter; filter != nil && filter.And != nil && (filter.Prefix != nil || filter.ObjectSizeGreaterThan != nil || filter.ObjectSizeLessThan != nil) {
+				return errS3(http.StatusBadRequest, "MalformedXML", "Filter conditions must be in And")
+			}
+		}
+		b.lifecycle = &configuration
+	case http.MethodDelete:
+		b.lifecycle = nil
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		return errS3(http.StatusNotImplemented, "NotImplemented", "Unsupported request %s %s", r.Method, r.URL)
+	}
+	return nil
+}
+
+// serveBucketWebsite shares the website configuration with the admin API,
+// which only knows about its documents.
+func (f *fakeGarage) serveBucketWebsite(w http.ResponseWriter, r *http.Request, b *fakeBucket) *s3Error {
+	switch r.Method {
+	case http.MethodGet:
+		if b.website == nil {
+			return errS3(http.StatusNotFound, "NoSuchWebsiteConfiguration", "The website configuration does not exist")
+		}
+		configuration := websiteConfiguration{}
+		if b.websiteRouting != nil {
+			configuration = *b.websiteRouting
+		}
+		if b.website.IndexDocument != "" {
+			configuration.IndexDocument = &websiteIndexDocument{Suffix: b.website.IndexDocument}
+		}
+		if b.website.ErrorDocument != nil {
+			configuration.ErrorDocument = &websiteErrorDocument{Key: *b.website.ErrorDocument}
+		}
+		writeFakeS3Answer(w, configuration)
+	case http.MethodPut:
+		body, apiErr := checkContentMD5(r)
+		if apiErr != nil {
+			return apiErr
+		}
+		// Garage parses RedirectAllRequestsTo, but refuses it.
+		if bytes.Contains(body, []byte("<RedirectAllRequestsTo>")) {
+			return errS3(http.StatusNotImplemented, "NotImplemented", "RedirectAllRequestsTo is not currently implemented in Garage")
+		}
+		var configuration websiteConfiguration
+		if err := xml.Unmarshal(body, &configuration); err != nil {
+			return errS3(http.StatusBadRequest, "MalformedXML", "Invalid website configuration")
+		}
+		if configuration.IndexDocument == nil {
+			return errS3(http.StatusBadRequest, "InvalidArgument", "Bad XML: IndexDocument must be specified")
+		}
+		for _, rule := range configuration.RoutingRules {
+			if rule.Redirect.ReplaceKeyPrefixWith != nil && rule.Redirect.ReplaceKeyWith != nil {
+				return errS3(http.StatusBadRequest, "InvalidArgument", "Bad XML: ReplaceKeyPrefixWith and ReplaceKeyWith conflict")
+			}
+		}
+
+		b.website = &bucketWebsiteConfig{}
+		if configuration.IndexDocument != nil {
+			b.website.IndexDocument = configuration.IndexDocument.Suffix
+		}
+		if configuration.ErrorDocument != nil {
+			b.website.ErrorDocument = &configuration.ErrorDocument.Key
+		}
+		b.websiteRouting = &websiteConfiguration{
+			RoutingRules: configuration.RoutingRules,
+		}
+	case http.MethodDelete:
+		b.website = nil
+		b.websiteRouting = nil
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		return errS3(http.StatusNotImplemented, "NotImplemented", "Unsupported request %s %s", r.Method, r.URL)
+	}
+	return nil
+}
+
+// listObjects answers ListObjectsV2, using the last key or common prefix of a
+// page as its continuation token.
+func (f *fakeGarage) listObjects(w http.ResponseWriter, query url.Values, b *fakeBucket) *s3Error {
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	maxKeys := 1000
+	if query.Has("max-keys") {
+		var err error
+		maxKeys, err = strconv.Atoi(query.Get("max-keys"))
+		if err != nil || maxKeys < 0 {
+			return errS3(http.StatusBadRequest, "InvalidArgument", "Invalid max-keys")
+		}
+		if maxKeys > 1000 {
+			maxKeys = 1000
+		}
+	}
+	after := query.Get("continuation-token")
+
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := listBucketResult{}
+	count := 0
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+		entry := key
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			entry = key[:len(prefix)+i+len(delimiter)]
+			if entry <= after || (len(result.CommonPrefixes) > 0 && result.CommonPrefixes[len(result.CommonPrefixes)-1].Prefix == entry) {
+				continue
+			}
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		count++
+		if entry != key {
+			result.CommonPrefixes = append(result.CommonPrefixes, struct {
+				Prefix string `xml:"Prefix"`
+			}{Prefix: entry})
+		} else {
+			object := b.objects[key]
+			result.Contents = append(result.Contents, s3ObjectListItem{
+				Key:          key,
+				LastModified: object.lastModified.Format(time.RFC3339),
+				ETag:         strconv.Quote(object.etag),
+				Size:         int64(len(object.content)),
+			})
+		}
+		result.NextContinuationToken = entry
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+
+	writeFakeS3Answer(w, struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		listBucketResult
+	}{listBucketResult: result})
+	return nil
+}
+
+func objectMetadataFromRequest(r *http.Request) s3ObjectMetadata {
+	metadata := objectFromHeader(r.Header).s3ObjectMetadata
+	if metadata.ContentType == "" {
+		metadata.ContentType = "blob"
+	}
+	return metadata
+}
+
+func (f *fakeGarage) storeObject(b *fakeBucket, key string, metadata s3ObjectMetadata, content []byte, etag string) *fakeObject {
+	object := &fakeObject{
+		s3ObjectMetadata: metadata,
+		content:          content,
+		etag:             etag,
+		lastModified:     time.Now().UTC(),
+	}
+	b.objects[key] = object
+	return object
+}
+
+func (f *fakeGarage) upload(uploadID string, b *fakeBucket, key string) (*fakeUpload, *s3Error) {
+	upload, ok := f.uploads[uploadID]
+	if !ok || upload.bucketID != b.id || upload.key != key {
+		return nil, errS3(http.StatusNotFound, "NoSuchUpload", "Upload not found")
+	}
+	return upload, nil
+}
+
+func (f *fakeGarage) completeUpload(w http.ResponseWriter, r *http.Request, b *fakeBucket, key string) *s3Error {
+	uploadID := r.URL.Query().Get("uploadId")
+	upload, apiErr := f.upload(uploadID, b, key)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	var request completeMultipartUpload
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Parts) == 0 {
+		return errS3(http.StatusBadRequest, "MalformedXML", "Invalid CompleteMultipartUpload request")
+	}
+	if !sort.SliceIsSorted(request.Parts, func(i, j int) bool { return request.Parts[i].PartNumber < request.Parts[j].PartNumber }) {
+		return errS3(http.StatusBadRequest, "InvalidPartOrder", "Parts are not in ascending order")
+	}
+
+	// Garage's ETag of multipart uploads is the MD5 of the concatenated
+	// hex-encoded ETags of the parts, followed by their number.
+	var content []byte
+	var partETags string
+	for i, part := range request.Parts {
+		partContent, ok := upload.parts[part.PartNumber]
+		if !ok || unquoteETag(part.ETag) != contentETag(partContent) {
+			return errS3(http.StatusBadRequest, "InvalidPart", "Part %d not found or ETag mismatch", part.PartNumber)
+		}
+		if i < len(request.Parts)-1 && len(partContent) < 5<<20 {
+			return errS3(http.StatusBadRequest, "EntityTooSmall", "Part %d is too small", part.PartNumber)
+		}
+		partETags += contentETag(partContent)
+		content = append(content, partContent...)
+	}
+	sum := md5.Sum([]byte(partETags))
+	etag := fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(request.Parts))
+
+	f.storeObject(b, key, upload.metadata, content, etag)
+	delete(f.uploads, uploadID)
+	writeFakeS3Answer(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Key     string   `xml:"Key"`
+		ETag    string   `xml:"ETag"`
+	}{Key: key, ETag: strconv.Quote(etag)})
+	return nil
+}
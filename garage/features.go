@@ -0,0 +1,156 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// feature is a capability of Garage the provider relies on, available from
+// the version introducing it and until the one removing it, if any.
+type feature struct {
+	description string
+	since       *version.Version
+	removedIn   *version.Version
+}
+
+// Capability table of the Garage versions supported by the provider.
+var (
+	featureAdminAPIv0 = &feature{
+		description: "the v0 admin API",
+		since:       version.Must(version.NewVersion("0.8.0")),
+		removedIn:   version.Must(version.NewVersion("2.0.0")),
+	}
+	featureAdminAPIv1 = &feature{
+		description: "the v1 admin API",
+		since:       version.Must(version.NewVersion("0.9.0")),
+	}
+	featureAdminAPIv2 = &feature{
+		description: "the v2 admin API",
+		since:       version.Must(version.NewVersion("2.0.0")),
+	}
+	featureBucketLifecycle = &feature{
+		description: "bucket lifecycle configuration",
+		since:       version.Must(version.NewVersion("0.9.0")),
+	}
+	featureKeyExpiration = &feature{
+		description: "key expiration",
+		since:       version.Must(version.NewVersion("2.0.0")),
+	}
+	featureAdminTokens = &feature{
+		description: "admin tokens",
+		since:       version.Must(version.NewVersion("2.0.0")),
+	}
+	// Garage 1 refuses routing rules with NotImplemented, as
+	// TestAccFeatureWebsiteRoutingRules checks against actual nodes.
+	featureWebsiteRoutingRules = &feature{
+		description: "website redirects and routing rules",
+		since:       version.Must(version.NewVersion("2.0.0")),
+	}
+)
+
+var garageVersionRegexp = regexp.MustCompile(`v?(\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?)`)
+
+// parseGarageVersion parses versions as reported by Garage, such as
+// "v0.8.0" or "git:v0.9.0-rc1-12-g0123abc".
+func parseGarageVersion(garageVersion string) (*version.Version, error) {
+	match := garageVersionRegexp.FindStringSubmatch(garageVersion)
+	if match == nil {
+		return nil, fmt.Errorf("unrecognized Garage version %q", garageVersion)
+	}
+
+	parsed, err := version.NewVersion(match[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// Release candidates are expected to support what the release does.
+	return parsed.Core(), nil
+}
+
+// supports reports whether the connected Garage has f. An unknown version,
+// when the connectivity check was skipped, is assumed to support everything.
+func (p *garageProvider) supports(f *feature) bool {
+	if p == nil || p.garageVersion == nil {
+		return true
+	}
+	if p.garageVersion.LessThan(f.since) {
+		return false
+	}
+	return f.removedIn == nil || p.garageVersion.LessThan(f.removedIn)
+}
+
+// requireFeature returns an error explaining why f can't be used with the
+// connected Garage, if that's the case.
+func (p *garageProvider) requireFeature(f *feature) error {
+	if p.supports(f) {
+		return nil
+	}
+	if p.garageVersion.LessThan(f.since) {
+		return fmt.Errorf("%s requires Garage %s or later, but the cluster runs Garage %s", f.description, f.since, p.garageVersion)
+	}
+	return fmt.Errorf("%s was removed in Garage %s, but the cluster runs Garage %s", f.description, f.removedIn, p.garageVersion)
+}
+
+// requireAnyFeature returns an error when the connected Garage has none of
+// features.
+func (p *garageProvider) requireAnyFeature(features ...*feature) error {
+	var err error
+	for _, f := range features {
+		if err = p.requireFeature(f); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// customizeDiffRequireAdminAPI fails the plan of any resource when the
+// connected Garage speaks no admin API version the provider supports.
+func customizeDiffRequireAdminAPI(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	p, _ := m.(*garageProvider)
+	return p.requireAnyFeature(featureAdminAPIv2, featureAdminAPIv1, featureAdminAPIv0)
+}
+
+// customizeDiffRequireAPIVersion returns a CustomizeDiffFunc failing the plan
+// of resourceType when the provider speaks an admin API older than apiVersion,
+// or when the connected Garage doesn't have f.
+func customizeDiffRequireAPIVersion(resourceType string, apiVersion int, f *feature) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+		p, _ := m.(*garageProvider)
+		if p != nil && p.apiVersion < apiVersion {
+			return fmt.Errorf("%s requires the v%d admin API, but the provider uses v%d: set admin_api_version to v%d", resourceType, apiVersion, p.apiVersion, apiVersion)
+		}
+		return p.requireFeature(f)
+	}
+}
+
+// customizeDiffRequireFeatureIfSet returns a CustomizeDiffFunc failing the
+// plan when attribute is set in the configuration but the connected Garage
+// doesn't have f.
+func customizeDiffRequireFeatureIfSet(attribute string, f *feature) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+		p, _ := m.(*garageProvider)
+		if _, ok := d.GetOk(attribute); !ok {
+			return nil
+		}
+		if err := p.requireFeature(f); err != nil {
+			return fmt.Errorf("%s: %w", attribute, err)
+		}
+		return nil
+	}
+}
+
+// customizeDiffRequireFeature returns a CustomizeDiffFunc failing the plan of
+// resourceType when the connected Garage doesn't have f.
+func customizeDiffRequireFeature(resourceType string, f *feature) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+		p, _ := m.(*garageProvider)
+		if err := p.requireFeature(f); err != nil {
+			return fmt.Errorf("%s: %w", resourceType, err)
+		}
+		return nil
+	}
+}
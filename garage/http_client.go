@@ -0,0 +1,106 @@
+package garage
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// readPEM returns value itself when it holds PEM data, and the content of the
+// file it points to otherwise.
+func readPEM(value string) ([]byte, error) {
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
+
+func newTLSConfig(d *schema.ResourceData) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         d.Get("tls_server_name").(string),
+		InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+	}
+
+	caCertPEM := []byte(d.Get("ca_cert_pem").(string))
+	if caCertFile := d.Get("ca_cert_file").(string); caCertFile != "" {
+		content, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_cert_file: %w", err)
+		}
+		caCertPEM = append(caCertPEM, '\n')
+		caCertPEM = append(caCertPEM, content...)
+	}
+	if len(strings.TrimSpace(string(caCertPEM))) > 0 {
+		certPool, err := x509.SystemCertPool()
+		if err != nil {
+			certPool = x509.NewCertPool()
+		}
+		if !certPool.AppendCertsFromPEM(caCertPEM) {
+			return nil, fmt.Errorf("no valid certificate found in ca_cert_file or ca_cert_pem")
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	clientCert := d.Get("client_cert").(string)
+	clientKey := d.Get("client_key").(string)
+	if clientCert != "" || clientKey != "" {
+		if clientCert == "" || clientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		certPEM, err := readPEM(clientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client_cert: %w", err)
+		}
+		keyPEM, err := readPEM(clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client_key: %w", err)
+		}
+		certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// newHTTPClient builds the HTTP client used to reach the admin API.
+func newHTTPClient(d *schema.ResourceData) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// The default transport already honours HTTPS_PROXY, HTTP_PROXY and
+	// NO_PROXY.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	if proxyURL := d.Get("proxy_url").(string); proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy_url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
+
+// newDataHTTPClient builds the HTTP client used to reach the S3 and K2V APIs.
+// It shares none of the TLS, proxy and header settings of the admin API, which
+// may only be meant for it, and honours HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+func newDataHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
+}
@@ -0,0 +1,111 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// importStateFromID returns an importer setting attributes from an ID made of
+// their values joined by slashes, as resources without an ID of their own in
+// Garage build it. The last value may contain slashes, like object keys.
+func importStateFromID(attributes ...string) schema.StateContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+		values := strings.SplitN(d.Id(), "/", len(attributes))
+		if len(values) != len(attributes) {
+			return nil, fmt.Errorf("unexpected ID %q, expected %s", d.Id(), strings.Join(attributes, "/"))
+		}
+
+		for i, attribute := range attributes {
+			if values[i] == "" {
+				return nil, fmt.Errorf("unexpected ID %q, expected %s", d.Id(), strings.Join(attributes, "/"))
+			}
+			err := d.Set(attribute, values[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return []*schema.ResourceData{d}, nil
+	}
+}
+
+// importS3StateFromID is importStateFromID for resources going through the
+// S3 API, whose key isn't part of their ID. Unless the provider has static
+// S3 credentials, the imported resource signs its requests with a key granted
+// on the bucket, preferably owning it as changing bucket settings requires.
+func importS3StateFromID(attributes ...string) schema.StateContextFunc {
+	importState := importStateFromID(attributes...)
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+		p := m.(*garageProvider)
+
+		imported, err := importState(ctx, d, m)
+		if err != nil || (p.s3 != nil && p.s3.credentials != nil) {
+			return imported, err
+		}
+
+		err = importBucketKey(ctx, d, p, func(permissions bucketKeyPermissions) bool {
+			return permissions.Owner
+		}, "grant one or set s3_access_key_id on the provider")
+		if err != nil {
+			return nil, err
+		}
+		return imported, nil
+	}
+}
+
+// importK2VStateFromID is importStateFromID for resources going through the
+// K2V API, which sign their requests with a key granted on the bucket,
+// preferably allowed to write to it.
+func importK2VStateFromID(attributes ...string) schema.StateContextFunc {
+	importState := importStateFromID(attributes...)
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+		imported, err := importState(ctx, d, m)
+		if err != nil {
+			return nil, err
+		}
+
+		err = importBucketKey(ctx, d, m.(*garageProvider), func(permissions bucketKeyPermissions) bool {
+			return permissions.Write
+		}, "grant one")
+		if err != nil {
+			return nil, err
+		}
+		return imported, nil
+	}
+}
+
+// importBucketKey sets access_key_id to a key allowed to read the bucket,
+// preferably one with the permissions preferred checks. hint tells what to do
+// when there is none.
+func importBucketKey(ctx context.Context, d *schema.ResourceData, p *garageProvider, preferred func(permissions bucketKeyPermissions) bool, hint string) error {
+	bucketName := d.Get("bucket").(string)
+	var bucketInfo *bucket
+	var err error
+	if bucketIDRegexp.MatchString(bucketName) {
+		bucketInfo, err = p.client.GetBucket(ctx, bucketName)
+	} else {
+		bucketInfo, err = p.client.FindBucket(ctx, bucketName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find a key to import bucket %s with: %w", bucketName, err)
+	}
+
+	var accessKeyID string
+	for _, key := range bucketInfo.Keys {
+		if key.Permissions.Read && preferred(key.Permissions) {
+			accessKeyID = key.AccessKeyID
+			break
+		}
+		if key.Permissions.Read && accessKeyID == "" {
+			accessKeyID = key.AccessKeyID
+		}
+	}
+	if accessKeyID == "" {
+		return fmt.Errorf("no key is allowed to read bucket %s: %s", bucketName, hint)
+	}
+
+	return d.Set("access_key_id", accessKeyID)
+}
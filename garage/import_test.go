@@ -0,0 +1,114 @@
+package garage
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestImportS3StateFromID(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	client := fake.client()
+	endpoint, _ := url.Parse(fake.s3.URL)
+	p := &garageProvider{client: client, s3: &s3Config{endpoint: endpoint}}
+
+	bucketInfo, err := client.CreateBucket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddBucketGlobalAlias(ctx, bucketInfo.ID, "website"); err != nil {
+		t.Fatal(err)
+	}
+	importState := func(id string) (*schema.ResourceData, error) {
+		d := schema.TestResourceDataRaw(t, schemaBucketObject(), map[string]interface{}{})
+		d.SetId(id)
+		_, err := importS3StateFromID("bucket", "key")(ctx, d, p)
+		return d, err
+	}
+
+	if _, err := importState("website/index.html"); err == nil {
+		t.Errorf("expected a bucket without key to fail")
+	}
+
+	for _, permissions := range []bucketKeyPermissions{{Write: true}, {Read: true}, {Read: true, Owner: true}} {
+		key, err := client.CreateKey(ctx, "import")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.GrantKey(ctx, bucketInfo.ID, key.AccessKeyID, permissions); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, id := range []string{"website/index.html", bucketInfo.ID + "/index.html"} {
+			d, err := importState(id)
+			if permissions.Read != (err == nil) {
+				t.Fatalf("%s with %+v: unexpected error %v", id, permissions, err)
+			}
+			if err != nil {
+				continue
+			}
+			if d.Get("access_key_id") != key.AccessKeyID {
+				t.Errorf("%s with %+v: expected key %s, got %s", id, permissions, key.AccessKeyID, d.Get("access_key_id"))
+			}
+		}
+	}
+
+	p.s3.credentials = &s3Credentials{AccessKeyID: "GKstatic", SecretAccessKey: "secret"}
+	d, err := importState("website/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Get("access_key_id") != "" {
+		t.Errorf("expected the static credentials to be used, got key %s", d.Get("access_key_id"))
+	}
+}
+
+func TestImportK2VStateFromID(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	client := fake.client()
+	p := &garageProvider{client: client}
+
+	bucketInfo, err := client.CreateBucket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddBucketGlobalAlias(ctx, bucketInfo.ID, "k2v"); err != nil {
+		t.Fatal(err)
+	}
+	importState := func(id string) (*schema.ResourceData, error) {
+		d := schema.TestResourceDataRaw(t, schemaK2VItem(), map[string]interface{}{})
+		d.SetId(id)
+		_, err := importK2VStateFromID("bucket", "partition_key", "sort_key")(ctx, d, p)
+		return d, err
+	}
+
+	if _, err := importState("k2v/users/alice"); err == nil {
+		t.Errorf("expected a bucket without key to fail")
+	}
+
+	var writer string
+	for _, permissions := range []bucketKeyPermissions{{Read: true, Owner: true}, {Read: true, Write: true}, {Read: true}} {
+		key, err := client.CreateKey(ctx, "import")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.GrantKey(ctx, bucketInfo.ID, key.AccessKeyID, permissions); err != nil {
+			t.Fatal(err)
+		}
+		if permissions.Write {
+			writer = key.AccessKeyID
+		}
+	}
+
+	d, err := importState("k2v/users/alice/2024")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Get("access_key_id") != writer || d.Get("partition_key") != "users" || d.Get("sort_key") != "alice/2024" {
+		t.Errorf("expected the key allowed to write to import users/alice/2024, got %v", d.State())
+	}
+}
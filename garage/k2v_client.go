@@ -0,0 +1,218 @@
+package garage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// k2vConfig is how the provider reaches the K2V API, the key-value store of
+// Garage, which buckets are addressed in the path of.
+type k2vConfig struct {
+	endpoint   *url.URL
+	region     string
+	httpClient *http.Client
+}
+
+// k2vClient calls the K2V API with the credentials of one key.
+type k2vClient struct {
+	config      *k2vConfig
+	credentials s3Credentials
+}
+
+// k2vRequest is a request to the K2V API. Bucket is the global alias, or a
+// local alias of the signing key, of the bucket, and PartitionKey is empty
+// for requests on the whole bucket.
+type k2vRequest struct {
+	Method       string
+	Bucket       string
+	PartitionKey string
+	Query        url.Values
+	Header       http.Header
+	Body         []byte
+}
+
+// k2vError is an error answered by the K2V API.
+type k2vError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *k2vError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("K2V API answered %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("K2V API answered %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// newK2VConfig returns the K2V settings of the provider, taking the endpoint
+// from config_file when it is not set, or nil when no K2V endpoint is
+// configured. Requests are signed for the region of the S3 API.
+func newK2VConfig(d *schema.ResourceData, httpClient *http.Client) (*k2vConfig, error) {
+	endpoint := d.Get("k2v_endpoint").(string)
+	region := d.Get("s3_region").(string)
+	if configFile := d.Get("config_file").(string); configFile != "" && (endpoint == "" || region == "") {
+		config, err := readGarageConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if endpoint == "" {
+			endpoint, err = config.k2vEndpoint()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if region == "" {
+			region = config.S3API.S3Region
+		}
+	}
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid k2v_endpoint %q: %w", endpoint, err)
+	}
+	if (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid k2v_endpoint %q: expected an http or https URL", endpoint)
+	}
+	endpointURL.Path = strings.TrimSuffix(endpointURL.Path, "/")
+	endpointURL.RawPath = ""
+
+	return &k2vConfig{
+		endpoint:   endpointURL,
+		region:     region,
+		httpClient: httpClient,
+	}, nil
+}
+
+// k2vBucket returns a client of the K2V API signing requests with the key
+// accessKeyID, whose secret is read through the admin API, along with the
+// name addressing bucket, a bucket ID or alias, through it.
+func (p *garageProvider) k2vBucket(ctx context.Context, bucket string, accessKeyID string) (*k2vClient, string, error) {
+	if p == nil || p.k2v == nil {
+		return nil, "", fmt.Errorf("the K2V API endpoint is unknown: set k2v_endpoint on the provider")
+	}
+
+	credentials, err := p.keyCredentials(ctx, accessKeyID)
+	if err != nil {
+		return nil, "", err
+	}
+	bucketName, err := p.bucketName(ctx, bucket, accessKeyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &k2vClient{config: p.k2v, credentials: credentials}, bucketName, nil
+}
+
+// requestURL returns the URL of partitionKey in bucket.
+func (c *k2vClient) requestURL(bucket string, partitionKey string, query url.Values) *url.URL {
+	requestURL := *c.config.endpoint
+	path := requestURL.Path + "/" + bucket
+	if partitionKey != "" {
+		path += "/" + partitionKey
+	}
+
+	requestURL.Path = path
+	requestURL.RawPath = sigV4Escape(path, false)
+	requestURL.RawQuery = canonicalQuery(query)
+	return &requestURL
+}
+
+// do sends r, signed, and returns the answer along with its body.
+func (c *k2vClient) do(ctx context.Context, r k2vRequest) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, r.Method, c.requestURL(r.Bucket, r.PartitionKey, r.Query).String(), bytes.NewReader(r.Body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range r.Header {
+		req.Header[name] = values
+	}
+	req.ContentLength = int64(len(r.Body))
+	payloadHash := sha256Hex(r.Body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := signV4(ctx, req, c.credentials, c.config.region, "k2v", payloadHash, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	httpResp, err := c.config.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer httpResp.Body.Close()
+
+	content, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return httpResp, nil, err
+	}
+
+	if httpResp.StatusCode >= 300 {
+		apiErr := &k2vError{}
+		_ = json.Unmarshal(content, apiErr)
+		apiErr.StatusCode = httpResp.StatusCode
+		if apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(content))
+		}
+		return httpResp, content, apiErr
+	}
+
+	return httpResp, content, nil
+}
+
+// call sends r and decodes the JSON answer into out, if any.
+func (c *k2vClient) call(ctx context.Context, r k2vRequest, out interface{}) (*http.Response, error) {
+	httpResp, content, err := c.do(ctx, r)
+	if err != nil {
+		return httpResp, err
+	}
+
+	if out != nil && len(bytes.TrimSpace(content)) > 0 {
+		if err := json.Unmarshal(content, out); err != nil {
+			return httpResp, fmt.Errorf("failed to decode K2V API answer: %w", err)
+		}
+	}
+
+	return httpResp, nil
+}
+
+// isK2VNotFound reports whether err is the K2V API answering that the bucket
+// or item doesn't exist.
+func isK2VNotFound(err error) bool {
+	var apiErr *k2vError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// withK2VBucket adds to the schema of a resource going through the K2V API
+// the bucket it manages items of and the key signing its requests.
+func withK2VBucket(resourceSchema map[string]*schema.Schema) map[string]*schema.Schema {
+	resourceSchema["bucket"] = &schema.Schema{
+		Description: "ID or global alias of the bucket. Buckets without global alias are addressed by a local alias of the signing key.",
+		Type:        schema.TypeString,
+		Required:    true,
+		ForceNew:    true,
+	}
+	resourceSchema["access_key_id"] = &schema.Schema{
+		Description:  "ID of the key signing requests to the K2V API, typically of a `garage_key` managed in the same configuration, which needs permissions on the bucket.",
+		Type:         schema.TypeString,
+		Required:     true,
+		ValidateFunc: validation.StringIsNotEmpty,
+	}
+	return resourceSchema
+}
@@ -0,0 +1,121 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestK2VClientRequestURL(t *testing.T) {
+	endpoint, _ := url.Parse("https://k2v.example/prefix")
+	client := &k2vClient{config: &k2vConfig{endpoint: endpoint}}
+
+	for _, c := range []struct {
+		bucket       string
+		partitionKey string
+		query        url.Values
+		expected     string
+	}{
+		{"app", "", url.Values{"prefix": {"a b"}}, "https://k2v.example/prefix/app?prefix=a%20b"},
+		{"app", "users/a b", url.Values{"sort_key": {"x+y"}}, "https://k2v.example/prefix/app/users/a%20b?sort_key=x%2By"},
+		{"app", "", url.Values{"search": {""}}, "https://k2v.example/prefix/app?search="},
+	} {
+		if requestURL := client.requestURL(c.bucket, c.partitionKey, c.query).String(); requestURL != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, requestURL)
+		}
+	}
+}
+
+func TestK2VClientCall(t *testing.T) {
+	credentials := s3Credentials{AccessKeyID: "GK" + randomHex(24), SecretAccessKey: randomHex(64)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := verifySigV4(r, "k2v", func(accessKeyID string) (string, bool) {
+			return credentials.SecretAccessKey, accessKeyID == credentials.AccessKeyID
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, `{"code":"AccessDenied","message":%q}`, err.Error())
+			return
+		}
+		if r.URL.Path == "/app/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":"NoSuchKey","message":"Key not found","region":"garage","path":"/app/missing"}`)
+			return
+		}
+		fmt.Fprint(w, `{"partitionKeys":[{"pk":"users","entries":2,"conflicts":0,"values":2,"bytes":10}],"more":false,"nextStart":null}`)
+	}))
+	t.Cleanup(server.Close)
+
+	endpoint, _ := url.Parse(server.URL)
+	client := &k2vClient{config: &k2vConfig{endpoint: endpoint, region: defaultS3Region, httpClient: server.Client()}, credentials: credentials}
+	ctx := context.Background()
+
+	var index k2vIndex
+	if _, err := client.call(ctx, k2vRequest{Method: http.MethodGet, Bucket: "app"}, &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.PartitionKeys) != 1 || index.PartitionKeys[0] != (k2vPartition{PartitionKey: "users", Entries: 2, Values: 2, Bytes: 10}) {
+		t.Errorf("unexpected index: %+v", index)
+	}
+
+	_, err := client.call(ctx, k2vRequest{Method: http.MethodGet, Bucket: "app", PartitionKey: "missing", Query: url.Values{"sort_key": {"a"}}}, nil)
+	if !isK2VNotFound(err) || err.(*k2vError).Code != "NoSuchKey" {
+		t.Errorf("expected a NoSuchKey error, got %v", err)
+	}
+
+	client.credentials.SecretAccessKey = randomHex(64)
+	_, err = client.call(ctx, k2vRequest{Method: http.MethodGet, Bucket: "app"}, nil)
+	if apiErr, ok := err.(*k2vError); !ok || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected the wrong secret to be rejected, got %v", err)
+	}
+}
+
+func TestNewK2VConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "garage.toml")
+	err := os.WriteFile(configFile, []byte("[s3_api]\ns3_region = \"eu-west\"\n\n[k2v_api]\napi_bind_addr = \"0.0.0.0:3904\"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		raw      map[string]interface{}
+		endpoint string
+		region   string
+		err      string
+	}{
+		{raw: map[string]interface{}{}},
+		{raw: map[string]interface{}{"k2v_endpoint": "https://k2v.example/"}, endpoint: "https://k2v.example", region: defaultS3Region},
+		{raw: map[string]interface{}{"k2v_endpoint": "https://k2v.example", "s3_region": "eu-east"}, endpoint: "https://k2v.example", region: "eu-east"},
+		{raw: map[string]interface{}{"config_file": configFile}, endpoint: "http://127.0.0.1:3904", region: "eu-west"},
+		{raw: map[string]interface{}{"config_file": filepath.Join(t.TempDir(), "missing.toml")}, err: "failed to parse"},
+	} {
+		d := schema.TestResourceDataRaw(t, Provider().Schema, c.raw)
+		config, err := newK2VConfig(d, http.DefaultClient)
+		switch {
+		case c.err != "":
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%v: expected an error containing %q, got %v", c.raw, c.err, err)
+			}
+		case err != nil:
+			t.Errorf("%v: %v", c.raw, err)
+		case c.endpoint == "":
+			if config != nil {
+				t.Errorf("%v: expected no K2V configuration, got %+v", c.raw, config)
+			}
+		case config == nil:
+			t.Errorf("%v: expected a K2V configuration", c.raw)
+		default:
+			if config.endpoint.String() != c.endpoint || config.region != c.region {
+				t.Errorf("%v: unexpected K2V configuration %s %s", c.raw, config.endpoint, config.region)
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package garage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// k2vCausalityTokenHeader carries the causality token of an item, telling
+// which of its values a write supersedes.
+const k2vCausalityTokenHeader = "X-Garage-Causality-Token"
+
+// k2vItem is an item of a partition. Values are its concurrent values, which
+// are several when it was written without knowing the other ones, and none
+// when it was deleted.
+type k2vItem struct {
+	SortKey        string
+	CausalityToken string
+	Values         [][]byte
+}
+
+// k2vBatchQuery is a query of ReadBatch, reading items of a partition with
+// sort keys from Start, included, to End, excluded.
+type k2vBatchQuery struct {
+	PartitionKey string  `json:"partitionKey"`
+	Prefix       *string `json:"prefix"`
+	Start        *string `json:"start"`
+	End          *string `json:"end"`
+	Limit        *int    `json:"limit"`
+	Reverse      bool    `json:"reverse"`
+	SingleItem   bool    `json:"singleItem"`
+	Tombstones   bool    `json:"tombstones"`
+}
+
+// k2vBatchItem is an item as answered by ReadBatch, with base64-encoded
+// values, null for tombstones.
+type k2vBatchItem struct {
+	SortKey        string    `json:"sk"`
+	CausalityToken string    `json:"ct"`
+	Values         []*string `json:"v"`
+}
+
+type k2vBatchResult struct {
+	PartitionKey string         `json:"partitionKey"`
+	Items        []k2vBatchItem `json:"items"`
+	More         bool           `json:"more"`
+	NextStart    *string        `json:"nextStart"`
+}
+
+// k2vIndexQuery selects partitions of ReadIndex, with keys from Start,
+// included, to End, excluded.
+type k2vIndexQuery struct {
+	Prefix string
+	Start  string
+	End    string
+	Limit  int
+}
+
+// k2vPartition is a partition as counted by the index of a bucket.
+type k2vPartition struct {
+	PartitionKey string `json:"pk"`
+	Entries      int64  `json:"entries"`
+	Conflicts    int64  `json:"conflicts"`
+	Values       int64  `json:"values"`
+	Bytes        int64  `json:"bytes"`
+}
+
+type k2vIndex struct {
+	PartitionKeys []k2vPartition `json:"partitionKeys"`
+	More          bool           `json:"more"`
+	NextStart     *string        `json:"nextStart"`
+}
+
+// decodeK2VValues decodes base64-encoded values, skipping tombstones.
+func decodeK2VValues(encoded []*string) ([][]byte, error) {
+	values := [][]byte{}
+	for _, value := range encoded {
+		if value == nil {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(*value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode K2V value: %w", err)
+		}
+		values = append(values, decoded)
+	}
+	return values, nil
+}
+
+// ReadItem reads the item of partitionKey with sortKey, failing with a not
+// found error when it never existed.
+func (c *k2vClient) ReadItem(ctx context.Context, bucket string, partitionKey string, sortKey string) (*k2vItem, error) {
+	var encoded []*string
+	httpResp, err := c.call(ctx, k2vRequest{
+		Method:       http.MethodGet,
+		Bucket:       bucket,
+		PartitionKey: partitionKey,
+		Query:        url.Values{"sort_key": {sortKey}},
+		Header:       http.Header{"Accept": {"application/json"}},
+	}, &encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := decodeK2VValues(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return &k2vItem{
+		SortKey:        sortKey,
+		CausalityToken: httpResp.Header.Get(k2vCausalityTokenHeader),
+		Values:         values,
+	}, nil
+}
+
+// InsertItem writes value to the item of partitionKey with sortKey,
+// superseding the values causalityToken was read along with. Values it
+// doesn't know of are kept as concurrent values.
+func (c *k2vClient) InsertItem(ctx context.Context, bucket string, partitionKey string, sortKey string, causalityToken string, value []byte) error {
+	header := http.Header{}
+	if causalityToken != "" {
+		header.Set(k2vCausalityTokenHeader, causalityToken)
+	}
+	_, err := c.call(ctx, k2vRequest{
+		Method:       http.MethodPut,
+		Bucket:       bucket,
+		PartitionKey: partitionKey,
+		Query:        url.Values{"sort_key": {sortKey}},
+		Header:       header,
+		Body:         value,
+	}, nil)
+	return err
+}
+
+// DeleteItem deletes the values of the item of partitionKey with sortKey
+// causalityToken was read along with.
+func (c *k2vClient) DeleteItem(ctx context.Context, bucket string, partitionKey string, sortKey string, causalityToken string) error {
+	_, err := c.call(ctx, k2vRequest{
+		Method:       http.MethodDelete,
+		Bucket:       bucket,
+		PartitionKey: partitionKey,
+		Query:        url.Values{"sort_key": {sortKey}},
+		Header:       http.Header{k2vCausalityTokenHeader: {causalityToken}},
+	}, nil)
+	return err
+}
+
+// ReadBatch runs queries, each answered with a page of items.
+func (c *k2vClient) ReadBatch(ctx context.Context, bucket string, queries []k2vBatchQuery) ([]k2vBatchResult, error) {
+	body, err := json.Marshal(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []k2vBatchResult
+	_, err = c.call(ctx, k2vRequest{
+		Method: http.MethodPost,
+		Bucket: bucket,
+		Query:  url.Values{"search": {""}},
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   body,
+	}, &results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != len(queries) {
+		return nil, fmt.Errorf("K2V API answered %d results to %d queries", len(results), len(queries))
+	}
+	return results, nil
+}
+
+// ReadItems reads the items selected by query, going through as many pages
+// as needed. Its Limit, when set, is the number of items read in total.
+func (c *k2vClient) ReadItems(ctx context.Context, bucket string, query k2vBatchQuery) ([]k2vItem, error) {
+	items := []k2vItem{}
+	limit := query.Limit
+	for {
+		if limit != nil {
+			remaining := *limit - len(items)
+			query.Limit = &remaining
+		}
+		results, err := c.ReadBatch(ctx, bucket, []k2vBatchQuery{query})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range results[0].Items {
+			values, err := decodeK2VValues(item.Values)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, k2vItem{SortKey: item.SortKey, CausalityToken: item.CausalityToken, Values: values})
+		}
+		if !results[0].More || results[0].NextStart == nil || (limit != nil && len(items) >= *limit) {
+			return items, nil
+		}
+		query.Start = results[0].NextStart
+	}
+}
+
+// ReadIndex lists the partitions of bucket selected by query, going through
+// as many pages as needed. A Limit of 0 lists them all.
+func (c *k2vClient) ReadIndex(ctx context.Context, bucket string, query k2vIndexQuery) ([]k2vPartition, error) {
+	partitions := []k2vPartition{}
+	start := query.Start
+	for {
+		values := url.Values{}
+		if query.Prefix != "" {
+			values.Set("prefix", query.Prefix)
+		}
+		if start != "" {
+			values.Set("start", start)
+		}
+		if query.End != "" {
+			values.Set("end", query.End)
+		}
+		if query.Limit > 0 {
+			values.Set("limit", strconv.Itoa(query.Limit-len(partitions)))
+		}
+
+		var index k2vIndex
+		_, err := c.call(ctx, k2vRequest{Method: http.MethodGet, Bucket: bucket, Query: values}, &index)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, index.PartitionKeys...)
+
+		if !index.More || index.NextStart == nil || (query.Limit > 0 && len(partitions) >= query.Limit) {
+			return partitions, nil
+		}
+		start = *index.NextStart
+	}
+}
@@ -0,0 +1,168 @@
+package garage
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// newFakeK2VBucket creates a bucket with a global alias on the fake node and
+// returns a client of its K2V API signing with a key allowed to read and
+// write to it.
+func newFakeK2VBucket(t *testing.T, fake *fakeGarage, alias string) *k2vClient {
+	t.Helper()
+	ctx := context.Background()
+	client := fake.client()
+
+	bucketInfo, err := client.CreateBucket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddBucketGlobalAlias(ctx, bucketInfo.ID, alias); err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.CreateKey(ctx, alias)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.GrantKey(ctx, bucketInfo.ID, key.AccessKeyID, bucketKeyPermissions{Read: true, Write: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, _ := url.Parse(fake.k2v.URL)
+	return &k2vClient{
+		config: &k2vConfig{
+			endpoint:   endpoint,
+			region:     defaultS3Region,
+			httpClient: fake.k2v.Client(),
+		},
+		credentials: s3Credentials{AccessKeyID: key.AccessKeyID, SecretAccessKey: key.SecretAccessKey},
+	}
+}
+
+func TestK2VClientItem(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeK2VBucket(t, fake, "app")
+	ctx := context.Background()
+
+	if _, err := client.ReadItem(ctx, "app", "users", "alice"); !isK2VNotFound(err) {
+		t.Errorf("expected a missing item, got %v", err)
+	}
+
+	if err := client.InsertItem(ctx, "app", "users", "alice", "", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	item, err := client.ReadItem(ctx, "app", "users", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(item.Values) != 1 || string(item.Values[0]) != "v1" || item.CausalityToken == "" {
+		t.Errorf("unexpected item %+v", item)
+	}
+
+	// Writes without the token of the value are concurrent
+	if err := client.InsertItem(ctx, "app", "users", "alice", "", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+	concurrent, err := client.ReadItem(ctx, "app", "users", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(concurrent.Values) != 2 {
+		t.Errorf("expected 2 concurrent values, got %q", concurrent.Values)
+	}
+
+	if err := client.InsertItem(ctx, "app", "users", "alice", concurrent.CausalityToken, []byte("v3")); err != nil {
+		t.Fatal(err)
+	}
+	item, err = client.ReadItem(ctx, "app", "users", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(item.Values) != 1 || string(item.Values[0]) != "v3" {
+		t.Errorf("expected the concurrent values to be superseded, got %q", item.Values)
+	}
+
+	if err := client.DeleteItem(ctx, "app", "users", "alice", item.CausalityToken); err != nil {
+		t.Fatal(err)
+	}
+	item, err = client.ReadItem(ctx, "app", "users", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(item.Values) != 0 {
+		t.Errorf("expected a deleted item, got %q", item.Values)
+	}
+}
+
+func TestK2VClientReadItemsAndIndex(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeK2VBucket(t, fake, "app")
+	ctx := context.Background()
+
+	for _, partitionKey := range []string{"sessions", "users/eu", "users/us"} {
+		for _, sortKey := range []string{"a", "b", "c", "d"} {
+			if err := client.InsertItem(ctx, "app", partitionKey, sortKey, "", []byte(partitionKey+sortKey)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	item, err := client.ReadItem(ctx, "app", "sessions", "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteItem(ctx, "app", "sessions", "d", item.CausalityToken); err != nil {
+		t.Fatal(err)
+	}
+
+	sortKeys := func(items []k2vItem) []string {
+		keys := []string{}
+		for _, item := range items {
+			keys = append(keys, item.SortKey)
+		}
+		return keys
+	}
+	start, end, limit := "b", "d", 1
+	for _, c := range []struct {
+		query    k2vBatchQuery
+		expected []string
+	}{
+		{k2vBatchQuery{PartitionKey: "sessions"}, []string{"a", "b", "c"}},
+		{k2vBatchQuery{PartitionKey: "users/eu", Start: &start, End: &end}, []string{"b", "c"}},
+		{k2vBatchQuery{PartitionKey: "users/eu", Reverse: true, Start: &end}, []string{"d", "c", "b", "a"}},
+		{k2vBatchQuery{PartitionKey: "users/eu", Start: &start, Limit: &limit}, []string{"b"}},
+		{k2vBatchQuery{PartitionKey: "missing"}, []string{}},
+	} {
+		items, err := client.ReadItems(ctx, "app", c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := sortKeys(items); !reflect.DeepEqual(keys, c.expected) {
+			t.Errorf("%+v: expected %q, got %q", c.query, c.expected, keys)
+		}
+	}
+	items, err := client.ReadItems(ctx, "app", k2vBatchQuery{PartitionKey: "users/us", Prefix: optionalString("c")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || !bytes.Equal(items[0].Values[0], []byte("users/usc")) || items[0].CausalityToken == "" {
+		t.Errorf("unexpected items %+v", items)
+	}
+
+	partitions, err := client.ReadIndex(ctx, "app", k2vIndexQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 3 || partitions[0] != (k2vPartition{PartitionKey: "sessions", Entries: 3, Values: 3, Bytes: 27}) {
+		t.Errorf("unexpected partitions %+v", partitions)
+	}
+	partitions, err = client.ReadIndex(ctx, "app", k2vIndexQuery{Prefix: "users/", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 1 || partitions[0].PartitionKey != "users/eu" {
+		t.Errorf("unexpected partitions %+v", partitions)
+	}
+}
@@ -2,25 +2,76 @@ package garage
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
-	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 type garageProvider struct {
-	client *garage.APIClient
-	ctx    context.Context
+	client             garageClient
+	apiVersion         int
+	consistencyTimeout time.Duration
+	// dnsCompatibleAliases requires global aliases to be single DNS labels.
+	dnsCompatibleAliases bool
+	// s3 is how to reach the S3 API, nil when no S3 endpoint is configured.
+	s3 *s3Config
+	// k2v is how to reach the K2V API, nil when no K2V endpoint is
+	// configured.
+	k2v *k2vConfig
+	// garageVersion is the version of the Garage node answering the admin
+	// API, nil when unknown because the connectivity check was skipped.
+	garageVersion *version.Version
 }
 
-func updateContext(tfCtx context.Context, p *garageProvider) context.Context {
-	return context.WithValue(tfCtx, garage.ContextAccessToken, p.ctx.Value(garage.ContextAccessToken))
+// Default timeouts of resources, overridable through their timeouts block.
+const (
+	defaultCreateTimeout = 5 * time.Minute
+	defaultReadTimeout   = 2 * time.Minute
+	defaultUpdateTimeout = 5 * time.Minute
+	defaultDeleteTimeout = 5 * time.Minute
+)
+
+// computedSchema turns the schema of a resource into the one of the matching
+// data source, where every attribute is read.
+func computedSchema(resourceSchema map[string]*schema.Schema) map[string]*schema.Schema {
+	for _, attribute := range resourceSchema {
+		attribute.Optional = false
+		attribute.Required = false
+		attribute.Computed = true
+		attribute.ForceNew = false
+		attribute.Default = nil
+		attribute.DefaultFunc = nil
+		attribute.ValidateFunc = nil
+		attribute.ValidateDiagFunc = nil
+		attribute.DiffSuppressFunc = nil
+		attribute.ConflictsWith = nil
+	}
+	return resourceSchema
 }
 
 // Provider -
 func Provider() *schema.Provider {
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
+			"config_file": {
+				Description: "Path to the `garage.toml` of a Garage node. The admin API address and token are taken from its `[admin]` section, the S3 API address and region from its `[s3_api]` section, and the K2V API address from its `[k2v_api]` section, when they are not set otherwise.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_CONFIG_FILE", nil),
+			},
+			"endpoint": {
+				Description:  "URL of the admin API, which may include a path prefix when it is served behind a reverse proxy, for example `https://ops.example/garage-admin`. Takes precedence over `host` and `scheme`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("GARAGE_ENDPOINT", nil),
+				ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+			},
 			"host": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -37,46 +88,327 @@ func Provider() *schema.Provider {
 				Sensitive:   true,
 				DefaultFunc: schema.EnvDefaultFunc("GARAGE_TOKEN", nil),
 			},
+			"token_file": {
+				Description: "Path to a file holding the admin token, used when `token` is not set.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_TOKEN_FILE", nil),
+			},
+			"token_command": {
+				Description: "Command, given as the program followed by its arguments, printing the admin token on its standard output. Used when neither `token` nor `token_file` are set.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"proxy_url": {
+				Description:  "URL of the proxy used to reach the admin API. Defaults to the proxy set in the `HTTPS_PROXY`, `HTTP_PROXY` and `NO_PROXY` environment variables.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("GARAGE_PROXY_URL", nil),
+				ValidateFunc: validation.IsURLWithScheme([]string{"http", "https", "socks5"}),
+			},
+			"headers": {
+				Description: "Additional HTTP headers sent with every admin API request, for example to authenticate against a proxy.",
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Sensitive:   true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"ca_cert_file": {
+				Description: "Path to a PEM-encoded CA certificate bundle used to verify the admin API certificate.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_CA_CERT_FILE", ""),
+			},
+			"ca_cert_pem": {
+				Description: "PEM-encoded CA certificate bundle used to verify the admin API certificate.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_CA_CERT_PEM", ""),
+			},
+			"client_cert": {
+				Description: "PEM-encoded client certificate, or path to it, presented to the admin API for mutual TLS.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_CLIENT_CERT", ""),
+			},
+			"client_key": {
+				Description: "PEM-encoded private key of `client_cert`, or path to it.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_CLIENT_KEY", ""),
+			},
+			"tls_server_name": {
+				Description: "Server name used to verify the admin API certificate, when it differs from `host`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_TLS_SERVER_NAME", ""),
+			},
+			"insecure_skip_verify": {
+				Description: "Disable verification of the admin API certificate. Only use this for testing.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_INSECURE_SKIP_VERIFY", false),
+			},
+			"admin_api_version": {
+				Description:  "Version of the admin API to use, either `v0`, `v1` or `v2`. Defaults to the most recent one the cluster speaks, or `v0` when `skip_connectivity_check` is set.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("GARAGE_ADMIN_API_VERSION", nil),
+				ValidateFunc: validation.StringInSlice([]string{"v0", "v1", "v2"}, false),
+			},
+			"skip_connectivity_check": {
+				Description: "Skip the admin API call made when configuring the provider to check the endpoint and token, for example in CI runs without access to the cluster.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_SKIP_CONNECTIVITY_CHECK", false),
+			},
+			"consistency_timeout": {
+				Description:  "How long to wait for a created or updated object to be visible through the admin API, as a duration such as `30s` or `2m`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("GARAGE_CONSISTENCY_TIMEOUT", "1m"),
+				ValidateFunc: validateDuration,
+			},
+			"dns_compatible_aliases": {
+				Description: "Require global aliases to be single DNS labels, without dots, so that buckets can be served as websites at `<alias>.<root_domain>` by the `[s3_web]` endpoint under a wildcard certificate.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_DNS_COMPATIBLE_ALIASES", false),
+			},
+			"s3_endpoint": {
+				Description:  "URL of the S3 API, for example `https://s3.example`, through which resources manage bucket contents and settings the admin API doesn't cover. Defaults to the `[s3_api]` address of `config_file`. It is reached with the system CA certificates and the proxy set in the environment, not with the TLS, proxy and header settings of the admin API.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("GARAGE_S3_ENDPOINT", nil),
+				ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+			},
+			"k2v_endpoint": {
+				Description:  "URL of the K2V API, for example `https://k2v.example`, through which K2V items are managed and read. Defaults to the `[k2v_api]` address of `config_file`. Requests are signed for `s3_region`, and it is reached as `s3_endpoint` is.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("GARAGE_K2V_ENDPOINT", nil),
+				ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+			},
+			"s3_region": {
+				Description: "Region requests to the S3 API are signed for, the `s3_region` of the `[s3_api]` section of `garage.toml`. Defaults to the one of `config_file`, or `garage`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_S3_REGION", nil),
+			},
+			"s3_use_path_style": {
+				Description: "Address buckets in the path of `s3_endpoint` URLs. Set to `false` to address them as subdomains of its host instead, which requires `root_domain` in the `[s3_api]` section of `garage.toml`.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_S3_USE_PATH_STYLE", true),
+			},
+			"s3_access_key_id": {
+				Description: "ID of the key signing requests to the S3 API for resources not given an `access_key_id`. Resources given one, typically of a `garage_key` managed in the same configuration, sign requests with that key, whose secret is read through the admin API.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_S3_ACCESS_KEY_ID", nil),
+			},
+			"s3_secret_access_key": {
+				Description: "Secret of `s3_access_key_id`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_S3_SECRET_ACCESS_KEY", nil),
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"garage_bucket":              resourceBucket(),
-			"garage_bucket_global_alias": resourceBucketGlobalAlias(),
-			"garage_bucket_key":          resourceBucketKey(),
-			"garage_bucket_local_alias":  resourceBucketLocalAlias(),
-			"garage_key":                 resourceKey(),
+			"garage_admin_token":           resourceAdminToken(),
+			"garage_bucket":                resourceBucket(),
+			"garage_bucket_cors":           resourceBucketCors(),
+			"garage_bucket_directory_sync": resourceBucketDirectorySync(),
+			"garage_bucket_global_alias":   resourceBucketGlobalAlias(),
+			"garage_bucket_key":            resourceBucketKey(),
+			"garage_bucket_lifecycle":      resourceBucketLifecycle(),
+			"garage_bucket_local_alias":    resourceBucketLocalAlias(),
+			"garage_bucket_object":         resourceBucketObject(),
+			"garage_bucket_website":        resourceBucketWebsite(),
+			"garage_cluster_layout":        resourceClusterLayout(),
+			"garage_k2v_item":              resourceK2VItem(),
+			"garage_key":                   resourceKey(),
+			"garage_key_rotation":          resourceKeyRotation(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"garage_bucket":         dataSourceBucket(),
+			"garage_bucket_object":  dataSourceBucketObject(),
+			"garage_bucket_objects": dataSourceBucketObjects(),
+			"garage_cluster_health": dataSourceClusterHealth(),
+			"garage_k2v_index":      dataSourceK2VIndex(),
+			"garage_k2v_item":       dataSourceK2VItem(),
+			"garage_k2v_items":      dataSourceK2VItems(),
+			"garage_key":            dataSourceKey(),
 		},
-		DataSourcesMap:       map[string]*schema.Resource{},
 		ConfigureContextFunc: providerConfigure,
 	}
 }
 
+// adminEndpoint returns the base URL of the admin API, without trailing slash,
+// from endpoint or, for older configurations, from host and scheme.
+func adminEndpoint(d *schema.ResourceData) (string, error) {
+	endpoint := d.Get("endpoint").(string)
+	if endpoint == "" {
+		host := d.Get("host").(string)
+		if host == "" {
+			return "", nil
+		}
+		endpoint = fmt.Sprintf("%s://%s", d.Get("scheme").(string), host)
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	if (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q: expected an http or https URL", endpoint)
+	}
+
+	return strings.TrimSuffix(endpointURL.String(), "/"), nil
+}
+
 func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	host := d.Get("host").(string)
-	scheme := d.Get("scheme").(string)
-	token := d.Get("token").(string)
+	consistencyTimeout, _ := time.ParseDuration(d.Get("consistency_timeout").(string))
 
 	var diags diag.Diagnostics
 
-	if host == "" || token == "" {
+	token, err := resolveToken(ctx, d)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to resolve token",
+			Detail:   err.Error(),
+		})
+		return nil, diags
+	}
+
+	endpoint, err := adminEndpoint(d)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Invalid endpoint",
+			Detail:   err.Error(),
+		})
+		return nil, diags
+	}
+
+	if configFile := d.Get("config_file").(string); configFile != "" && (endpoint == "" || token == "") {
+		endpoint, token, err = withGarageConfig(configFile, endpoint, token)
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Invalid config_file",
+				Detail:   err.Error(),
+			})
+			return nil, diags
+		}
+	}
+
+	if endpoint == "" || token == "" {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to find endpoint or token",
+			Detail:   "Either endpoint or host, and one of token, token_file or token_command must be set, or read from config_file",
+		})
+		return nil, diags
+	}
+
+	httpClient, err := newHTTPClient(d)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Invalid connection configuration",
+			Detail:   err.Error(),
+		})
+		return nil, diags
+	}
+
+	dataHTTPClient := newDataHTTPClient()
+	s3, err := newS3Config(d, dataHTTPClient)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Invalid S3 configuration",
+			Detail:   err.Error(),
+		})
+		return nil, diags
+	}
+
+	k2v, err := newK2VConfig(d, dataHTTPClient)
+	if err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
-			Summary:  "Unable to find host or token",
-			Detail:   "Those values must be set",
+			Summary:  "Invalid K2V configuration",
+			Detail:   err.Error(),
 		})
 		return nil, diags
 	}
 
-	// TODO: add more configuration values
-	configuration := garage.NewConfiguration()
-	configuration.Host = host
-	configuration.Scheme = scheme
+	headers := map[string]string{}
+	for name, value := range d.Get("headers").(map[string]interface{}) {
+		headers[name] = value.(string)
+	}
 
-	client := garage.NewAPIClient(configuration)
+	admin := &adminClient{
+		endpoint:   endpoint,
+		httpClient: httpClient,
+		headers:    headers,
+		token:      token,
+	}
+
+	apiVersions := adminAPIVersions
+	if apiVersion, ok := d.GetOk("admin_api_version"); ok {
+		apiVersions = []int{parseAPIVersion(apiVersion.(string))}
+	}
 
-	ctx = context.WithValue(ctx, garage.ContextAccessToken, token)
+	apiVersion := apiVersions[len(apiVersions)-1]
+	var garageVersion *version.Version
+	if !d.Get("skip_connectivity_check").(bool) {
+		var rawGarageVersion string
+		var checkDiags diag.Diagnostics
+		apiVersion, rawGarageVersion, checkDiags = checkConnectivity(ctx, admin, apiVersions)
+		diags = append(diags, checkDiags...)
+		if diags.HasError() {
+			return nil, diags
+		}
+
+		garageVersion, err = parseGarageVersion(rawGarageVersion)
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Unable to detect the Garage version",
+				Detail:   fmt.Sprintf("%s. Features won't be checked against the cluster before applying changes.", err),
+			})
+		}
+	}
+	admin.apiVersion = apiVersion
 
 	return &garageProvider{
-		client: client,
-		ctx:    ctx,
+		client:               newGarageClient(admin),
+		apiVersion:           apiVersion,
+		consistencyTimeout:   consistencyTimeout,
+		dnsCompatibleAliases: d.Get("dns_compatible_aliases").(bool),
+		s3:                   s3,
+		k2v:                  k2v,
+		garageVersion:        garageVersion,
 	}, diags
 }
+
+func validateDuration(v interface{}, k string) (ws []string, errors []error) {
+	duration, err := time.ParseDuration(v.(string))
+	if err != nil {
+		errors = append(errors, fmt.Errorf("%q must be a duration: %w", k, err))
+		return
+	}
+	if duration <= 0 {
+		errors = append(errors, fmt.Errorf("%q must be positive", k))
+	}
+	return
+}
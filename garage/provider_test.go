@@ -0,0 +1,205 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// testAPIVersions are the admin API versions resources are tested against.
+var testAPIVersions = []int{1, 2}
+
+// unknownValue stands for values only known after apply in raw configurations,
+// as the SDK marks them.
+const unknownValue = "74D93920-ED26-11E3-AC10-0800200C9A66"
+
+func testProviderFactories() map[string]func() (*schema.Provider, error) {
+	return map[string]func() (*schema.Provider, error){
+		"garage": func() (*schema.Provider, error) {
+			return Provider(), nil
+		},
+	}
+}
+
+// skipWithoutTerraform skips tests driving the terraform CLI when it can't be
+// found. The development shell of the flake provides it.
+func skipWithoutTerraform(t *testing.T) {
+	t.Helper()
+
+	if os.Getenv("TF_ACC_TERRAFORM_PATH") != "" {
+		return
+	}
+	if _, err := exec.LookPath("terraform"); err != nil {
+		t.Skip("terraform CLI not found: add it to PATH or set TF_ACC_TERRAFORM_PATH")
+	}
+}
+
+// unitTest runs the test case built by testCase against a fake node of each
+// of apiVersions, checking that every object was destroyed afterwards.
+func unitTest(t *testing.T, apiVersions []int, testCase func(fake *fakeGarage) resource.TestCase) {
+	skipWithoutTerraform(t)
+
+	for _, apiVersion := range apiVersions {
+		apiVersion := apiVersion
+		t.Run(fmt.Sprintf("v%d", apiVersion), func(t *testing.T) {
+			fake := newFakeGarage(t, apiVersion)
+			c := testCase(fake)
+			c.ProviderFactories = testProviderFactories()
+			if c.CheckDestroy == nil {
+				c.CheckDestroy = fake.checkDestroyed
+			}
+			resource.UnitTest(t, c)
+		})
+	}
+}
+
+// testGarage is the Garage node acceptance tests run against.
+type testGarage struct {
+	// providerConfig is the provider block, empty when the provider is
+	// configured by the environment.
+	providerConfig string
+	client         garageClient
+	provider       *garageProvider
+}
+
+// newTestGarage returns the Garage node configured by the GARAGE_*
+// environment variables, such as the one of docker/setup.sh, or a fake node
+// when none is configured.
+func newTestGarage(t *testing.T) *testGarage {
+	t.Helper()
+
+	if os.Getenv(resource.EnvTfAcc) == "" {
+		t.Skipf("acceptance tests are skipped unless %s is set", resource.EnvTfAcc)
+	}
+
+	garage := &testGarage{}
+	config := map[string]interface{}{}
+	if os.Getenv("GARAGE_ENDPOINT") == "" && os.Getenv("GARAGE_HOST") == "" && os.Getenv("GARAGE_CONFIG_FILE") == "" {
+		fake := newFakeGarage(t, adminAPIVersions[0])
+		garage.providerConfig = fake.providerConfig()
+		config["endpoint"] = fake.URL
+		config["token"] = fakeAdminToken
+		config["s3_endpoint"] = fake.s3.URL
+		config["k2v_endpoint"] = fake.k2v.URL
+	}
+
+	provider := Provider()
+	diags := provider.Configure(context.Background(), terraform.NewResourceConfigRaw(config))
+	if diags.HasError() {
+		t.Fatalf("failed to configure the provider: %v", diags)
+	}
+	garage.provider = provider.Meta().(*garageProvider)
+	garage.client = garage.provider.client
+
+	return garage
+}
+
+// accTest runs the acceptance test case built by testCase, checking that
+// every bucket and key was destroyed afterwards.
+func accTest(t *testing.T, testCase func(garage *testGarage) resource.TestCase) {
+	garage := newTestGarage(t)
+	c := testCase(garage)
+	c.ProviderFactories = testProviderFactories()
+	c.CheckDestroy = garage.checkDestroyed
+	resource.Test(t, c)
+}
+
+func (g *testGarage) checkDestroyed(s *terraform.State) error {
+	ctx := context.Background()
+
+	for _, rs := range s.RootModule().Resources {
+		var err error
+		switch rs.Type {
+		case "garage_bucket":
+			_, err = g.client.GetBucket(ctx, rs.Primary.ID)
+		case "garage_key":
+			_, err = g.client.GetKey(ctx, rs.Primary.ID)
+		case "garage_key_rotation":
+			_, err = g.client.GetKey(ctx, rs.Primary.Attributes["access_key_id"])
+		default:
+			continue
+		}
+		if !isNotFound(err) {
+			return fmt.Errorf("%s %s wasn't destroyed: %v", rs.Type, rs.Primary.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// outOfBand returns a PreConfig function making changes behind the back of
+// Terraform.
+func (g *testGarage) outOfBand(t *testing.T, change func(ctx context.Context, client garageClient) error) func() {
+	return func() {
+		if err := change(context.Background(), g.client); err != nil {
+			t.Fatalf("failed to change Garage out of band: %v", err)
+		}
+	}
+}
+
+// skipWithoutS3 skips tests going through the S3 API when no S3 endpoint is
+// configured, as GARAGE_S3_ENDPOINT does.
+func (g *testGarage) skipWithoutS3(t *testing.T) {
+	t.Helper()
+
+	if g.provider.s3 == nil {
+		t.Skip("S3 API endpoint not configured: set GARAGE_S3_ENDPOINT")
+	}
+}
+
+// skipWithoutK2V skips tests going through the K2V API when no K2V endpoint
+// is configured, as GARAGE_K2V_ENDPOINT does.
+func (g *testGarage) skipWithoutK2V(t *testing.T) {
+	t.Helper()
+
+	if g.provider.k2v == nil {
+		t.Skip("K2V API endpoint not configured: set GARAGE_K2V_ENDPOINT")
+	}
+}
+
+// checkBucket runs check on the bucket with the ID stored in bucketID.
+func (g *testGarage) checkBucket(bucketID *string, check func(bucketInfo *bucket) error) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		bucketInfo, err := g.client.GetBucket(context.Background(), *bucketID)
+		if err != nil {
+			return err
+		}
+		return check(bucketInfo)
+	}
+}
+
+// checkKey runs check on the key with the ID stored in accessKeyID.
+func (g *testGarage) checkKey(accessKeyID *string, check func(keyInfo *accessKey) error) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		keyInfo, err := g.client.GetKey(context.Background(), *accessKeyID)
+		if err != nil {
+			return err
+		}
+		return check(keyInfo)
+	}
+}
+
+// storeAttr stores the value of an attribute, for later steps to find the
+// objects in Garage.
+func storeAttr(name string, key string, value *string) resource.TestCheckFunc {
+	return resource.TestCheckResourceAttrWith(name, key, func(v string) error {
+		*value = v
+		return nil
+	})
+}
+
+func TestProvider(t *testing.T) {
+	if err := Provider().InternalValidate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
@@ -0,0 +1,180 @@
+package garage
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func schemaAdminToken() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"name": {
+			Description: "The name of the token.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"scope": {
+			Description: "The admin API endpoints the token may call, such as `GetBucketInfo`, or `*` for all of them.",
+			Type:        schema.TypeList,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+			Optional: true,
+			Computed: true,
+		},
+		"expiration": {
+			Description:   "The expiration date of the token, in RFC 3339 format.",
+			Type:          schema.TypeString,
+			Optional:      true,
+			Computed:      true,
+			ValidateFunc:  validation.IsRFC3339Time,
+			ConflictsWith: []string{"never_expires"},
+		},
+		"never_expires": {
+			Description:   "Remove the expiration date of the token.",
+			Type:          schema.TypeBool,
+			Optional:      true,
+			Default:       false,
+			ConflictsWith: []string{"expiration"},
+		},
+		// Computed
+		"secret_token": {
+			Description: "The secret of the token, only known when it was created by Terraform.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"created": {
+			Description: "The creation date of the token.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"expired": {
+			Description: "Whether the token has expired.",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+	}
+}
+
+func resourceAdminToken() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to manage Garage admin API tokens, through the v2 admin API.",
+		CreateContext: resourceAdminTokenCreate,
+		ReadContext:   resourceAdminTokenRead,
+		UpdateContext: resourceAdminTokenUpdate,
+		DeleteContext: resourceAdminTokenDelete,
+		Schema:        schemaAdminToken(),
+		CustomizeDiff: customizeDiffRequireAPIVersion("garage_admin_token", 2, featureAdminTokens),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+	}
+}
+
+func expandAdminToken(d *schema.ResourceData) adminTokenUpdate {
+	adminTokenRequest := adminTokenUpdate{
+		Name:         d.Get("name").(string),
+		NeverExpires: d.Get("never_expires").(bool),
+	}
+
+	if expiration, ok := d.GetOk("expiration"); ok && !adminTokenRequest.NeverExpires {
+		expiration := expiration.(string)
+		adminTokenRequest.Expiration = &expiration
+	}
+
+	if scope, ok := d.GetOk("scope"); ok {
+		for _, endpoint := range scope.([]interface{}) {
+			adminTokenRequest.Scope = append(adminTokenRequest.Scope, endpoint.(string))
+		}
+	}
+
+	return adminTokenRequest
+}
+
+func flattenAdminToken(token *adminToken) interface{} {
+	expiration := ""
+	if token.Expiration != nil {
+		expiration = *token.Expiration
+	}
+	return map[string]interface{}{
+		"name":       token.Name,
+		"scope":      token.Scope,
+		"expiration": expiration,
+		"created":    token.Created,
+		"expired":    token.Expired,
+	}
+}
+
+func resourceAdminTokenCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	token, err := p.client.CreateAdminToken(ctx, expandAdminToken(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(token.ID)
+
+	err = d.Set("secret_token", token.SecretToken)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return setAdminToken(d, token)
+}
+
+func resourceAdminTokenRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	token, err := p.client.GetAdminToken(ctx, d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return setAdminToken(d, token)
+}
+
+func setAdminToken(d *schema.ResourceData, token *adminToken) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for key, value := range flattenAdminToken(token).(map[string]interface{}) {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceAdminTokenUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	token, err := p.client.UpdateAdminToken(ctx, d.Id(), expandAdminToken(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return setAdminToken(d, token)
+}
+
+func resourceAdminTokenDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	err := p.client.DeleteAdminToken(ctx, d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
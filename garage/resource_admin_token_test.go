@@ -0,0 +1,72 @@
+package garage
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestUnitResourceAdminToken(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+resource "garage_admin_token" "test" {
+  name       = "test"
+  scope      = ["GetBucketInfo", "ListBuckets"]
+  expiration = "2100-01-01T00:00:00Z"
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_admin_token.test", "name", "test"),
+						resource.TestCheckResourceAttr("garage_admin_token.test", "scope.#", "2"),
+						resource.TestCheckResourceAttr("garage_admin_token.test", "expiration", "2100-01-01T00:00:00Z"),
+						resource.TestCheckResourceAttr("garage_admin_token.test", "expired", "false"),
+						resource.TestCheckResourceAttrSet("garage_admin_token.test", "secret_token"),
+					),
+				},
+				{
+					Config: fake.providerConfig() + `
+resource "garage_admin_token" "test" {
+  name          = "renamed"
+  scope         = ["*"]
+  never_expires = true
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_admin_token.test", "name", "renamed"),
+						resource.TestCheckResourceAttr("garage_admin_token.test", "expiration", ""),
+						fake.withState(func() error {
+							for _, token := range fake.tokens {
+								if token.Expiration != nil {
+									return fmt.Errorf("admin token %s still expires", token.ID)
+								}
+							}
+							return nil
+						}),
+					),
+				},
+			},
+		}
+	})
+}
+
+func TestUnitResourceAdminTokenRequiresV2(t *testing.T) {
+	unitTest(t, []int{1}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+resource "garage_admin_token" "test" {
+  name = "test"
+}
+`,
+					ExpectError: regexp.MustCompile("garage_admin_token"),
+				},
+			},
+		}
+	})
+}
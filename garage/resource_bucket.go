@@ -3,7 +3,6 @@ package garage
 import (
 	"context"
 
-	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/thoas/go-funk"
@@ -12,19 +11,28 @@ import (
 func schemaBucket() map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		"website_access_enabled": {
-			Type:     schema.TypeBool,
-			Optional: true,
-			Default:  false,
+			Type:             schema.TypeBool,
+			Optional:         true,
+			Default:          false,
+			DiffSuppressFunc: suppressUnmanagedWebsite,
 		},
 		"website_config_index_document": {
-			Type:     schema.TypeString,
-			Optional: true,
-			Computed: true,
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			DiffSuppressFunc: suppressUnmanagedWebsite,
 		},
 		"website_config_error_document": {
-			Type:     schema.TypeString,
-			Optional: true,
-			Computed: true,
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			DiffSuppressFunc: suppressUnmanagedWebsite,
+		},
+		"manage_website": {
+			Description: "Whether website access and documents are set from the `website_*` attributes. Set it to `false` when `garage_bucket_website` manages them, so that the `website_*` attributes only read them.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     true,
 		},
 		"quota_max_size": {
 			Type:     schema.TypeInt,
@@ -91,6 +99,24 @@ func schemaBucket() map[string]*schema.Schema {
 			Type:     schema.TypeInt,
 			Computed: true,
 		},
+		// Computed, only reported by the v2 admin API
+		"created": {
+			Description: "The creation date of the bucket.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"unfinished_multipart_uploads": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"unfinished_multipart_upload_parts": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"unfinished_multipart_upload_bytes": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
 	}
 }
 
@@ -102,49 +128,73 @@ func resourceBucket() *schema.Resource {
 		UpdateContext: resourceBucketUpdate,
 		DeleteContext: resourceBucketDelete,
 		Schema:        schemaBucket(),
+		CustomizeDiff: customizeDiffRequireAdminAPI,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: importBucketState,
 		},
 	}
 }
 
-func flattenBucketKey(bucketKey garage.BucketKeyInfo) interface{} {
+// suppressUnmanagedWebsite ignores the website attributes of buckets whose
+// website is managed by garage_bucket_website.
+func suppressUnmanagedWebsite(k, old, new string, d *schema.ResourceData) bool {
+	return !d.Get("manage_website").(bool)
+}
+
+// importBucketState imports buckets with manage_website at its default, which
+// isn't recorded in Garage.
+func importBucketState(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	err := d.Set("manage_website", true)
+	if err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+func flattenBucketKey(key bucketKey) interface{} {
 	return map[string]interface{}{
-		"access_key_id":     *bucketKey.AccessKeyId,
-		"name":              *bucketKey.Name,
-		"permissions_read":  *bucketKey.Permissions.Read,
-		"permissions_write": *bucketKey.Permissions.Write,
-		"permissions_owner": *bucketKey.Permissions.Owner,
+		"access_key_id":     key.AccessKeyID,
+		"name":              key.Name,
+		"permissions_read":  key.Permissions.Read,
+		"permissions_write": key.Permissions.Write,
+		"permissions_owner": key.Permissions.Owner,
 	}
 }
 
-func flattenBucketInfo(bucket *garage.BucketInfo) interface{} {
+func flattenBucketInfo(bucketInfo *bucket) interface{} {
 	b := map[string]interface{}{}
-	b["global_aliases"] = bucket.GlobalAliases
+	b["global_aliases"] = bucketInfo.GlobalAliases
 
-	if bucket.HasWebsiteAccess() {
-		b["website_access_enabled"] = bucket.GetWebsiteAccess()
-	}
+	b["website_access_enabled"] = bucketInfo.WebsiteAccess
 
-	if bucket.HasWebsiteConfig() {
-		b["website_config_index_document"] = bucket.GetWebsiteConfig().IndexDocument
-		b["website_config_error_document"] = bucket.GetWebsiteConfig().ErrorDocument
+	if bucketInfo.WebsiteConfig != nil {
+		b["website_config_index_document"] = bucketInfo.WebsiteConfig.IndexDocument
+		b["website_config_error_document"] = stringValue(bucketInfo.WebsiteConfig.ErrorDocument)
 	}
 
-	if bucket.HasQuotas() {
-		if bucket.Quotas.MaxSize.IsSet() {
-			b["quota_max_size"] = bucket.GetQuotas().MaxSize.Get()
-		}
-		if bucket.Quotas.MaxObjects.IsSet() {
-			b["quota_max_objects"] = bucket.GetQuotas().MaxObjects.Get()
-		}
+	if bucketInfo.Quotas.MaxSize != nil {
+		b["quota_max_size"] = *bucketInfo.Quotas.MaxSize
+	}
+	if bucketInfo.Quotas.MaxObjects != nil {
+		b["quota_max_objects"] = *bucketInfo.Quotas.MaxObjects
 	}
 
-	b["keys"] = funk.Map(bucket.GetKeys(), flattenBucketKey)
+	b["keys"] = funk.Map(bucketInfo.Keys, flattenBucketKey)
+
+	b["objects"] = bucketInfo.Objects
+	b["bytes"] = bucketInfo.Bytes
+	b["unfinished_uploads"] = bucketInfo.UnfinishedUploads
 
-	b["objects"] = *bucket.Objects
-	b["bytes"] = *bucket.Bytes
-	b["unfinished_uploads"] = *bucket.UnfinishedUploads
+	b["created"] = bucketInfo.Created
+	b["unfinished_multipart_uploads"] = bucketInfo.UnfinishedMultipartUploads
+	b["unfinished_multipart_upload_parts"] = bucketInfo.UnfinishedMultipartUploadParts
+	b["unfinished_multipart_upload_bytes"] = bucketInfo.UnfinishedMultipartUploadBytes
 
 	return b
 }
@@ -153,12 +203,17 @@ func resourceBucketCreate(ctx context.Context, d *schema.ResourceData, m interfa
 	p := m.(*garageProvider)
 	var diags diag.Diagnostics
 
-	bucketInfo, _, err := p.client.BucketApi.CreateBucket(updateContext(ctx, p)).CreateBucketRequest(garage.CreateBucketRequest{}).Execute()
+	bucketInfo, err := p.client.CreateBucket(ctx)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	d.SetId(*bucketInfo.Id)
+	d.SetId(bucketInfo.ID)
+
+	_, err = waitForBucket(ctx, p, d.Id(), bucketExists)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
 	diags = resourceBucketUpdate(ctx, d, m)
 
@@ -167,15 +222,25 @@ func resourceBucketCreate(ctx context.Context, d *schema.ResourceData, m interfa
 
 func resourceBucketRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	p := m.(*garageProvider)
-	var diags diag.Diagnostics
 
 	bucketID := d.Id()
 
-	bucketInfo, _, err := p.client.BucketApi.GetBucketInfo(updateContext(ctx, p), bucketID).Execute()
+	bucketInfo, err := p.client.GetBucket(ctx, bucketID)
+	if isNotFound(err) {
+		// Deleted outside of Terraform
+		d.SetId("")
+		return nil
+	}
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
+	return setBucketInfo(d, bucketInfo)
+}
+
+func setBucketInfo(d *schema.ResourceData, bucketInfo *bucket) diag.Diagnostics {
+	var diags diag.Diagnostics
+
 	for key, value := range flattenBucketInfo(bucketInfo).(map[string]interface{}) {
 		err := d.Set(key, value)
 		if err != nil {
@@ -188,65 +253,61 @@ func resourceBucketRead(ctx context.Context, d *schema.ResourceData, m interface
 
 func resourceBucketUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	p := m.(*garageProvider)
-	var diags diag.Diagnostics
 
-	webAccessEnabled := false
-	var webConfigIndexDoc *string
-	webConfigIndexDoc = nil
-	var webConfigErrorDoc *string
-	webConfigErrorDoc = nil
+	website := bucketWebsite{}
 	if webAccessEnabledVal, ok := d.GetOk("website_access_enabled"); ok {
-		webAccessEnabled = webAccessEnabledVal.(bool)
+		website.Enabled = webAccessEnabledVal.(bool)
 	}
-	if webConfigIndexDocVal, ok := d.GetOk("website_config_index_document"); ok {
+	// Garage refuses documents when disabling website access, and they may
+	// linger in the state as they are computed.
+	if webConfigIndexDocVal, ok := d.GetOk("website_config_index_document"); ok && website.Enabled {
 		webConfigIndexDocVal := webConfigIndexDocVal.(string)
-		webConfigIndexDoc = &webConfigIndexDocVal
+		website.IndexDocument = &webConfigIndexDocVal
 	}
-	if webConfigErrorDocVal, ok := d.GetOk("website_config_error_document"); ok {
+	if webConfigErrorDocVal, ok := d.GetOk("website_config_error_document"); ok && website.Enabled {
 		webConfigErrorDocVal := webConfigErrorDocVal.(string)
-		webConfigErrorDoc = &webConfigErrorDocVal
+		website.ErrorDocument = &webConfigErrorDocVal
 	}
 
-	var quotaMaxSize *int32
-	quotaMaxSize = nil
-	var quotaMaxObjects *int32
-	quotaMaxObjects = nil
+	quotas := bucketQuotas{}
 	if quotaMaxSizeVal, ok := d.GetOk("quota_max_size"); ok {
-		quotaMaxSizeVal := int32(quotaMaxSizeVal.(int))
-		quotaMaxSize = &quotaMaxSizeVal
+		quotaMaxSizeVal := int64(quotaMaxSizeVal.(int))
+		quotas.MaxSize = &quotaMaxSizeVal
 	}
 	if quotaMaxObjectsVal, ok := d.GetOk("quota_max_objects"); ok {
-		quotaMaxObjectsVal := int32(quotaMaxObjectsVal.(int))
-		quotaMaxObjects = &quotaMaxObjectsVal
+		quotaMaxObjectsVal := int64(quotaMaxObjectsVal.(int))
+		quotas.MaxObjects = &quotaMaxObjectsVal
 	}
 
-	updateBucketRequest := garage.UpdateBucketRequest{
-		WebsiteAccess: &garage.UpdateBucketRequestWebsiteAccess{
-			Enabled:       &webAccessEnabled,
-			IndexDocument: webConfigIndexDoc,
-			ErrorDocument: webConfigErrorDoc,
-		},
-		Quotas: &garage.UpdateBucketRequestQuotas{
-			MaxSize:    *garage.NewNullableInt32(quotaMaxSize),
-			MaxObjects: *garage.NewNullableInt32(quotaMaxObjects),
-		},
+	// Setting website access through the admin API drops the redirects and
+	// routing rules of garage_bucket_website, so it's left to it when the
+	// website isn't managed here.
+	var websiteUpdate *bucketWebsite
+	if d.Get("manage_website").(bool) {
+		_, err := p.client.SetBucketWebsite(ctx, d.Id(), website)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		websiteUpdate = &website
 	}
-
-	_, _, err := p.client.BucketApi.UpdateBucket(updateContext(ctx, p), d.Id()).UpdateBucketRequest(updateBucketRequest).Execute()
+	_, err := p.client.SetBucketQuotas(ctx, d.Id(), quotas)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	diags = resourceBucketRead(ctx, d, m)
+	bucketInfo, err := waitForBucket(ctx, p, d.Id(), bucketUpdateApplied(websiteUpdate, quotas))
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
-	return diags
+	return setBucketInfo(d, bucketInfo)
 }
 
 func resourceBucketDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	p := m.(*garageProvider)
 	var diags diag.Diagnostics
 
-	_, err := p.client.BucketApi.DeleteBucket(updateContext(ctx, p), d.Id()).Execute()
+	err := p.client.DeleteBucket(ctx, d.Id())
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -0,0 +1,211 @@
+package garage
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func schemaBucketCors() map[string]*schema.Schema {
+	return withS3Bucket(map[string]*schema.Schema{
+		"cors_rule": {
+			Description: "Rule allowing cross-origin requests to the bucket. Requests are checked against the rules in order.",
+			Type:        schema.TypeList,
+			Required:    true,
+			MinItems:    1,
+			MaxItems:    100,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"id": {
+						Description:  "Identifier of the rule.",
+						Type:         schema.TypeString,
+						Optional:     true,
+						ValidateFunc: validation.StringLenBetween(1, 255),
+					},
+					"allowed_origins": {
+						Description: "Origins allowed to send cross-origin requests, such as `https://example.com`, possibly with one `*` wildcard.",
+						Type:        schema.TypeList,
+						Required:    true,
+						MinItems:    1,
+						Elem: &schema.Schema{
+							Type: schema.TypeString,
+						},
+					},
+					"allowed_methods": {
+						Description: "HTTP methods allowed in cross-origin requests, among `GET`, `PUT`, `HEAD`, `POST` and `DELETE`.",
+						Type:        schema.TypeList,
+						Required:    true,
+						MinItems:    1,
+						Elem: &schema.Schema{
+							Type:         schema.TypeString,
+							ValidateFunc: validation.StringInSlice([]string{"GET", "PUT", "HEAD", "POST", "DELETE"}, false),
+						},
+					},
+					"allowed_headers": {
+						Description: "Headers allowed in preflight requests, possibly with `*` wildcards.",
+						Type:        schema.TypeList,
+						Optional:    true,
+						Elem: &schema.Schema{
+							Type: schema.TypeString,
+						},
+					},
+					"expose_headers": {
+						Description: "Headers of the answers that browsers let applications read.",
+						Type:        schema.TypeList,
+						Optional:    true,
+						Elem: &schema.Schema{
+							Type: schema.TypeString,
+						},
+					},
+					"max_age_seconds": {
+						Description:  "How long, in seconds, browsers may cache the answer to preflight requests.",
+						Type:         schema.TypeInt,
+						Optional:     true,
+						ValidateFunc: validation.IntAtLeast(0),
+					},
+				},
+			},
+		},
+	})
+}
+
+func resourceBucketCors() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to manage the CORS configuration of a Garage bucket, which lets browser applications of other origins call its S3 API, through the S3 API. The signing key must own the bucket.",
+		CreateContext: resourceBucketCorsPut,
+		ReadContext:   resourceBucketCorsRead,
+		UpdateContext: resourceBucketCorsPut,
+		DeleteContext: resourceBucketCorsDelete,
+		Schema:        schemaBucketCors(),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importS3StateFromID("bucket"),
+		},
+	}
+}
+
+func expandStringList(values []interface{}) []string {
+	list := make([]string, len(values))
+	for i, value := range values {
+		list[i] = value.(string)
+	}
+	return list
+}
+
+func expandCorsRules(rules []interface{}) []corsRule {
+	corsRules := make([]corsRule, len(rules))
+	for i, rule := range rules {
+		rule := rule.(map[string]interface{})
+
+		corsRules[i] = corsRule{
+			ID:             rule["id"].(string),
+			AllowedOrigins: expandStringList(rule["allowed_origins"].([]interface{})),
+			AllowedMethods: expandStringList(rule["allowed_methods"].([]interface{})),
+			AllowedHeaders: expandStringList(rule["allowed_headers"].([]interface{})),
+			ExposeHeaders:  expandStringList(rule["expose_headers"].([]interface{})),
+		}
+		if maxAgeSeconds := rule["max_age_seconds"].(int); maxAgeSeconds > 0 {
+			corsRules[i].MaxAgeSeconds = &maxAgeSeconds
+		}
+	}
+	return corsRules
+}
+
+func flattenCorsRule(rule corsRule) interface{} {
+	maxAgeSeconds := 0
+	if rule.MaxAgeSeconds != nil {
+		maxAgeSeconds = *rule.MaxAgeSeconds
+	}
+	return map[string]interface{}{
+		"id":              rule.ID,
+		"allowed_origins": rule.AllowedOrigins,
+		"allowed_methods": rule.AllowedMethods,
+		"allowed_headers": rule.AllowedHeaders,
+		"expose_headers":  rule.ExposeHeaders,
+		"max_age_seconds": maxAgeSeconds,
+	}
+}
+
+// resourceBucketCorsPut replaces the CORS configuration of the bucket, as S3
+// has no way to change rules separately.
+func resourceBucketCorsPut(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	bucket := d.Get("bucket").(string)
+
+	client, bucketName, err := p.s3Bucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = client.PutBucketCors(ctx, bucketName, expandCorsRules(d.Get("cors_rule").([]interface{})))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(bucket)
+
+	return resourceBucketCorsRead(ctx, d, m)
+}
+
+func resourceBucketCorsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	rules, err := client.GetBucketCors(ctx, bucketName)
+	if isS3NotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	corsRules := make([]interface{}, len(rules))
+	for i, rule := range rules {
+		corsRules[i] = flattenCorsRule(rule)
+	}
+
+	err = d.Set("cors_rule", corsRules)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceBucketCorsDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = client.DeleteBucketCors(ctx, bucketName)
+	if err != nil && !isS3NotFound(err) {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
@@ -0,0 +1,206 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// bucketOwnerConfig is a bucket reachable through the S3 API with a key
+// owning it, as changing bucket settings requires, along with settings, the
+// blocks configuring them.
+func bucketOwnerConfig(providerConfig string, alias string, settings string) string {
+	return providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {}
+
+resource "garage_bucket_global_alias" "test" {
+  bucket_id = garage_bucket.test.id
+  alias     = %q
+}
+
+resource "garage_key" "test" {
+  name = "settings"
+}
+
+resource "garage_bucket_key" "test" {
+  bucket_id     = garage_bucket.test.id
+  access_key_id = garage_key.test.access_key_id
+  read          = true
+  owner         = true
+}
+`, alias) + settings
+}
+
+func bucketCorsConfig(origin string) string {
+	return fmt.Sprintf(`
+resource "garage_bucket_cors" "test" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+
+  cors_rule {
+    id              = "app"
+    allowed_origins = [%q]
+    allowed_methods = ["GET", "PUT"]
+    allowed_headers = ["*"]
+    expose_headers  = ["ETag"]
+    max_age_seconds = 3600
+  }
+
+  cors_rule {
+    allowed_origins = ["*"]
+    allowed_methods = ["GET"]
+  }
+}
+`, origin)
+}
+
+func TestUnitResourceBucketCors(t *testing.T) {
+	unitTest(t, []int{1, 2}, func(fake *fakeGarage) resource.TestCase {
+		checkOrigin := func(origin string) resource.TestCheckFunc {
+			return fake.withState(func() error {
+				for _, b := range fake.buckets {
+					if b.cors != nil && len(b.cors.Rules) == 2 && b.cors.Rules[0].AllowedOrigins[0] == origin {
+						return nil
+					}
+				}
+				return fmt.Errorf("no bucket allows origin %s", origin)
+			})
+		}
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketOwnerConfig(fake.providerConfig(), "website", bucketCorsConfig("https://app.example.com")),
+					Check: resource.ComposeTestCheckFunc(
+						checkOrigin("https://app.example.com"),
+						resource.TestCheckResourceAttr("garage_bucket_cors.test", "id", "website"),
+						resource.TestCheckResourceAttr("garage_bucket_cors.test", "cors_rule.0.max_age_seconds", "3600"),
+					),
+				},
+				{
+					Config: bucketOwnerConfig(fake.providerConfig(), "website", bucketCorsConfig("https://www.example.com")),
+					Check:  checkOrigin("https://www.example.com"),
+				},
+				// Change out of band
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, b := range fake.buckets {
+							if b.cors != nil {
+								b.cors.Rules[1].AllowedMethods = []string{"GET", "DELETE"}
+							}
+						}
+					},
+					Config:             bucketOwnerConfig(fake.providerConfig(), "website", bucketCorsConfig("https://www.example.com")),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				// Deletion out of band
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, b := range fake.buckets {
+							b.cors = nil
+						}
+					},
+					Config:             bucketOwnerConfig(fake.providerConfig(), "website", bucketCorsConfig("https://www.example.com")),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: bucketOwnerConfig(fake.providerConfig(), "website", bucketCorsConfig("https://www.example.com")),
+					Check:  checkOrigin("https://www.example.com"),
+				},
+				{
+					ResourceName:      "garage_bucket_cors.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceBucketCors(t *testing.T) {
+	alias := "tf-acc-" + randomHex(10)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		garage.skipWithoutS3(t)
+
+		var accessKeyID string
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketOwnerConfig(garage.providerConfig, alias, bucketCorsConfig("https://app.example.com")),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						resource.TestCheckResourceAttr("garage_bucket_cors.test", "cors_rule.#", "2"),
+						resource.TestCheckResourceAttr("garage_bucket_cors.test", "cors_rule.0.allowed_origins.0", "https://app.example.com"),
+					),
+				},
+				// Deletion out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, _ garageClient) error {
+						client, bucketName, err := garage.provider.s3Bucket(ctx, alias, accessKeyID)
+						if err != nil {
+							return err
+						}
+						return client.DeleteBucketCors(ctx, bucketName)
+					}),
+					Config:             bucketOwnerConfig(garage.providerConfig, alias, bucketCorsConfig("https://app.example.com")),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: bucketOwnerConfig(garage.providerConfig, alias, bucketCorsConfig("https://www.example.com")),
+					Check:  resource.TestCheckResourceAttr("garage_bucket_cors.test", "cors_rule.0.allowed_origins.0", "https://www.example.com"),
+				},
+				{
+					ResourceName:            "garage_bucket_cors.test",
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"access_key_id"},
+				},
+			},
+		}
+	})
+}
+
+func TestResourceBucketCors(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "website")
+	ctx := context.Background()
+	endpoint, _ := url.Parse(fake.s3.URL)
+	p := &garageProvider{client: fake.client(), s3: &s3Config{endpoint: endpoint, region: defaultS3Region, pathStyle: true, httpClient: fake.s3.Client()}}
+
+	d := schema.TestResourceDataRaw(t, schemaBucketCors(), map[string]interface{}{
+		"bucket":        "website",
+		"access_key_id": client.credentials.AccessKeyID,
+		"cors_rule": []interface{}{
+			map[string]interface{}{
+				"allowed_origins": []interface{}{"https://app.example.com"},
+				"allowed_methods": []interface{}{"GET"},
+				"max_age_seconds": 600,
+			},
+		},
+	})
+	if diags := resourceBucketCorsPut(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if d.Id() != "website" || d.Get("cors_rule.0.allowed_origins.0") != "https://app.example.com" || d.Get("cors_rule.0.max_age_seconds") != 600 {
+		t.Errorf("unexpected state %v", d.State())
+	}
+
+	if diags := resourceBucketCorsDelete(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceBucketCorsRead(ctx, d, p); diags.HasError() || d.Id() != "" {
+		t.Errorf("expected the CORS configuration to be gone, got %v, %v", d.Id(), diags)
+	}
+}
@@ -0,0 +1,379 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func schemaBucketDirectorySync() map[string]*schema.Schema {
+	return withS3Bucket(map[string]*schema.Schema{
+		"source_dir": {
+			Description: "Path to the local directory mirrored into the bucket.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"prefix": {
+			Description: "Prefix prepended to the path of files, relative to `source_dir`, to build their keys. It usually ends with `/`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"delete_orphans": {
+			Description: "Delete the objects under `prefix` that don't match any file of `source_dir`, listed in `orphans`, even when they were not uploaded by this resource.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+		},
+		"content_types": {
+			Description: "Content types by file extension, such as `.md`, overriding the ones guessed from the extension.",
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"cache_control": {
+			Description: "Cache-Control header of the uploaded objects.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		// Computed
+		"files": {
+			Description: "Manifest of the mirrored files, mapping their path relative to `source_dir` to their hex-encoded SHA-256. The plan shows the files to upload or delete as changes to it.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"etags": {
+			Description: "ETags of the objects uploaded from the files of the manifest. Changes made to the objects outside of Terraform are detected through them.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"orphans": {
+			Description: "Paths, relative to `prefix`, of the objects that don't match any file of `source_dir`. The plan shows their deletion when `delete_orphans` is set. They are never deleted along with the resource.",
+			Type:        schema.TypeSet,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+	})
+}
+
+func resourceBucketDirectorySync() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to mirror a local directory into a Garage bucket, for example to deploy the content of a website, through the S3 API. Only the files whose content or metadata changed are uploaded.",
+		CreateContext: resourceBucketDirectorySyncApply,
+		ReadContext:   resourceBucketDirectorySyncRead,
+		UpdateContext: resourceBucketDirectorySyncApply,
+		DeleteContext: resourceBucketDirectorySyncDelete,
+		Schema:        schemaBucketDirectorySync(),
+		CustomizeDiff: customizeDiffBucketDirectorySyncFiles,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+	}
+}
+
+// localManifest maps the path, relative to dir and slash-separated, of every
+// regular file under dir to its hex-encoded SHA-256.
+func localManifest(dir string) (map[string]string, error) {
+	manifest := map[string]string{}
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		hash, _, err := fileDigest(filePath)
+		if err != nil {
+			return err
+		}
+		manifest[filepath.ToSlash(relativePath)] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source_dir: %w", err)
+	}
+	return manifest, nil
+}
+
+// remoteManifest maps the path, relative to prefix, of the objects under it
+// to their ETag.
+func remoteManifest(ctx context.Context, client *s3Client, bucketName string, prefix string) (map[string]string, error) {
+	objects, _, err := client.ListObjects(ctx, bucketName, prefix, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := map[string]string{}
+	for _, object := range objects {
+		manifest[strings.TrimPrefix(object.Key, prefix)] = object.ETag
+	}
+	return manifest, nil
+}
+
+// guessContentType returns the content type of the file at relativePath from
+// its extension, looked up in overrides first.
+func guessContentType(relativePath string, overrides map[string]interface{}) string {
+	extension := strings.ToLower(path.Ext(relativePath))
+	if contentType, ok := overrides[extension]; ok {
+		return contentType.(string)
+	}
+	if contentType := mime.TypeByExtension(extension); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+func stringMap(values map[string]interface{}) map[string]string {
+	result := map[string]string{}
+	for key, value := range values {
+		result[key] = value.(string)
+	}
+	return result
+}
+
+// customizeDiffBucketDirectorySyncFiles plans the manifest of source_dir, so
+// that files added, changed or removed locally, as well as objects changed or
+// deleted outside of Terraform, show in the plan. The ETags of the objects are
+// planned to change along with the manifest or the metadata of the files.
+func customizeDiffBucketDirectorySyncFiles(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("source_dir") {
+		for _, attribute := range []string{"files", "etags", "orphans"} {
+			if err := d.SetNewComputed(attribute); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	manifest, err := localManifest(d.Get("source_dir").(string))
+	if err != nil {
+		return err
+	}
+	if err := planOrphans(d, manifest); err != nil {
+		return err
+	}
+	if d.Id() != "" && reflect.DeepEqual(manifest, stringMap(d.Get("files").(map[string]interface{}))) && !d.HasChanges("cache_control", "content_types") {
+		return nil
+	}
+	if err := d.SetNew("files", manifest); err != nil {
+		return err
+	}
+	return d.SetNewComputed("etags")
+}
+
+// planOrphans plans the deletion of orphans when delete_orphans is set, and
+// drops the ones replaced by files of manifest. Orphans aren't known before
+// the objects under prefix are listed on creation.
+func planOrphans(d *schema.ResourceDiff, manifest map[string]string) error {
+	deleteOrphans := d.Get("delete_orphans").(bool)
+	if d.Id() == "" {
+		if deleteOrphans {
+			return d.SetNew("orphans", []string{})
+		}
+		return nil
+	}
+
+	recorded := d.Get("orphans").(*schema.Set).List()
+	orphans := []string{}
+	for _, orphan := range recorded {
+		if _, ok := manifest[orphan.(string)]; !ok && !deleteOrphans {
+			orphans = append(orphans, orphan.(string))
+		}
+	}
+	if len(orphans) == len(recorded) {
+		return nil
+	}
+	return d.SetNew("orphans", orphans)
+}
+
+// resourceBucketDirectorySyncApply uploads the files of source_dir that
+// changed since the last apply, whose object changed outside of Terraform or
+// whose metadata changed, and deletes the objects of the files removed since
+// the last apply, or of any file missing locally when delete_orphans is set.
+func resourceBucketDirectorySyncApply(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	bucket := d.Get("bucket").(string)
+	prefix := d.Get("prefix").(string)
+	sourceDir := d.Get("source_dir").(string)
+
+	client, bucketName, err := p.s3Bucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	local, err := localManifest(sourceDir)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	remote, err := remoteManifest(ctx, client, bucketName, prefix)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	previous, _ := d.GetChange("files")
+	uploaded := previous.(map[string]interface{})
+	previousETags, _ := d.GetChange("etags")
+	uploadedETags := previousETags.(map[string]interface{})
+	previousContentTypes, contentTypes := d.GetChange("content_types")
+	etags := map[string]string{}
+	for relativePath, hash := range local {
+		contentType := guessContentType(relativePath, contentTypes.(map[string]interface{}))
+		inSync := uploaded[relativePath] == hash && remote[relativePath] != "" && remote[relativePath] == uploadedETags[relativePath]
+		metadataChanged := d.HasChange("cache_control") || contentType != guessContentType(relativePath, previousContentTypes.(map[string]interface{}))
+		if inSync && !metadataChanged {
+			etags[relativePath] = remote[relativePath]
+			continue
+		}
+		metadata := s3ObjectMetadata{
+			ContentType:  contentType,
+			CacheControl: d.Get("cache_control").(string),
+		}
+		etag, err := client.PutObjectFile(ctx, bucketName, prefix+relativePath, metadata, filepath.Join(sourceDir, filepath.FromSlash(relativePath)))
+		if err != nil {
+			return diag.Errorf("failed to upload %s: %s", relativePath, err)
+		}
+		etags[relativePath] = etag
+	}
+
+	deleteOrphans := d.Get("delete_orphans").(bool)
+	orphans := []string{}
+	for relativePath := range remote {
+		if _, ok := local[relativePath]; ok {
+			continue
+		}
+		if _, ok := uploaded[relativePath]; !ok && !deleteOrphans {
+			orphans = append(orphans, relativePath)
+			continue
+		}
+		err := client.DeleteObject(ctx, bucketName, prefix+relativePath)
+		if err != nil {
+			return diag.Errorf("failed to delete %s: %s", relativePath, err)
+		}
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", bucket, prefix))
+	err = d.Set("files", local)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = d.Set("etags", etags)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = d.Set("orphans", orphans)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+// resourceBucketDirectorySyncRead drops the files of the manifest whose object
+// was deleted or changed outside of Terraform, so that their upload is
+// planned, and lists the objects matching no file of the manifest as orphans.
+func resourceBucketDirectorySyncRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	remote, err := remoteManifest(ctx, client, bucketName, d.Get("prefix").(string))
+	if isS3NotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	files := map[string]string{}
+	etags := map[string]string{}
+	recordedFiles := d.Get("files").(map[string]interface{})
+	recordedETags := d.Get("etags").(map[string]interface{})
+	for relativePath, hash := range recordedFiles {
+		if etag, ok := remote[relativePath]; ok && etag == recordedETags[relativePath] {
+			files[relativePath] = hash.(string)
+			etags[relativePath] = etag
+		}
+	}
+	orphans := []string{}
+	for relativePath := range remote {
+		if _, ok := recordedFiles[relativePath]; !ok {
+			orphans = append(orphans, relativePath)
+		}
+	}
+
+	err = d.Set("files", files)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = d.Set("etags", etags)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = d.Set("orphans", orphans)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+// resourceBucketDirectorySyncDelete deletes the objects uploaded from the
+// files of the manifest, leaving orphans alone.
+func resourceBucketDirectorySyncDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	prefix := d.Get("prefix").(string)
+	for relativePath := range d.Get("files").(map[string]interface{}) {
+		err := client.DeleteObject(ctx, bucketName, prefix+relativePath)
+		if err != nil && !isS3NotFound(err) {
+			return diag.Errorf("failed to delete %s: %s", relativePath, err)
+		}
+	}
+
+	return diags
+}
@@ -0,0 +1,460 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// writeFiles writes files, mapping slash-separated paths relative to dir to
+// their content, and removes the ones mapped to an empty content.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for relativePath, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(relativePath))
+		if content == "" {
+			if err := os.Remove(filePath); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func directorySyncConfig(providerConfig string, alias string, sourceDir string, deleteOrphans bool) string {
+	return bucketObjectConfig(providerConfig, alias, fmt.Sprintf(`
+resource "garage_bucket_directory_sync" "test" {
+  bucket         = garage_bucket_global_alias.test.alias
+  access_key_id  = garage_bucket_key.test.access_key_id
+  source_dir     = %q
+  prefix         = "site/"
+  delete_orphans = %t
+  content_types = {
+    ".md" = "text/markdown"
+  }
+}
+`, sourceDir, deleteOrphans))
+}
+
+func TestUnitResourceBucketDirectorySync(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		checkKeys := func(expected ...string) resource.TestCheckFunc {
+			return fake.withState(func() error {
+				for _, b := range fake.buckets {
+					if len(b.globalAliases) == 0 {
+						continue
+					}
+					keys := []string{}
+					for key := range b.objects {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					if strings.Join(keys, ",") != strings.Join(expected, ",") {
+						return fmt.Errorf("expected objects %v, got %v", expected, keys)
+					}
+				}
+				return nil
+			})
+		}
+		storeOrphan := func() {
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			for _, b := range fake.buckets {
+				if len(b.globalAliases) > 0 {
+					fake.storeObject(b, "site/orphan.txt", s3ObjectMetadata{}, []byte("orphan"), contentETag([]byte("orphan")))
+				}
+			}
+		}
+
+		return resource.TestCase{
+			PreCheck: func() {
+				writeFiles(t, sourceDir, map[string]string{"index.html": "<h1>Hello</h1>", "docs/readme.md": "# Readme"})
+			},
+			Steps: []resource.TestStep{
+				{
+					Config: directorySyncConfig(fake.providerConfig(), "website", sourceDir, false),
+					Check: resource.ComposeTestCheckFunc(
+						checkKeys("site/docs/readme.md", "site/index.html"),
+						resource.TestCheckResourceAttr("garage_bucket_directory_sync.test", "id", "website/site/"),
+						resource.TestCheckResourceAttr("garage_bucket_directory_sync.test", "files.index.html", sha256Hex([]byte("<h1>Hello</h1>"))),
+						resource.TestCheckResourceAttr("garage_bucket_directory_sync.test", "etags.index.html", contentETag([]byte("<h1>Hello</h1>"))),
+						fake.withState(func() error {
+							for _, b := range fake.buckets {
+								if o, ok := b.objects["site/docs/readme.md"]; ok && o.ContentType != "text/markdown" {
+									return fmt.Errorf("unexpected content type %s", o.ContentType)
+								}
+							}
+							return nil
+						}),
+					),
+				},
+				// Files changed, added and removed
+				{
+					PreConfig: func() {
+						writeFiles(t, sourceDir, map[string]string{"index.html": "<h1>Hi</h1>", "style.css": "h1 {}", "docs/readme.md": ""})
+					},
+					Config:             directorySyncConfig(fake.providerConfig(), "website", sourceDir, false),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: directorySyncConfig(fake.providerConfig(), "website", sourceDir, false),
+					Check: resource.ComposeTestCheckFunc(
+						checkKeys("site/index.html", "site/style.css"),
+						resource.TestCheckResourceAttr("garage_bucket_directory_sync.test", "files.index.html", sha256Hex([]byte("<h1>Hi</h1>"))),
+					),
+				},
+				// Orphans are kept by default
+				{
+					PreConfig: storeOrphan,
+					Config:    directorySyncConfig(fake.providerConfig(), "website", sourceDir, false),
+					PlanOnly:  true,
+				},
+				{
+					Config: directorySyncConfig(fake.providerConfig(), "website", sourceDir, false),
+					Check: resource.ComposeTestCheckFunc(
+						checkKeys("site/index.html", "site/orphan.txt", "site/style.css"),
+						resource.TestCheckNoResourceAttr("garage_bucket_directory_sync.test", "files.orphan.txt"),
+						resource.TestCheckTypeSetElemAttr("garage_bucket_directory_sync.test", "orphans.*", "orphan.txt"),
+					),
+				},
+				{
+					Config: directorySyncConfig(fake.providerConfig(), "website", sourceDir, true),
+					Check: resource.ComposeTestCheckFunc(
+						checkKeys("site/index.html", "site/style.css"),
+						resource.TestCheckResourceAttr("garage_bucket_directory_sync.test", "orphans.#", "0"),
+					),
+				},
+				{
+					PreConfig:          storeOrphan,
+					Config:             directorySyncConfig(fake.providerConfig(), "website", sourceDir, true),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: directorySyncConfig(fake.providerConfig(), "website", sourceDir, true),
+					Check:  checkKeys("site/index.html", "site/style.css"),
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceBucketDirectorySync(t *testing.T) {
+	alias := "tf-acc-" + randomHex(10)
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, map[string]string{"index.html": "<h1>Hello</h1>", "css/site.css": "h1 {}"})
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		garage.skipWithoutS3(t)
+
+		config := directorySyncConfig(garage.providerConfig, alias, sourceDir, false)
+		var accessKeyID string
+		checkObjects := func(ctx context.Context) error {
+			client, bucketName, err := garage.provider.s3Bucket(ctx, alias, accessKeyID)
+			if err != nil {
+				return err
+			}
+			remote, err := remoteManifest(ctx, client, bucketName, "site/")
+			if err != nil {
+				return err
+			}
+			expected := map[string]string{"index.html": contentETag([]byte("<h1>Hello</h1>")), "css/site.css": contentETag([]byte("h1 {}"))}
+			if !reflect.DeepEqual(expected, remote) {
+				return fmt.Errorf("expected objects %v, got %v", expected, remote)
+			}
+			return nil
+		}
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						func(*terraform.State) error { return checkObjects(context.Background()) },
+					),
+				},
+				// Change out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, _ garageClient) error {
+						client, bucketName, err := garage.provider.s3Bucket(ctx, alias, accessKeyID)
+						if err != nil {
+							return err
+						}
+						_, err = client.PutObject(ctx, bucketName, "site/index.html", s3ObjectMetadata{}, []byte("tampered"))
+						return err
+					}),
+					Config:             config,
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: config,
+					Check:  func(*terraform.State) error { return checkObjects(context.Background()) },
+				},
+			},
+		}
+	})
+}
+
+func TestResourceBucketDirectorySync(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "website")
+	ctx := context.Background()
+	endpoint, _ := url.Parse(fake.s3.URL)
+	p := &garageProvider{client: fake.client(), s3: &s3Config{endpoint: endpoint, region: defaultS3Region, pathStyle: true, httpClient: fake.s3.Client()}}
+
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, map[string]string{"index.html": "<h1>Hello</h1>", "img/logo.png": "png", "data.unknown": "?", "video.mp4": strings.Repeat("v", s3PartSize+1)})
+	if _, err := client.PutObject(ctx, "website", "site/orphan.txt", s3ObjectMetadata{}, []byte("orphan")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := resourceBucketDirectorySync()
+	config := map[string]interface{}{
+		"bucket":        "website",
+		"access_key_id": client.credentials.AccessKeyID,
+		"source_dir":    sourceDir,
+		"prefix":        "site/",
+	}
+	// apply refreshes state, then plans and applies config like terraform
+	// apply does.
+	apply := func(state *terraform.InstanceState) *terraform.InstanceState {
+		t.Helper()
+		if state != nil {
+			var diags diag.Diagnostics
+			state, diags = r.RefreshWithoutUpgrade(ctx, state, p)
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+		}
+		instanceDiff, err := r.Diff(ctx, state, terraform.NewResourceConfigRaw(config), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if instanceDiff.Empty() {
+			return state
+		}
+		state, diags := r.Apply(ctx, state, instanceDiff, p)
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return state
+	}
+	// uploadedObjects returns the objects of the bucket by key, which are
+	// replaced when uploaded again.
+	uploadedObjects := func() map[string]*fakeObject {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		objects := map[string]*fakeObject{}
+		for _, b := range fake.buckets {
+			for key, object := range b.objects {
+				objects[key] = object
+			}
+		}
+		return objects
+	}
+	checkUploaded := func(previous map[string]*fakeObject, expected ...string) {
+		t.Helper()
+		for key, object := range uploadedObjects() {
+			reuploaded := false
+			for _, e := range expected {
+				reuploaded = reuploaded || e == key
+			}
+			if unchanged := object == previous[key]; unchanged == reuploaded {
+				t.Errorf("%s: expected to be uploaded again: %t", key, !unchanged)
+			}
+		}
+	}
+	state := apply(nil)
+
+	for key, contentType := range map[string]string{
+		"site/index.html":   "text/html; charset=utf-8",
+		"site/img/logo.png": "image/png",
+		"site/data.unknown": "application/octet-stream",
+		"site/video.mp4":    "video/mp4",
+		"site/orphan.txt":   "blob",
+	} {
+		object, err := client.HeadObject(ctx, "website", key)
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if object.ContentType != contentType {
+			t.Errorf("%s: expected content type %s, got %s", key, contentType, object.ContentType)
+		}
+	}
+
+	// Only the files changed are uploaded, including the ones uploaded in
+	// parts
+	uploaded := uploadedObjects()
+	state = apply(state)
+	checkUploaded(uploaded)
+	writeFiles(t, sourceDir, map[string]string{"index.html": "<h1>Hi</h1>"})
+	state = apply(state)
+	checkUploaded(uploaded, "site/index.html")
+
+	// Objects changed outside of Terraform are uploaded again
+	if _, err := client.PutObject(ctx, "website", "site/img/logo.png", s3ObjectMetadata{}, []byte("tampered")); err != nil {
+		t.Fatal(err)
+	}
+	uploaded = uploadedObjects()
+	state = apply(state)
+	checkUploaded(uploaded, "site/img/logo.png")
+
+	// Changes of the metadata reach the files already uploaded
+	uploaded = uploadedObjects()
+	config["content_types"] = map[string]interface{}{".unknown": "text/plain"}
+	state = apply(state)
+	checkUploaded(uploaded, "site/data.unknown")
+	uploaded = uploadedObjects()
+	config["cache_control"] = "max-age=60"
+	state = apply(state)
+	checkUploaded(uploaded, "site/index.html", "site/img/logo.png", "site/data.unknown", "site/video.mp4")
+	object, err := client.HeadObject(ctx, "website", "site/video.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.CacheControl != "max-age=60" {
+		t.Errorf("expected the cache control to be updated, got %+v", object)
+	}
+
+	// Orphans are listed apart from the manifest, and deleted only along
+	// with delete_orphans
+	state, diags := r.RefreshWithoutUpgrade(ctx, state, p)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if _, ok := state.Attributes["files.orphan.txt"]; ok {
+		t.Errorf("unexpected orphan in %v", state.Attributes)
+	}
+	if state.Attributes["orphans.#"] != "1" {
+		t.Errorf("expected the orphan to be listed, got %v", state.Attributes)
+	}
+	config["delete_orphans"] = true
+	state = apply(state)
+	if _, err := client.HeadObject(ctx, "website", "site/orphan.txt"); !isS3NotFound(err) {
+		t.Errorf("expected the orphan to be deleted, got %v", err)
+	}
+	if state.Attributes["orphans.#"] != "0" {
+		t.Errorf("expected no orphan left, got %v", state.Attributes)
+	}
+
+	// Only the objects uploaded are deleted along with the resource
+	config["delete_orphans"] = false
+	state = apply(state)
+	if _, err := client.PutObject(ctx, "website", "site/foreign.txt", s3ObjectMetadata{}, []byte("foreign")); err != nil {
+		t.Fatal(err)
+	}
+	if state, diags = r.RefreshWithoutUpgrade(ctx, state, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if _, diags := r.Apply(ctx, state, &terraform.InstanceDiff{Destroy: true}, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	objects, _, err := client.ListObjects(ctx, "website", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Key != "site/foreign.txt" {
+		t.Errorf("expected only the foreign object to be left, got %v", objects)
+	}
+}
+
+func TestCustomizeDiffBucketDirectorySyncFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, map[string]string{"index.html": "<h1>Hello</h1>", "css/site.css": "h1 {}"})
+	synced := map[string]string{
+		"files.%":            "2",
+		"files.index.html":   sha256Hex([]byte("<h1>Hello</h1>")),
+		"files.css/site.css": sha256Hex([]byte("h1 {}")),
+	}
+
+	orphanKey := func(relativePath string) string {
+		return fmt.Sprintf("orphans.%d", schema.HashSchema(resourceBucketDirectorySync().Schema["orphans"].Elem.(*schema.Schema))(relativePath))
+	}
+	orphan := map[string]string{"orphans.#": "1", orphanKey("orphan.txt"): "orphan.txt"}
+	replaced := map[string]string{"files.%": "1", "files.index.html": synced["files.index.html"], "orphans.#": "1", orphanKey("css/site.css"): "css/site.css"}
+	for name, value := range synced {
+		orphan[name] = value
+	}
+
+	for _, c := range []struct {
+		name          string
+		files         map[string]string
+		sourceDir     string
+		cacheControl  string
+		deleteOrphans bool
+		changed       []string
+		planError     string
+	}{
+		{"in sync", synced, sourceDir, "", false, nil, ""},
+		{"changed", map[string]string{"files.%": "2", "files.index.html": sha256Hex([]byte("<h1>Hi</h1>")), "files.css/site.css": synced["files.css/site.css"]}, sourceDir, "", false, []string{"etags.%", "etags.index.html", "files.index.html"}, ""},
+		{"deleted", map[string]string{"files.%": "1", "files.index.html": synced["files.index.html"]}, sourceDir, "", false, []string{"etags.%", "etags.index.html", "files.%", "files.css/site.css"}, ""},
+		{"orphan kept", orphan, sourceDir, "", false, nil, ""},
+		{"orphan deleted", orphan, sourceDir, "", true, []string{"delete_orphans", "orphans.#", orphanKey("orphan.txt")}, ""},
+		{"orphan replaced", replaced, sourceDir, "", false, []string{"etags.%", "etags.index.html", "files.%", "files.css/site.css", "orphans.#", orphanKey("css/site.css")}, ""},
+		{"metadata changed", synced, sourceDir, "max-age=60", false, []string{"cache_control", "etags.%", "etags.index.html"}, ""},
+		{"missing source_dir", synced, sourceDir + "/missing", "", false, nil, "failed to read source_dir"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			attributes := map[string]string{
+				"id":               "website/",
+				"bucket":           "website",
+				"source_dir":       sourceDir,
+				"delete_orphans":   "false",
+				"etags.%":          "1",
+				"etags.index.html": contentETag([]byte("<h1>Hello</h1>")),
+				"orphans.#":        "0",
+			}
+			for name, value := range c.files {
+				attributes[name] = value
+			}
+			config := map[string]interface{}{"bucket": "website", "source_dir": c.sourceDir, "delete_orphans": c.deleteOrphans}
+			if c.cacheControl != "" {
+				config["cache_control"] = c.cacheControl
+			}
+
+			diff, err := resourceBucketDirectorySync().Diff(context.Background(), &terraform.InstanceState{ID: "website/", Attributes: attributes}, terraform.NewResourceConfigRaw(config), &garageProvider{})
+			if c.planError != "" {
+				if err == nil || !strings.Contains(err.Error(), c.planError) {
+					t.Fatalf("expected an error containing %q, got %v", c.planError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			changed := []string{}
+			if diff != nil {
+				for name := range diff.Attributes {
+					changed = append(changed, name)
+				}
+			}
+			sort.Strings(changed)
+			if strings.Join(changed, ",") != strings.Join(c.changed, ",") {
+				t.Errorf("expected changes to %v, got %v", c.changed, changed)
+			}
+		})
+	}
+}
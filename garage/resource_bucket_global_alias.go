@@ -5,7 +5,9 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/thoas/go-funk"
 )
 
 func schemaBucketGlobalAlias() map[string]*schema.Schema {
@@ -16,9 +18,10 @@ func schemaBucketGlobalAlias() map[string]*schema.Schema {
 			ForceNew: true,
 		},
 		"alias": {
-			Type:     schema.TypeString,
-			Required: true,
-			ForceNew: true,
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validateBucketAlias,
 		},
 	}
 }
@@ -30,7 +33,52 @@ func resourceBucketGlobalAlias() *schema.Resource {
 		ReadContext:   resourceBucketGlobalAliasRead,
 		DeleteContext: resourceBucketGlobalAliasDelete,
 		Schema:        schemaBucketGlobalAlias(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffRequireAdminAPI,
+			customizeDiffDNSCompatibleAlias,
+			customizeDiffGlobalAliasAvailable,
+		),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importStateFromID("bucket_id", "alias"),
+		},
+	}
+}
+
+// customizeDiffGlobalAliasAvailable fails the plan when the alias already
+// points to another bucket, rather than the apply once the bucket was created.
+func customizeDiffGlobalAliasAvailable(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	p, _ := m.(*garageProvider)
+	if p == nil || p.client == nil || !d.NewValueKnown("alias") {
+		return nil
+	}
+	if d.Id() != "" && !d.HasChange("alias") && !d.HasChange("bucket_id") {
+		return nil
+	}
+
+	alias := d.Get("alias").(string)
+	bucketInfo, err := p.client.FindBucket(ctx, alias)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if d.NewValueKnown("bucket_id") && d.Get("bucket_id").(string) == bucketInfo.ID {
+		return nil
+	}
+	// A replaced alias is removed from its bucket first.
+	oldBucketID, _ := d.GetChange("bucket_id")
+	if d.Id() != "" && oldBucketID.(string) == bucketInfo.ID {
+		return nil
 	}
+
+	return fmt.Errorf("global alias %q already points to bucket %s", alias, bucketInfo.ID)
 }
 
 func resourceBucketGlobalAliasCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -40,7 +88,7 @@ func resourceBucketGlobalAliasCreate(ctx context.Context, d *schema.ResourceData
 	bucketID := d.Get("bucket_id").(string)
 	alias := d.Get("alias").(string)
 
-	_, _, err := p.client.BucketApi.PutBucketGlobalAlias(updateContext(ctx, p)).Id(bucketID).Alias(alias).Execute()
+	err := p.client.AddBucketGlobalAlias(ctx, bucketID, alias)
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -51,8 +99,25 @@ func resourceBucketGlobalAliasCreate(ctx context.Context, d *schema.ResourceData
 }
 
 func resourceBucketGlobalAliasRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
 	var diags diag.Diagnostics
-	// Noop
+
+	bucketID := d.Get("bucket_id").(string)
+	alias := d.Get("alias").(string)
+
+	bucketInfo, err := p.client.GetBucket(ctx, bucketID)
+	if isNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !funk.ContainsString(bucketInfo.GlobalAliases, alias) {
+		d.SetId("")
+	}
+
 	return diags
 }
 
@@ -63,7 +128,7 @@ func resourceBucketGlobalAliasDelete(ctx context.Context, d *schema.ResourceData
 	bucketID := d.Get("bucket_id").(string)
 	alias := d.Get("alias").(string)
 
-	_, _, err := p.client.BucketApi.DeleteBucketGlobalAlias(updateContext(ctx, p)).Id(bucketID).Alias(alias).Execute()
+	err := p.client.RemoveBucketGlobalAlias(ctx, bucketID, alias)
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -0,0 +1,152 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestUnitResourceBucketGlobalAlias(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		config := fake.providerConfig() + `
+resource "garage_bucket" "test" {}
+
+resource "garage_bucket_global_alias" "test" {
+  bucket_id = garage_bucket.test.id
+  alias     = "website"
+}
+`
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_bucket_global_alias.test", "alias", "website"),
+						fake.withState(func() error {
+							if _, ok := fake.bucketByAlias("website"); !ok {
+								return fmt.Errorf("alias website wasn't added")
+							}
+							return nil
+						}),
+					),
+				},
+				{
+					Config: config + `
+resource "garage_bucket" "other" {}
+
+resource "garage_bucket_global_alias" "other" {
+  bucket_id = garage_bucket.other.id
+  alias     = "website"
+}
+`,
+					ExpectError: regexp.MustCompile("already points to bucket"),
+				},
+				{
+					Config: fake.providerConfig() + `
+resource "garage_bucket" "test" {}
+
+resource "garage_bucket_global_alias" "test" {
+  bucket_id = garage_bucket.test.id
+  alias     = "Not_A_Bucket"
+}
+`,
+					ExpectError: regexp.MustCompile("Invalid bucket alias"),
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceBucketGlobalAlias(t *testing.T) {
+	var bucketID string
+	alias := "tf-acc-" + randomHex(10)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		config := garage.providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {}
+
+resource "garage_bucket_global_alias" "test" {
+  bucket_id = garage_bucket.test.id
+  alias     = %q
+}
+`, alias)
+		checkAlias := garage.checkBucket(&bucketID, func(bucketInfo *bucket) error {
+			if len(bucketInfo.GlobalAliases) != 1 || bucketInfo.GlobalAliases[0] != alias {
+				return fmt.Errorf("unexpected global aliases %v", bucketInfo.GlobalAliases)
+			}
+			return nil
+		})
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_bucket.test", "id", &bucketID),
+						resource.TestCheckResourceAttr("garage_bucket_global_alias.test", "alias", alias),
+						checkAlias,
+					),
+				},
+				// Removal out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, client garageClient) error {
+						return client.RemoveBucketGlobalAlias(ctx, bucketID, alias)
+					}),
+					Config:             config,
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: config,
+					Check:  checkAlias,
+				},
+				{
+					ResourceName:      "garage_bucket_global_alias.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+// planGlobalAlias plans a new global alias with the given attributes, against
+// the client of fake.
+func planGlobalAlias(fake *fakeGarage, config map[string]interface{}) error {
+	p := &garageProvider{client: fake.client(), apiVersion: fake.apiVersion}
+	_, err := resourceBucketGlobalAlias().Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), p)
+	return err
+}
+
+func TestCustomizeDiffGlobalAliasAvailable(t *testing.T) {
+	forEachAPIVersion(t, func(t *testing.T, fake *fakeGarage, client garageClient) {
+		ctx := context.Background()
+
+		bucketInfo, err := client.CreateBucket(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.AddBucketGlobalAlias(ctx, bucketInfo.ID, "taken"); err != nil {
+			t.Fatal(err)
+		}
+
+		err = planGlobalAlias(fake, map[string]interface{}{"bucket_id": "other", "alias": "taken"})
+		if err == nil || !regexp.MustCompile("already points to bucket "+bucketInfo.ID).MatchString(err.Error()) {
+			t.Errorf("expected the taken alias to fail the plan, got %v", err)
+		}
+		err = planGlobalAlias(fake, map[string]interface{}{"bucket_id": unknownValue, "alias": "taken"})
+		if err == nil {
+			t.Errorf("expected the taken alias to fail the plan of a new bucket")
+		}
+		if err := planGlobalAlias(fake, map[string]interface{}{"bucket_id": bucketInfo.ID, "alias": "taken"}); err != nil {
+			t.Errorf("expected the alias of the same bucket to be planned, got %v", err)
+		}
+		if err := planGlobalAlias(fake, map[string]interface{}{"bucket_id": "other", "alias": "free"}); err != nil {
+			t.Errorf("expected the free alias to be planned, got %v", err)
+		}
+	})
+}
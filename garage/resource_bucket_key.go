@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
@@ -47,6 +46,16 @@ func resourceBucketKey() *schema.Resource {
 		UpdateContext: resourceBucketKeyCreateOrUpdate,
 		DeleteContext: resourceBucketKeyDelete,
 		Schema:        schemaBucketKey(),
+		CustomizeDiff: customizeDiffRequireAdminAPI,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importStateFromID("bucket_id", "access_key_id"),
+		},
 	}
 }
 
@@ -60,30 +69,22 @@ func resourceBucketKeyCreateOrUpdate(ctx context.Context, d *schema.ResourceData
 	write := d.Get("write").(bool)
 	owner := d.Get("owner").(bool)
 
-	allowBucketKeyRequest := garage.AllowBucketKeyRequest{
-		BucketId:    bucketID,
-		AccessKeyId: accessKeyID,
-		Permissions: garage.AllowBucketKeyRequestPermissions{
-			Read:  read,
-			Write: write,
-			Owner: owner,
-		},
+	allow := bucketKeyPermissions{
+		Read:  read,
+		Write: write,
+		Owner: owner,
 	}
-	denyBucketKeyRequest := garage.AllowBucketKeyRequest{
-		BucketId:    bucketID,
-		AccessKeyId: accessKeyID,
-		Permissions: garage.AllowBucketKeyRequestPermissions{
-			Read:  !read,
-			Write: !write,
-			Owner: !owner,
-		},
+	deny := bucketKeyPermissions{
+		Read:  !read,
+		Write: !write,
+		Owner: !owner,
 	}
 
-	_, _, err := p.client.BucketApi.AllowBucketKey(updateContext(ctx, p)).AllowBucketKeyRequest(allowBucketKeyRequest).Execute()
+	err := p.client.GrantKey(ctx, bucketID, accessKeyID, allow)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	_, _, err = p.client.BucketApi.DenyBucketKey(updateContext(ctx, p)).AllowBucketKeyRequest(denyBucketKeyRequest).Execute()
+	err = p.client.RevokeKey(ctx, bucketID, accessKeyID, deny)
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -94,11 +95,53 @@ func resourceBucketKeyCreateOrUpdate(ctx context.Context, d *schema.ResourceData
 }
 
 func resourceBucketKeyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
 	var diags diag.Diagnostics
-	// Noop
+
+	bucketID := d.Get("bucket_id").(string)
+	accessKeyID := d.Get("access_key_id").(string)
+
+	bucketInfo, err := p.client.GetBucket(ctx, bucketID)
+	if isNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Keys without any permission aren't listed on the bucket.
+	permissions := bucketKeyPermissions{}
+	if key := findBucketKey(bucketInfo, accessKeyID); key != nil {
+		permissions = key.Permissions
+	}
+
+	attributes := map[string]interface{}{
+		"read":  permissions.Read,
+		"write": permissions.Write,
+		"owner": permissions.Owner,
+	}
+	for attribute, value := range attributes {
+		err := d.Set(attribute, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	return diags
 }
 
+// findBucketKey returns the key of bucketInfo with the given access key ID,
+// if it is allowed on the bucket or has local aliases to it.
+func findBucketKey(bucketInfo *bucket, accessKeyID string) *bucketKey {
+	for i := range bucketInfo.Keys {
+		if bucketInfo.Keys[i].AccessKeyID == accessKeyID {
+			return &bucketInfo.Keys[i]
+		}
+	}
+	return nil
+}
+
 func resourceBucketKeyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	p := m.(*garageProvider)
 	var diags diag.Diagnostics
@@ -106,17 +149,13 @@ func resourceBucketKeyDelete(ctx context.Context, d *schema.ResourceData, m inte
 	bucketID := d.Get("bucket_id").(string)
 	accessKeyID := d.Get("access_key_id").(string)
 
-	denyBucketKeyRequest := garage.AllowBucketKeyRequest{
-		BucketId:    bucketID,
-		AccessKeyId: accessKeyID,
-		Permissions: garage.AllowBucketKeyRequestPermissions{
-			Read:  true,
-			Write: true,
-			Owner: true,
-		},
+	deny := bucketKeyPermissions{
+		Read:  true,
+		Write: true,
+		Owner: true,
 	}
 
-	_, _, err := p.client.BucketApi.DenyBucketKey(updateContext(ctx, p)).AllowBucketKeyRequest(denyBucketKeyRequest).Execute()
+	err := p.client.RevokeKey(ctx, bucketID, accessKeyID, deny)
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -0,0 +1,143 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func testBucketKeyConfig(fake *fakeGarage, read bool, write bool, owner bool) string {
+	return fake.providerConfig() + fmt.Sprintf(`
+resource "garage_bucket" "test" {}
+
+resource "garage_key" "test" {
+  name = "test"
+}
+
+resource "garage_bucket_key" "test" {
+  bucket_id     = garage_bucket.test.id
+  access_key_id = garage_key.test.access_key_id
+  read          = %t
+  write         = %t
+  owner         = %t
+}
+`, read, write, owner)
+}
+
+// checkFakeBucketKey checks the permissions of the single key allowed on the
+// single bucket of fake.
+func checkFakeBucketKey(fake *fakeGarage, permissions bucketKeyPermissions) resource.TestCheckFunc {
+	return fake.withState(func() error {
+		for _, b := range fake.buckets {
+			for accessKeyID, keyPermissions := range b.permissions {
+				if keyPermissions != permissions {
+					return fmt.Errorf("key %s has permissions %+v on bucket %s, expected %+v", accessKeyID, keyPermissions, b.id, permissions)
+				}
+			}
+			if len(b.permissions) == 0 && permissions != (bucketKeyPermissions{}) {
+				return fmt.Errorf("no key is allowed on bucket %s", b.id)
+			}
+		}
+		return nil
+	})
+}
+
+func TestUnitResourceBucketKey(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: testBucketKeyConfig(fake, true, true, false),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttrPair("garage_bucket_key.test", "bucket_id", "garage_bucket.test", "id"),
+						resource.TestCheckResourceAttrPair("garage_bucket_key.test", "access_key_id", "garage_key.test", "access_key_id"),
+						checkFakeBucketKey(fake, bucketKeyPermissions{Read: true, Write: true}),
+					),
+				},
+				{
+					Config: testBucketKeyConfig(fake, true, false, true),
+					Check:  checkFakeBucketKey(fake, bucketKeyPermissions{Read: true, Owner: true}),
+				},
+			},
+		}
+	})
+}
+
+func testAccBucketKeyConfig(garage *testGarage, read bool, write bool, owner bool) string {
+	return garage.providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {}
+
+resource "garage_key" "test" {}
+
+resource "garage_bucket_key" "test" {
+  bucket_id     = garage_bucket.test.id
+  access_key_id = garage_key.test.access_key_id
+  read          = %t
+  write         = %t
+  owner         = %t
+}
+`, read, write, owner)
+}
+
+// checkBucketKey checks the permissions of the key with the ID stored in
+// accessKeyID on the bucket with the ID stored in bucketID.
+func (g *testGarage) checkBucketKey(bucketID *string, accessKeyID *string, permissions bucketKeyPermissions) resource.TestCheckFunc {
+	return g.checkBucket(bucketID, func(bucketInfo *bucket) error {
+		keyPermissions := bucketKeyPermissions{}
+		if key := findBucketKey(bucketInfo, *accessKeyID); key != nil {
+			keyPermissions = key.Permissions
+		}
+		if keyPermissions != permissions {
+			return fmt.Errorf("key has permissions %+v on the bucket, expected %+v", keyPermissions, permissions)
+		}
+		return nil
+	})
+}
+
+func TestAccResourceBucketKey(t *testing.T) {
+	var bucketID string
+	var accessKeyID string
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: testAccBucketKeyConfig(garage, true, true, false),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_bucket.test", "id", &bucketID),
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						garage.checkBucketKey(&bucketID, &accessKeyID, bucketKeyPermissions{Read: true, Write: true}),
+					),
+				},
+				// Drift of the permissions
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, client garageClient) error {
+						return client.RevokeKey(ctx, bucketID, accessKeyID, bucketKeyPermissions{Write: true})
+					}),
+					Config:             testAccBucketKeyConfig(garage, true, true, false),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: testAccBucketKeyConfig(garage, true, true, false),
+					Check:  garage.checkBucketKey(&bucketID, &accessKeyID, bucketKeyPermissions{Read: true, Write: true}),
+				},
+				{
+					Config: testAccBucketKeyConfig(garage, true, false, true),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_bucket_key.test", "write", "false"),
+						resource.TestCheckResourceAttr("garage_bucket_key.test", "owner", "true"),
+						garage.checkBucketKey(&bucketID, &accessKeyID, bucketKeyPermissions{Read: true, Owner: true}),
+					),
+				},
+				{
+					ResourceName:      "garage_bucket_key.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
@@ -0,0 +1,311 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// lifecycleDateFormat is the format of expiration dates, which S3 takes as
+// timestamps at midnight UTC.
+const lifecycleDateFormat = "2006-01-02"
+
+func schemaBucketLifecycle() map[string]*schema.Schema {
+	return withS3Bucket(map[string]*schema.Schema{
+		"rule": {
+			Description: "Rule expiring objects or aborting unfinished multipart uploads. Garage applies it once a day.",
+			Type:        schema.TypeList,
+			Required:    true,
+			MinItems:    1,
+			MaxItems:    1000,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"id": {
+						Description:  "Identifier of the rule.",
+						Type:         schema.TypeString,
+						Required:     true,
+						ValidateFunc: validation.StringLenBetween(1, 255),
+					},
+					"enabled": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  true,
+					},
+					"prefix": {
+						Description: "Prefix of the keys of the objects the rule applies to.",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+					"object_size_greater_than": {
+						Description:  "Size, in bytes, the objects the rule applies to are larger than.",
+						Type:         schema.TypeInt,
+						Optional:     true,
+						ValidateFunc: validation.IntAtLeast(0),
+					},
+					"object_size_less_than": {
+						Description:  "Size, in bytes, the objects the rule applies to are smaller than.",
+						Type:         schema.TypeInt,
+						Optional:     true,
+						ValidateFunc: validation.IntAtLeast(1),
+					},
+					"expiration_days": {
+						Description:  "Number of days after their creation objects are deleted. Conflicts with `expiration_date`.",
+						Type:         schema.TypeInt,
+						Optional:     true,
+						ValidateFunc: validation.IntAtLeast(1),
+					},
+					"expiration_date": {
+						Description:  "Date, formatted as `YYYY-MM-DD`, from which objects are deleted. Conflicts with `expiration_days`.",
+						Type:         schema.TypeString,
+						Optional:     true,
+						ValidateFunc: validateLifecycleDate,
+					},
+					"abort_incomplete_multipart_upload_days": {
+						Description:  "Number of days after their start unfinished multipart uploads are aborted.",
+						Type:         schema.TypeInt,
+						Optional:     true,
+						ValidateFunc: validation.IntAtLeast(1),
+					},
+				},
+			},
+		},
+	})
+}
+
+func resourceBucketLifecycle() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to manage the lifecycle rules of a Garage bucket, expiring objects and aborting unfinished multipart uploads, through the S3 API. The signing key must own the bucket.",
+		CreateContext: resourceBucketLifecyclePut,
+		ReadContext:   resourceBucketLifecycleRead,
+		UpdateContext: resourceBucketLifecyclePut,
+		DeleteContext: resourceBucketLifecycleDelete,
+		Schema:        schemaBucketLifecycle(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffRequireFeature("garage_bucket_lifecycle", featureBucketLifecycle),
+			customizeDiffLifecycleRules,
+		),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importS3StateFromID("bucket"),
+		},
+	}
+}
+
+func validateLifecycleDate(v interface{}, k string) (ws []string, errors []error) {
+	if _, err := time.Parse(lifecycleDateFormat, v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("%q must be a date formatted as YYYY-MM-DD: %w", k, err))
+	}
+	return
+}
+
+// customizeDiffLifecycleRules fails the plan of rules Garage would refuse,
+// which the schema can't express within blocks.
+func customizeDiffLifecycleRules(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("rule") {
+		return nil
+	}
+
+	for i, rule := range d.Get("rule").([]interface{}) {
+		rule := rule.(map[string]interface{})
+
+		expirationDays := rule["expiration_days"].(int)
+		expirationDate := rule["expiration_date"].(string)
+		if expirationDays > 0 && expirationDate != "" {
+			return fmt.Errorf("rule %d: expiration_days and expiration_date conflict", i)
+		}
+		if expirationDays == 0 && expirationDate == "" && rule["abort_incomplete_multipart_upload_days"].(int) == 0 {
+			return fmt.Errorf("rule %d: set expiration_days, expiration_date or abort_incomplete_multipart_upload_days", i)
+		}
+
+		greaterThan := rule["object_size_greater_than"].(int)
+		lessThan := rule["object_size_less_than"].(int)
+		if greaterThan > 0 && lessThan > 0 && lessThan <= greaterThan {
+			return fmt.Errorf("rule %d: object_size_less_than must be greater than object_size_greater_than", i)
+		}
+	}
+	return nil
+}
+
+// expandLifecycleFilter returns the filter of rule, nesting its conditions
+// in And when there are several of them.
+func expandLifecycleFilter(rule map[string]interface{}) *lifecycleFilter {
+	filter := &lifecycleFilter{}
+	conditions := 0
+	if prefix := rule["prefix"].(string); prefix != "" {
+		filter.Prefix = &prefix
+		conditions++
+	}
+	if greaterThan := int64(rule["object_size_greater_than"].(int)); greaterThan > 0 {
+		filter.ObjectSizeGreaterThan = &greaterThan
+		conditions++
+	}
+	if lessThan := int64(rule["object_size_less_than"].(int)); lessThan > 0 {
+		filter.ObjectSizeLessThan = &lessThan
+		conditions++
+	}
+
+	if conditions > 1 {
+		return &lifecycleFilter{And: filter}
+	}
+	return filter
+}
+
+func expandLifecycleRules(rules []interface{}) []lifecycleRule {
+	lifecycleRules := make([]lifecycleRule, len(rules))
+	for i, rule := range rules {
+		rule := rule.(map[string]interface{})
+
+		lifecycleRules[i] = lifecycleRule{
+			ID:     rule["id"].(string),
+			Status: "Disabled",
+			Filter: expandLifecycleFilter(rule),
+		}
+		if rule["enabled"].(bool) {
+			lifecycleRules[i].Status = "Enabled"
+		}
+		if days := rule["expiration_days"].(int); days > 0 {
+			lifecycleRules[i].Expiration = &lifecycleExpiration{Days: &days}
+		}
+		if date := rule["expiration_date"].(string); date != "" {
+			date += "T00:00:00Z"
+			lifecycleRules[i].Expiration = &lifecycleExpiration{Date: &date}
+		}
+		if days := rule["abort_incomplete_multipart_upload_days"].(int); days > 0 {
+			lifecycleRules[i].AbortIncompleteMultipartUpload = &lifecycleAbortIncompleteMultipartUpload{DaysAfterInitiation: days}
+		}
+	}
+	return lifecycleRules
+}
+
+func flattenLifecycleRule(rule lifecycleRule) interface{} {
+	r := map[string]interface{}{
+		"id":                                     rule.ID,
+		"enabled":                                rule.Status == "Enabled",
+		"prefix":                                 "",
+		"object_size_greater_than":               0,
+		"object_size_less_than":                  0,
+		"expiration_days":                        0,
+		"expiration_date":                        "",
+		"abort_incomplete_multipart_upload_days": 0,
+	}
+
+	filters := []*lifecycleFilter{rule.Filter}
+	if rule.Filter != nil {
+		filters = append(filters, rule.Filter.And)
+	}
+	for _, filter := range filters {
+		if filter == nil {
+			continue
+		}
+		if filter.Prefix != nil {
+			r["prefix"] = *filter.Prefix
+		}
+		if filter.ObjectSizeGreaterThan != nil {
+			r["object_size_greater_than"] = int(*filter.ObjectSizeGreaterThan)
+		}
+		if filter.ObjectSizeLessThan != nil {
+			r["object_size_less_than"] = int(*filter.ObjectSizeLessThan)
+		}
+	}
+
+	if rule.Expiration != nil {
+		if rule.Expiration.Days != nil {
+			r["expiration_days"] = *rule.Expiration.Days
+		}
+		if date := rule.Expiration.Date; date != nil && len(*date) >= len(lifecycleDateFormat) {
+			r["expiration_date"] = (*date)[:len(lifecycleDateFormat)]
+		}
+	}
+	if rule.AbortIncompleteMultipartUpload != nil {
+		r["abort_incomplete_multipart_upload_days"] = rule.AbortIncompleteMultipartUpload.DaysAfterInitiation
+	}
+
+	return r
+}
+
+// resourceBucketLifecyclePut replaces the lifecycle configuration of the
+// bucket, as S3 has no way to change rules separately.
+func resourceBucketLifecyclePut(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	bucket := d.Get("bucket").(string)
+
+	client, bucketName, err := p.s3Bucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = client.PutBucketLifecycle(ctx, bucketName, expandLifecycleRules(d.Get("rule").([]interface{})))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(bucket)
+
+	return resourceBucketLifecycleRead(ctx, d, m)
+}
+
+func resourceBucketLifecycleRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	rules, err := client.GetBucketLifecycle(ctx, bucketName)
+	if isS3NotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	lifecycleRules := make([]interface{}, len(rules))
+	for i, rule := range rules {
+		lifecycleRules[i] = flattenLifecycleRule(rule)
+	}
+
+	err = d.Set("rule", lifecycleRules)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceBucketLifecycleDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = client.DeleteBucketLifecycle(ctx, bucketName)
+	if err != nil && !isS3NotFound(err) {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
@@ -0,0 +1,268 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func bucketLifecycleConfig(expirationDays int) string {
+	return fmt.Sprintf(`
+resource "garage_bucket_lifecycle" "test" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+
+  rule {
+    id                    = "tmp"
+    prefix                = "tmp/"
+    object_size_less_than = 1048576
+    expiration_days       = %d
+  }
+
+  rule {
+    id              = "archive"
+    enabled         = false
+    expiration_date = "2030-01-01"
+  }
+
+  rule {
+    id                                     = "uploads"
+    abort_incomplete_multipart_upload_days = 7
+  }
+}
+`, expirationDays)
+}
+
+func TestUnitResourceBucketLifecycle(t *testing.T) {
+	unitTest(t, []int{1, 2}, func(fake *fakeGarage) resource.TestCase {
+		checkExpirationDays := func(days int) resource.TestCheckFunc {
+			return fake.withState(func() error {
+				for _, b := range fake.buckets {
+					if b.lifecycle != nil && len(b.lifecycle.Rules) == 3 && *b.lifecycle.Rules[0].Expiration.Days == days {
+						return nil
+					}
+				}
+				return fmt.Errorf("no bucket expires tmp/ after %d days", days)
+			})
+		}
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketOwnerConfig(fake.providerConfig(), "logs", bucketLifecycleConfig(1)),
+					Check: resource.ComposeTestCheckFunc(
+						checkExpirationDays(1),
+						resource.TestCheckResourceAttr("garage_bucket_lifecycle.test", "id", "logs"),
+						resource.TestCheckResourceAttr("garage_bucket_lifecycle.test", "rule.1.enabled", "false"),
+						resource.TestCheckResourceAttr("garage_bucket_lifecycle.test", "rule.1.expiration_date", "2030-01-01"),
+					),
+				},
+				{
+					Config: bucketOwnerConfig(fake.providerConfig(), "logs", bucketLifecycleConfig(30)),
+					Check:  checkExpirationDays(30),
+				},
+				// Change out of band
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, b := range fake.buckets {
+							if b.lifecycle != nil {
+								b.lifecycle.Rules[2].Status = "Disabled"
+							}
+						}
+					},
+					Config:             bucketOwnerConfig(fake.providerConfig(), "logs", bucketLifecycleConfig(30)),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				// Deletion out of band
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, b := range fake.buckets {
+							b.lifecycle = nil
+						}
+					},
+					Config:             bucketOwnerConfig(fake.providerConfig(), "logs", bucketLifecycleConfig(30)),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: bucketOwnerConfig(fake.providerConfig(), "logs", bucketLifecycleConfig(30)),
+					Check:  checkExpirationDays(30),
+				},
+				{
+					ResourceName:      "garage_bucket_lifecycle.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+func TestUnitResourceBucketLifecycleRequiresGarage09(t *testing.T) {
+	unitTest(t, []int{0}, func(fake *fakeGarage) resource.TestCase {
+		fake.garageVersion = "v0.8.4"
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config:      bucketOwnerConfig(fake.providerConfig(), "logs", bucketLifecycleConfig(1)),
+					ExpectError: regexp.MustCompile("bucket lifecycle configuration requires Garage 0.9.0"),
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceBucketLifecycle(t *testing.T) {
+	alias := "tf-acc-" + randomHex(10)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		garage.skipWithoutS3(t)
+
+		var accessKeyID string
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketOwnerConfig(garage.providerConfig, alias, bucketLifecycleConfig(1)),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						resource.TestCheckResourceAttr("garage_bucket_lifecycle.test", "rule.#", "3"),
+						resource.TestCheckResourceAttr("garage_bucket_lifecycle.test", "rule.0.prefix", "tmp/"),
+						resource.TestCheckResourceAttr("garage_bucket_lifecycle.test", "rule.0.object_size_less_than", "1048576"),
+						resource.TestCheckResourceAttr("garage_bucket_lifecycle.test", "rule.1.expiration_date", "2030-01-01"),
+					),
+				},
+				// Deletion out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, _ garageClient) error {
+						client, bucketName, err := garage.provider.s3Bucket(ctx, alias, accessKeyID)
+						if err != nil {
+							return err
+						}
+						return client.DeleteBucketLifecycle(ctx, bucketName)
+					}),
+					Config:             bucketOwnerConfig(garage.providerConfig, alias, bucketLifecycleConfig(1)),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: bucketOwnerConfig(garage.providerConfig, alias, bucketLifecycleConfig(30)),
+					Check:  resource.TestCheckResourceAttr("garage_bucket_lifecycle.test", "rule.0.expiration_days", "30"),
+				},
+				{
+					ResourceName:            "garage_bucket_lifecycle.test",
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"access_key_id"},
+				},
+			},
+		}
+	})
+}
+
+func TestResourceBucketLifecycle(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "logs")
+	ctx := context.Background()
+	endpoint, _ := url.Parse(fake.s3.URL)
+	p := &garageProvider{client: fake.client(), s3: &s3Config{endpoint: endpoint, region: defaultS3Region, pathStyle: true, httpClient: fake.s3.Client()}}
+
+	rules := []interface{}{
+		map[string]interface{}{
+			"id":                       "tmp",
+			"prefix":                   "tmp/",
+			"object_size_greater_than": 10,
+			"object_size_less_than":    1000,
+			"expiration_days":          1,
+		},
+		map[string]interface{}{
+			"id":              "logs",
+			"enabled":         false,
+			"prefix":          "logs/",
+			"expiration_date": "2030-01-01",
+		},
+		map[string]interface{}{
+			"id":                                     "uploads",
+			"abort_incomplete_multipart_upload_days": 7,
+		},
+	}
+	d := schema.TestResourceDataRaw(t, schemaBucketLifecycle(), map[string]interface{}{
+		"bucket":        "logs",
+		"access_key_id": client.credentials.AccessKeyID,
+		"rule":          rules,
+	})
+	if diags := resourceBucketLifecyclePut(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	for i, rule := range rules {
+		for name, value := range rule.(map[string]interface{}) {
+			if read := d.Get(fmt.Sprintf("rule.%d.%s", i, name)); read != value {
+				t.Errorf("rule %d: expected %s to be %v, got %v", i, name, value, read)
+			}
+		}
+	}
+	if d.Get("rule.0.enabled") != true || d.Get("rule.1.enabled") != false || d.Get("rule.2.prefix") != "" {
+		t.Errorf("unexpected rules %v", d.Get("rule"))
+	}
+
+	if diags := resourceBucketLifecycleDelete(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceBucketLifecycleRead(ctx, d, p); diags.HasError() || d.Id() != "" {
+		t.Errorf("expected the lifecycle configuration to be gone, got %v, %v", d.Id(), diags)
+	}
+}
+
+func TestCustomizeDiffLifecycleRules(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		rule          map[string]interface{}
+		garageVersion string
+		planError     string
+	}{
+		{"expiration", map[string]interface{}{"expiration_days": 1}, "v1.0.0", ""},
+		{"abort", map[string]interface{}{"abort_incomplete_multipart_upload_days": 1}, "v0.9.0", ""},
+		{"no action", map[string]interface{}{"prefix": "tmp/"}, "v1.0.0", "set expiration_days"},
+		{"days and date", map[string]interface{}{"expiration_days": 1, "expiration_date": "2030-01-01"}, "v1.0.0", "conflict"},
+		{"invalid date", map[string]interface{}{"expiration_date": "2030-01-01T00:00:00Z"}, "v1.0.0", "YYYY-MM-DD"},
+		{"empty size range", map[string]interface{}{"expiration_days": 1, "object_size_greater_than": 10, "object_size_less_than": 10}, "v1.0.0", "object_size_less_than must be greater"},
+		{"Garage 0.8", map[string]interface{}{"expiration_days": 1}, "v0.8.4", "requires Garage 0.9.0"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rule := map[string]interface{}{"id": "test"}
+			for name, value := range c.rule {
+				rule[name] = value
+			}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{"bucket": "logs", "rule": []interface{}{rule}})
+			p := &garageProvider{garageVersion: version.Must(version.NewVersion(c.garageVersion))}
+
+			r := resourceBucketLifecycle()
+			diags := r.Validate(config)
+			var err error
+			if diags.HasError() {
+				err = fmt.Errorf("%v", diags)
+			} else {
+				_, err = r.Diff(context.Background(), nil, config, p)
+			}
+			if c.planError == "" && err != nil {
+				t.Fatal(err)
+			}
+			if c.planError != "" && (err == nil || !strings.Contains(err.Error(), c.planError)) {
+				t.Fatalf("expected an error containing %q, got %v", c.planError, err)
+			}
+		})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/thoas/go-funk"
 )
 
 func schemaBucketLocalAlias() map[string]*schema.Schema {
@@ -21,9 +23,10 @@ func schemaBucketLocalAlias() map[string]*schema.Schema {
 			ForceNew: true,
 		},
 		"alias": {
-			Type:     schema.TypeString,
-			Required: true,
-			ForceNew: true,
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validateBucketAlias,
 		},
 	}
 }
@@ -35,9 +38,72 @@ func resourceBucketLocalAlias() *schema.Resource {
 		ReadContext:   resourceBucketLocalAliasRead,
 		DeleteContext: resourceBucketLocalAliasDelete,
 		Schema:        schemaBucketLocalAlias(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffRequireAdminAPI,
+			customizeDiffLocalAliasAvailable,
+		),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importStateFromID("bucket_id", "access_key_id", "alias"),
+		},
 	}
 }
 
+// customizeDiffLocalAliasAvailable fails the plan when the access key doesn't
+// exist, or when it already has the alias to another bucket. Keys created by
+// the same plan have no access key ID yet, and aren't checked.
+func customizeDiffLocalAliasAvailable(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	p, _ := m.(*garageProvider)
+	if p == nil || p.client == nil || !d.NewValueKnown("access_key_id") {
+		return nil
+	}
+	if d.Id() != "" && !d.HasChange("alias") && !d.HasChange("bucket_id") && !d.HasChange("access_key_id") {
+		return nil
+	}
+
+	accessKeyID := d.Get("access_key_id").(string)
+	_, err := p.client.GetKey(ctx, accessKeyID)
+	if isNotFound(err) {
+		return fmt.Errorf("access key %s doesn't exist", accessKeyID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !d.NewValueKnown("alias") {
+		return nil
+	}
+	alias := d.Get("alias").(string)
+
+	buckets, err := p.client.ListBuckets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, bucketItem := range buckets {
+		for _, localAlias := range bucketItem.LocalAliases {
+			if localAlias.AccessKeyID != accessKeyID || localAlias.Alias != alias {
+				continue
+			}
+			if d.NewValueKnown("bucket_id") && d.Get("bucket_id").(string) == bucketItem.ID {
+				return nil
+			}
+			// A replaced alias is removed from its bucket first.
+			oldBucketID, _ := d.GetChange("bucket_id")
+			oldAccessKeyID, _ := d.GetChange("access_key_id")
+			if d.Id() != "" && oldBucketID.(string) == bucketItem.ID && oldAccessKeyID.(string) == accessKeyID {
+				return nil
+			}
+			return fmt.Errorf("local alias %q of access key %s already points to bucket %s", alias, accessKeyID, bucketItem.ID)
+		}
+	}
+
+	return nil
+}
+
 func resourceBucketLocalAliasCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	p := m.(*garageProvider)
 	var diags diag.Diagnostics
@@ -46,7 +112,7 @@ func resourceBucketLocalAliasCreate(ctx context.Context, d *schema.ResourceData,
 	accessKeyID := d.Get("access_key_id").(string)
 	alias := d.Get("alias").(string)
 
-	_, _, err := p.client.BucketApi.PutBucketLocalAlias(updateContext(ctx, p)).Id(bucketID).AccessKeyId(accessKeyID).Alias(alias).Execute()
+	err := p.client.AddBucketLocalAlias(ctx, bucketID, accessKeyID, alias)
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -57,8 +123,27 @@ func resourceBucketLocalAliasCreate(ctx context.Context, d *schema.ResourceData,
 }
 
 func resourceBucketLocalAliasRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
 	var diags diag.Diagnostics
-	// Noop
+
+	bucketID := d.Get("bucket_id").(string)
+	accessKeyID := d.Get("access_key_id").(string)
+	alias := d.Get("alias").(string)
+
+	bucketInfo, err := p.client.GetBucket(ctx, bucketID)
+	if isNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	key := findBucketKey(bucketInfo, accessKeyID)
+	if key == nil || !funk.ContainsString(key.BucketLocalAliases, alias) {
+		d.SetId("")
+	}
+
 	return diags
 }
 
@@ -70,7 +155,7 @@ func resourceBucketLocalAliasDelete(ctx context.Context, d *schema.ResourceData,
 	accessKeyID := d.Get("access_key_id").(string)
 	alias := d.Get("alias").(string)
 
-	_, _, err := p.client.BucketApi.DeleteBucketLocalAlias(updateContext(ctx, p)).Id(bucketID).AccessKeyId(accessKeyID).Alias(alias).Execute()
+	err := p.client.RemoveBucketLocalAlias(ctx, bucketID, accessKeyID, alias)
 	if err != nil {
 		return diag.FromErr(err)
 	}
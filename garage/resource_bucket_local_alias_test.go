@@ -0,0 +1,162 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestUnitResourceBucketLocalAlias(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		config := fake.providerConfig() + `
+resource "garage_bucket" "test" {}
+
+resource "garage_key" "test" {
+  name = "test"
+}
+
+resource "garage_bucket_local_alias" "test" {
+  bucket_id     = garage_bucket.test.id
+  access_key_id = garage_key.test.access_key_id
+  alias         = "mine"
+}
+`
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_bucket_local_alias.test", "alias", "mine"),
+						fake.withState(func() error {
+							for _, b := range fake.buckets {
+								for _, aliases := range b.localAliases {
+									if len(aliases) == 1 && aliases[0] == "mine" {
+										return nil
+									}
+								}
+							}
+							return fmt.Errorf("local alias mine wasn't added")
+						}),
+					),
+				},
+				{
+					Config: config + `
+resource "garage_bucket" "other" {}
+
+resource "garage_bucket_local_alias" "other" {
+  bucket_id     = garage_bucket.other.id
+  access_key_id = garage_key.test.access_key_id
+  alias         = "mine"
+}
+`,
+					ExpectError: regexp.MustCompile("already points to bucket"),
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceBucketLocalAlias(t *testing.T) {
+	var bucketID string
+	var accessKeyID string
+	alias := "tf-acc-" + randomHex(10)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		config := garage.providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {}
+
+resource "garage_key" "test" {}
+
+resource "garage_bucket_local_alias" "test" {
+  bucket_id     = garage_bucket.test.id
+  access_key_id = garage_key.test.access_key_id
+  alias         = %q
+}
+`, alias)
+		checkAlias := garage.checkBucket(&bucketID, func(bucketInfo *bucket) error {
+			key := findBucketKey(bucketInfo, accessKeyID)
+			if key == nil || len(key.BucketLocalAliases) != 1 || key.BucketLocalAliases[0] != alias {
+				return fmt.Errorf("unexpected keys %+v", bucketInfo.Keys)
+			}
+			return nil
+		})
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_bucket.test", "id", &bucketID),
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						checkAlias,
+					),
+				},
+				// Removal out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, client garageClient) error {
+						return client.RemoveBucketLocalAlias(ctx, bucketID, accessKeyID, alias)
+					}),
+					Config:             config,
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: config,
+					Check:  checkAlias,
+				},
+				{
+					ResourceName:      "garage_bucket_local_alias.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+func planLocalAlias(fake *fakeGarage, config map[string]interface{}) error {
+	p := &garageProvider{client: fake.client(), apiVersion: fake.apiVersion}
+	_, err := resourceBucketLocalAlias().Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), p)
+	return err
+}
+
+func TestCustomizeDiffLocalAliasAvailable(t *testing.T) {
+	forEachAPIVersion(t, func(t *testing.T, fake *fakeGarage, client garageClient) {
+		ctx := context.Background()
+
+		bucketInfo, err := client.CreateBucket(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := client.CreateKey(ctx, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.AddBucketLocalAlias(ctx, bucketInfo.ID, key.AccessKeyID, "taken"); err != nil {
+			t.Fatal(err)
+		}
+
+		err = planLocalAlias(fake, map[string]interface{}{"bucket_id": "other", "access_key_id": "GK000000000000000000000000", "alias": "free"})
+		if err == nil || !regexp.MustCompile("doesn't exist").MatchString(err.Error()) {
+			t.Errorf("expected the missing key to fail the plan, got %v", err)
+		}
+		// Keys created by the same plan aren't known yet.
+		if err := planLocalAlias(fake, map[string]interface{}{"bucket_id": "other", "access_key_id": unknownValue, "alias": "free"}); err != nil {
+			t.Errorf("expected the alias of a key yet to be created to be planned, got %v", err)
+		}
+		err = planLocalAlias(fake, map[string]interface{}{"bucket_id": unknownValue, "access_key_id": key.AccessKeyID, "alias": "taken"})
+		if err == nil || !regexp.MustCompile("already points to bucket "+bucketInfo.ID).MatchString(err.Error()) {
+			t.Errorf("expected the taken alias to fail the plan, got %v", err)
+		}
+		if err := planLocalAlias(fake, map[string]interface{}{"bucket_id": bucketInfo.ID, "access_key_id": key.AccessKeyID, "alias": "taken"}); err != nil {
+			t.Errorf("expected the alias of the same bucket to be planned, got %v", err)
+		}
+		if err := planLocalAlias(fake, map[string]interface{}{"bucket_id": "other", "access_key_id": key.AccessKeyID, "alias": "free"}); err != nil {
+			t.Errorf("expected the free alias to be planned, got %v", err)
+		}
+	})
+}
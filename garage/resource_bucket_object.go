@@ -0,0 +1,284 @@
+package garage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// objectContentAttributes are the attributes an object is uploaded from, of
+// which exactly one is set.
+var objectContentAttributes = []string{"content", "content_base64", "source"}
+
+func schemaBucketObject() map[string]*schema.Schema {
+	return withS3Bucket(map[string]*schema.Schema{
+		"key": {
+			Description:  "Key of the object in the bucket.",
+			Type:         schema.TypeString,
+			Required:     true,
+			ForceNew:     true,
+			ValidateFunc: validation.StringLenBetween(1, 1024),
+		},
+		"content": {
+			Description:  "Content of the object, as UTF-8 text.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			ExactlyOneOf: objectContentAttributes,
+		},
+		"content_base64": {
+			Description:  "Content of the object, base64-encoded, for binary content.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			ExactlyOneOf: objectContentAttributes,
+			ValidateFunc: validation.StringIsBase64,
+		},
+		"source": {
+			Description:  "Path to a file uploaded as the content of the object, in parts when it is larger than 8 MiB. Changes of the file are detected through its SHA-256, recorded in `source_hash`.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			ExactlyOneOf: objectContentAttributes,
+		},
+		"content_type": {
+			Description: "MIME type of the object. Garage stores `blob` when it is not set.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Computed:    true,
+		},
+		"cache_control": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"content_encoding": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"metadata": {
+			Description: "User metadata of the object, sent as `x-amz-meta-*` headers. Names are lowercase.",
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+			ValidateDiagFunc: validation.MapKeyMatch(regexp.MustCompile(`^[0-9a-z_-]+$`), "metadata names must only contain lowercase letters, digits, '_' and '-'"),
+		},
+		// Computed
+		"etag": {
+			Description: "ETag of the object. Changes made to the object outside of Terraform are detected through it, except for objects uploaded in parts from `source`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"source_hash": {
+			Description: "Hex-encoded SHA-256 of `source` when it was last uploaded.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	})
+}
+
+func resourceBucketObject() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to manage objects in Garage buckets, through the S3 API.",
+		CreateContext: resourceBucketObjectPut,
+		ReadContext:   resourceBucketObjectRead,
+		UpdateContext: resourceBucketObjectPut,
+		DeleteContext: resourceBucketObjectDelete,
+		Schema:        schemaBucketObject(),
+		CustomizeDiff: customizeDiffBucketObjectETag,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importS3StateFromID("bucket", "key"),
+		},
+	}
+}
+
+// configuredObjectDigest returns the SHA-256 of source, empty for content set
+// inline, and the ETag of the content set in the configuration once uploaded,
+// empty for sources uploaded in parts. It returns false when the content is
+// not known yet.
+func configuredObjectDigest(d *schema.ResourceDiff) (string, string, bool, error) {
+	for _, attribute := range objectContentAttributes {
+		if !d.NewValueKnown(attribute) {
+			return "", "", false, nil
+		}
+	}
+
+	if source, ok := d.GetOk("source"); ok {
+		hash, etag, err := fileDigest(source.(string))
+		if err != nil {
+			return "", "", false, fmt.Errorf("failed to read source: %w", err)
+		}
+		return hash, etag, true, nil
+	}
+	if contentBase64, ok := d.GetOk("content_base64"); ok {
+		content, err := base64.StdEncoding.DecodeString(contentBase64.(string))
+		if err != nil {
+			return "", "", false, err
+		}
+		return "", contentETag(content), true, nil
+	}
+	return "", contentETag([]byte(d.Get("content").(string))), true, nil
+}
+
+// customizeDiffBucketObjectETag plans to upload the object again when its
+// content changed, including changes of the source file, which show as a
+// SHA-256 not matching source_hash, and changes of the object in the bucket,
+// which show as an ETag not matching the one refreshed. The ETag Garage gives
+// objects uploaded in parts can't be predicted, so changes of those in the
+// bucket go unnoticed.
+func customizeDiffBucketObjectETag(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+	if d.HasChanges(objectContentAttributes...) {
+		if err := d.SetNewComputed("source_hash"); err != nil {
+			return err
+		}
+		return d.SetNewComputed("etag")
+	}
+
+	hash, etag, known, err := configuredObjectDigest(d)
+	if err != nil || !known {
+		return err
+	}
+	if hash != d.Get("source_hash").(string) {
+		if err := d.SetNew("source_hash", hash); err != nil {
+			return err
+		}
+		return d.SetNewComputed("etag")
+	}
+	if etag != "" && etag != d.Get("etag").(string) {
+		return d.SetNewComputed("etag")
+	}
+	return nil
+}
+
+func objectMetadataFromResource(d *schema.ResourceData) s3ObjectMetadata {
+	metadata := s3ObjectMetadata{
+		ContentType:     d.Get("content_type").(string),
+		CacheControl:    d.Get("cache_control").(string),
+		ContentEncoding: d.Get("content_encoding").(string),
+		Metadata:        map[string]string{},
+	}
+	for name, value := range d.Get("metadata").(map[string]interface{}) {
+		metadata.Metadata[name] = value.(string)
+	}
+	return metadata
+}
+
+func flattenObject(object *s3Object) interface{} {
+	return map[string]interface{}{
+		"content_type":     object.ContentType,
+		"cache_control":    object.CacheControl,
+		"content_encoding": object.ContentEncoding,
+		"metadata":         object.Metadata,
+		"etag":             object.ETag,
+	}
+}
+
+// resourceBucketObjectPut uploads the object, replacing its content and
+// metadata, as S3 has no way to change them separately.
+func resourceBucketObjectPut(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	bucket := d.Get("bucket").(string)
+	key := d.Get("key").(string)
+
+	client, bucketName, err := p.s3Bucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	metadata := objectMetadataFromResource(d)
+	sourceHash := ""
+	if source, ok := d.GetOk("source"); ok {
+		sourceHash, _, err = fileDigest(source.(string))
+		if err != nil {
+			return diag.Errorf("failed to read source: %s", err)
+		}
+		_, err = client.PutObjectFile(ctx, bucketName, key, metadata, source.(string))
+	} else if contentBase64, ok := d.GetOk("content_base64"); ok {
+		var content []byte
+		content, err = base64.StdEncoding.DecodeString(contentBase64.(string))
+		if err == nil {
+			_, err = client.PutObject(ctx, bucketName, key, metadata, content)
+		}
+	} else {
+		_, err = client.PutObject(ctx, bucketName, key, metadata, []byte(d.Get("content").(string)))
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", bucket, key))
+	err = d.Set("source_hash", sourceHash)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceBucketObjectRead(ctx, d, m)
+}
+
+func resourceBucketObjectRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	key := d.Get("key").(string)
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	object, err := client.HeadObject(ctx, bucketName, key)
+	if isS3NotFound(err) {
+		// Deleted outside of Terraform
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	for key, value := range flattenObject(object).(map[string]interface{}) {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceBucketObjectDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = client.DeleteObject(ctx, bucketName, d.Get("key").(string))
+	if err != nil && !isS3NotFound(err) {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
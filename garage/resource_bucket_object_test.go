@@ -0,0 +1,333 @@
+package garage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// bucketObjectConfig is a bucket reachable through the S3 API with a key
+// allowed to write to it, along with object, a garage_bucket_object block
+// named test.
+func bucketObjectConfig(providerConfig string, alias string, object string) string {
+	return providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {}
+
+resource "garage_bucket_global_alias" "test" {
+  bucket_id = garage_bucket.test.id
+  alias     = %q
+}
+
+resource "garage_key" "test" {
+  name = "objects"
+}
+
+resource "garage_bucket_key" "test" {
+  bucket_id     = garage_bucket.test.id
+  access_key_id = garage_key.test.access_key_id
+  read          = true
+  write         = true
+}
+`, alias) + object
+}
+
+func TestUnitResourceBucketObject(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		object := func(content string) string {
+			return fmt.Sprintf(`
+resource "garage_bucket_object" "test" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  key           = "config/app.json"
+  content       = %q
+  content_type  = "application/json"
+  metadata = {
+    owner = "ops"
+  }
+}
+`, content)
+		}
+		checkContent := func(content string) resource.TestCheckFunc {
+			return fake.withState(func() error {
+				for _, b := range fake.buckets {
+					if o, ok := b.objects["config/app.json"]; ok && string(o.content) == content && o.ContentType == "application/json" && o.Metadata["owner"] == "ops" {
+						return nil
+					}
+				}
+				return fmt.Errorf("object config/app.json wasn't stored with %q", content)
+			})
+		}
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketObjectConfig(fake.providerConfig(), "objects", object(`{"version":1}`)),
+					Check: resource.ComposeTestCheckFunc(
+						checkContent(`{"version":1}`),
+						resource.TestCheckResourceAttr("garage_bucket_object.test", "id", "objects/config/app.json"),
+						resource.TestCheckResourceAttr("garage_bucket_object.test", "etag", contentETag([]byte(`{"version":1}`))),
+					),
+				},
+				{
+					Config: bucketObjectConfig(fake.providerConfig(), "objects", object(`{"version":2}`)),
+					Check:  checkContent(`{"version":2}`),
+				},
+				// Change out of band
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, b := range fake.buckets {
+							if o, ok := b.objects["config/app.json"]; ok {
+								o.content = []byte("tampered")
+								o.etag = contentETag(o.content)
+							}
+						}
+					},
+					Config:             bucketObjectConfig(fake.providerConfig(), "objects", object(`{"version":2}`)),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: bucketObjectConfig(fake.providerConfig(), "objects", object(`{"version":2}`)),
+					Check:  checkContent(`{"version":2}`),
+				},
+				{
+					ResourceName:            "garage_bucket_object.test",
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"content"},
+					ImportStateIdFunc: func(s *terraform.State) (string, error) {
+						return "objects/config/app.json", nil
+					},
+				},
+			},
+		}
+	})
+}
+
+func TestUnitResourceBucketObjectSource(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "archive.bin")
+
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		config := bucketObjectConfig(fake.providerConfig(), "archives", fmt.Sprintf(`
+resource "garage_bucket_object" "test" {
+  bucket        = garage_bucket.test.id
+  access_key_id = garage_bucket_key.test.access_key_id
+  key           = "archive.bin"
+  source        = %q
+
+  depends_on = [garage_bucket_global_alias.test]
+}
+`, source))
+
+		return resource.TestCase{
+			PreCheck: func() {
+				if err := os.WriteFile(source, bytes.Repeat([]byte("a"), s3PartSize+1), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestMatchResourceAttr("garage_bucket_object.test", "etag", regexp.MustCompile(`-2$`)),
+						resource.TestCheckResourceAttr("garage_bucket_object.test", "source_hash", sha256Hex(bytes.Repeat([]byte("a"), s3PartSize+1))),
+						fake.withState(func() error {
+							for _, b := range fake.buckets {
+								if o, ok := b.objects["archive.bin"]; ok && len(o.content) == s3PartSize+1 {
+									return nil
+								}
+							}
+							return fmt.Errorf("archive.bin wasn't uploaded")
+						}),
+					),
+				},
+				// Change of the source
+				{
+					PreConfig: func() {
+						if err := os.WriteFile(source, []byte("small"), 0o600); err != nil {
+							t.Fatal(err)
+						}
+					},
+					Config:             config,
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_bucket_object.test", "etag", contentETag([]byte("small"))),
+						resource.TestCheckResourceAttr("garage_bucket_object.test", "source_hash", sha256Hex([]byte("small"))),
+					),
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceBucketObject(t *testing.T) {
+	alias := "tf-acc-" + randomHex(10)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		garage.skipWithoutS3(t)
+
+		config := bucketObjectConfig(garage.providerConfig, alias, `
+resource "garage_bucket_object" "test" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  key           = "robots.txt"
+  content       = "User-agent: *\nDisallow: /\n"
+  content_type  = "text/plain"
+  cache_control = "max-age=3600"
+}
+`)
+		var accessKeyID string
+		checkObject := func(ctx context.Context) error {
+			client, bucketName, err := garage.provider.s3Bucket(ctx, alias, accessKeyID)
+			if err != nil {
+				return err
+			}
+			object, err := client.HeadObject(ctx, bucketName, "robots.txt")
+			if err != nil {
+				return err
+			}
+			if object.ETag != contentETag([]byte("User-agent: *\nDisallow: /\n")) || object.CacheControl != "max-age=3600" {
+				return fmt.Errorf("unexpected object %+v", object)
+			}
+			return nil
+		}
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						func(*terraform.State) error { return checkObject(context.Background()) },
+					),
+				},
+				// Change out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, _ garageClient) error {
+						client, bucketName, err := garage.provider.s3Bucket(ctx, alias, accessKeyID)
+						if err != nil {
+							return err
+						}
+						_, err = client.PutObject(ctx, bucketName, "robots.txt", s3ObjectMetadata{}, []byte("tampered"))
+						return err
+					}),
+					Config:             config,
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: config,
+					Check:  func(*terraform.State) error { return checkObject(context.Background()) },
+				},
+				// Deletion out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, _ garageClient) error {
+						client, bucketName, err := garage.provider.s3Bucket(ctx, alias, accessKeyID)
+						if err != nil {
+							return err
+						}
+						return client.DeleteObject(ctx, bucketName, "robots.txt")
+					}),
+					Config:             config,
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: config,
+					Check:  func(*terraform.State) error { return checkObject(context.Background()) },
+				},
+				{
+					ResourceName:            "garage_bucket_object.test",
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"access_key_id", "content"},
+				},
+			},
+		}
+	})
+}
+
+func TestCustomizeDiffBucketObjectETag(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "index.html")
+	if err := os.WriteFile(source, []byte("<h1>Hello</h1>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	largeContent := bytes.Repeat([]byte("a"), s3PartSize+1)
+	largeSource := filepath.Join(t.TempDir(), "archive.bin")
+	if err := os.WriteFile(largeSource, largeContent, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256Hex([]byte("<h1>Hello</h1>"))
+
+	for _, c := range []struct {
+		name       string
+		config     map[string]interface{}
+		etag       string
+		sourceHash string
+		reupload   bool
+		planError  string
+	}{
+		{"unchanged content", map[string]interface{}{"content": "hello"}, contentETag([]byte("hello")), "", false, ""},
+		{"content changed out of band", map[string]interface{}{"content": "hello"}, contentETag([]byte("tampered")), "", true, ""},
+		{"unchanged base64 content", map[string]interface{}{"content_base64": "aGVsbG8="}, contentETag([]byte("hello")), "", false, ""},
+		{"unchanged source", map[string]interface{}{"source": source}, contentETag([]byte("<h1>Hello</h1>")), hash, false, ""},
+		{"source changed", map[string]interface{}{"source": source}, contentETag([]byte("<h1>Hello</h1>")), sha256Hex([]byte("<h1>Hi</h1>")), true, ""},
+		{"source changed out of band", map[string]interface{}{"source": source}, contentETag([]byte("tampered")), hash, true, ""},
+		{"unchanged large source", map[string]interface{}{"source": largeSource}, "0123456789abcdef0123456789abcdef-2", sha256Hex(largeContent), false, ""},
+		{"large source changed", map[string]interface{}{"source": largeSource}, "0123456789abcdef0123456789abcdef-2", hash, true, ""},
+		{"missing source", map[string]interface{}{"source": source + ".missing"}, "", hash, false, "failed to read source"},
+		{"unknown content", map[string]interface{}{"content": unknownValue}, contentETag([]byte("hello")), "", true, ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			attributes := map[string]string{
+				"id":           "objects/index.html",
+				"bucket":       "objects",
+				"key":          "index.html",
+				"content_type": "blob",
+				"etag":         c.etag,
+				"source_hash":  c.sourceHash,
+			}
+			for name, value := range c.config {
+				if value != unknownValue {
+					attributes[name] = value.(string)
+				} else {
+					attributes[name] = "hello"
+				}
+			}
+			config := map[string]interface{}{"bucket": "objects", "key": "index.html"}
+			for name, value := range c.config {
+				config[name] = value
+			}
+
+			diff, err := resourceBucketObject().Diff(context.Background(), &terraform.InstanceState{ID: "objects/index.html", Attributes: attributes}, terraform.NewResourceConfigRaw(config), &garageProvider{})
+			if c.planError != "" {
+				if err == nil || !strings.Contains(err.Error(), c.planError) {
+					t.Fatalf("expected an error containing %q, got %v", c.planError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reupload := diff != nil && diff.Attributes["etag"] != nil && diff.Attributes["etag"].NewComputed
+			if reupload != c.reupload {
+				t.Errorf("expected an upload to be planned: %t, got %+v", c.reupload, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestUnitResourceBucket(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+resource "garage_bucket" "test" {
+  website_access_enabled        = true
+  website_config_index_document = "index.html"
+  website_config_error_document = "error.html"
+  quota_max_size                = 1073741824
+  quota_max_objects             = 1000
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttrSet("garage_bucket.test", "id"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "website_access_enabled", "true"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "website_config_index_document", "index.html"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "website_config_error_document", "error.html"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "quota_max_size", "1073741824"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "quota_max_objects", "1000"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "global_aliases.#", "0"),
+						fake.withState(func() error {
+							for _, b := range fake.buckets {
+								if b.website == nil || b.website.IndexDocument != "index.html" {
+									return fmt.Errorf("website access of bucket %s wasn't enabled", b.id)
+								}
+							}
+							return nil
+						}),
+					),
+				},
+				{
+					Config: fake.providerConfig() + `
+resource "garage_bucket" "test" {
+  quota_max_size    = 1073741824
+  quota_max_objects = 2000
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_bucket.test", "website_access_enabled", "false"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "quota_max_objects", "2000"),
+						fake.withState(func() error {
+							for _, b := range fake.buckets {
+								if b.website != nil {
+									return fmt.Errorf("website access of bucket %s wasn't disabled", b.id)
+								}
+							}
+							return nil
+						}),
+					),
+				},
+				{
+					ResourceName:      "garage_bucket.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+					// Website documents are kept in the state once website
+					// access is disabled, but not read from Garage.
+					ImportStateVerifyIgnore: []string{"website_config_index_document", "website_config_error_document"},
+				},
+			},
+		}
+	})
+}
+
+func TestResourceBucketManageWebsite(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	p := &garageProvider{client: fake.client(), apiVersion: 2, consistencyTimeout: time.Second}
+
+	r := resourceBucket()
+	apply := func(state *terraform.InstanceState, config map[string]interface{}) *terraform.InstanceState {
+		t.Helper()
+		if state != nil {
+			var diags diag.Diagnostics
+			state, diags = r.RefreshWithoutUpgrade(ctx, state, p)
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+		}
+		instanceDiff, err := r.Diff(ctx, state, terraform.NewResourceConfigRaw(config), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if instanceDiff.Empty() {
+			return state
+		}
+		state, diags := r.Apply(ctx, state, instanceDiff, p)
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return state
+	}
+	website := func() (*bucketWebsiteConfig, *websiteConfiguration) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		for _, b := range fake.buckets {
+			return b.website, b.websiteRouting
+		}
+		return nil, nil
+	}
+
+	state := apply(nil, map[string]interface{}{"manage_website": false, "quota_max_objects": 1000})
+
+	// Website access set as garage_bucket_website does is read, but kept.
+	indexDocument := "index.html"
+	if _, err := fake.client().SetBucketWebsite(ctx, state.ID, bucketWebsite{Enabled: true, IndexDocument: &indexDocument}); err != nil {
+		t.Fatal(err)
+	}
+	fake.mu.Lock()
+	fake.buckets[state.ID].websiteRouting = &websiteConfiguration{}
+	fake.mu.Unlock()
+
+	state, diags := r.RefreshWithoutUpgrade(ctx, state, p)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	instanceDiff, err := r.Diff(ctx, state, terraform.NewResourceConfigRaw(map[string]interface{}{"manage_website": false, "quota_max_objects": 1000}), p)
+	if err != nil || !instanceDiff.Empty() {
+		t.Errorf("expected no changes, got %v, %v", instanceDiff, err)
+	}
+
+	state = apply(state, map[string]interface{}{"manage_website": false, "quota_max_objects": 2000})
+	if state.Attributes["website_access_enabled"] != "true" || state.Attributes["website_config_index_document"] != "index.html" || state.Attributes["quota_max_objects"] != "2000" {
+		t.Errorf("unexpected state %v", state.Attributes)
+	}
+	if config, routing := website(); config == nil || routing == nil {
+		t.Errorf("expected the website to be kept, got %+v and %+v", config, routing)
+	}
+
+	// Managing the website again disables it, as configured.
+	apply(state, map[string]interface{}{"quota_max_objects": 2000})
+	if config, routing := website(); config != nil || routing != nil {
+		t.Errorf("expected the website to be disabled, got %+v and %+v", config, routing)
+	}
+}
+
+func testAccBucketConfig(garage *testGarage, websiteAccessEnabled bool, quotaMaxObjects int) string {
+	return garage.providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {
+  website_access_enabled        = %t
+  website_config_index_document = "index.html"
+  quota_max_objects             = %d
+}
+`, websiteAccessEnabled, quotaMaxObjects)
+}
+
+func TestAccResourceBucket(t *testing.T) {
+	var bucketID string
+	var deletedBucketID string
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: testAccBucketConfig(garage, true, 1000),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_bucket.test", "id", &bucketID),
+						resource.TestCheckResourceAttr("garage_bucket.test", "website_access_enabled", "true"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "quota_max_objects", "1000"),
+						garage.checkBucket(&bucketID, func(bucketInfo *bucket) error {
+							if !bucketInfo.WebsiteAccess || !equalInt64Ptr(bucketInfo.Quotas.MaxObjects, int64Ptr(1000)) {
+								return fmt.Errorf("bucket wasn't configured: %+v", bucketInfo)
+							}
+							return nil
+						}),
+					),
+				},
+				// Drift of the quotas
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, client garageClient) error {
+						_, err := client.SetBucketQuotas(ctx, bucketID, bucketQuotas{MaxObjects: int64Ptr(5)})
+						return err
+					}),
+					Config:             testAccBucketConfig(garage, true, 1000),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: testAccBucketConfig(garage, true, 1000),
+					Check: garage.checkBucket(&bucketID, func(bucketInfo *bucket) error {
+						if !equalInt64Ptr(bucketInfo.Quotas.MaxObjects, int64Ptr(1000)) {
+							return fmt.Errorf("quotas weren't restored: %+v", bucketInfo.Quotas)
+						}
+						return nil
+					}),
+				},
+				{
+					Config: testAccBucketConfig(garage, false, 2000),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_bucket.test", "website_access_enabled", "false"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "quota_max_objects", "2000"),
+						garage.checkBucket(&bucketID, func(bucketInfo *bucket) error {
+							if bucketInfo.WebsiteAccess || !equalInt64Ptr(bucketInfo.Quotas.MaxObjects, int64Ptr(2000)) {
+								return fmt.Errorf("bucket wasn't updated: %+v", bucketInfo)
+							}
+							return nil
+						}),
+					),
+				},
+				// Deletion out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, client garageClient) error {
+						deletedBucketID = bucketID
+						return client.DeleteBucket(ctx, bucketID)
+					}),
+					Config:             testAccBucketConfig(garage, false, 2000),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: testAccBucketConfig(garage, false, 2000),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_bucket.test", "id", &bucketID),
+						func(*terraform.State) error {
+							if bucketID == deletedBucketID {
+								return fmt.Errorf("bucket %s wasn't recreated", bucketID)
+							}
+							return nil
+						},
+					),
+				},
+				{
+					ResourceName:            "garage_bucket.test",
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"website_config_index_document", "website_config_error_document"},
+				},
+			},
+		}
+	})
+}
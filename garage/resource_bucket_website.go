@@ -0,0 +1,322 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+var websiteProtocols = []string{"http", "https"}
+
+func schemaBucketWebsite() map[string]*schema.Schema {
+	return withS3Bucket(map[string]*schema.Schema{
+		"index_document": {
+			Description: "Suffix of the objects served for requests to directories, such as `index.html`.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"error_document": {
+			Description: "Key of the object served when requests fail.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"routing_rule": {
+			Description: "Rule redirecting or rewriting the requests matching its condition. The first matching rule applies.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"condition": {
+						Description: "Condition of the rule, which applies to every request without it.",
+						Type:        schema.TypeList,
+						Optional:    true,
+						MaxItems:    1,
+						Elem: &schema.Resource{
+							Schema: map[string]*schema.Schema{
+								"key_prefix_equals": {
+									Description: "Prefix of the keys of the requests the rule applies to.",
+									Type:        schema.TypeString,
+									Optional:    true,
+								},
+								"http_error_code_returned_equals": {
+									Description:  "HTTP error code, such as `404`, of the requests the rule applies to.",
+									Type:         schema.TypeInt,
+									Optional:     true,
+									ValidateFunc: validation.IntBetween(400, 599),
+								},
+							},
+						},
+					},
+					"redirect": {
+						Type:     schema.TypeList,
+						Required: true,
+						MinItems: 1,
+						MaxItems: 1,
+						Elem: &schema.Resource{
+							Schema: map[string]*schema.Schema{
+								"host_name": {
+									Description: "Host requests are redirected to, defaulting to the one of the request.",
+									Type:        schema.TypeString,
+									Optional:    true,
+								},
+								"protocol": {
+									Type:         schema.TypeString,
+									Optional:     true,
+									ValidateFunc: validation.StringInSlice(websiteProtocols, false),
+								},
+								"http_redirect_code": {
+									Description:  "HTTP status of the redirect, `301` by default. `200` rewrites the request, serving the replaced key instead of redirecting.",
+									Type:         schema.TypeInt,
+									Optional:     true,
+									ValidateFunc: validation.IntInSlice([]int{200, 301, 302, 303, 307, 308}),
+								},
+								"replace_key_prefix_with": {
+									Description: "Prefix replacing `key_prefix_equals` in the key requests are redirected to. Conflicts with `replace_key_with`.",
+									Type:        schema.TypeString,
+									Optional:    true,
+								},
+								"replace_key_with": {
+									Description: "Key requests are redirected to. Conflicts with `replace_key_prefix_with`.",
+									Type:        schema.TypeString,
+									Optional:    true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func resourceBucketWebsite() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to manage the website configuration of a Garage bucket, including redirects and routing rules the admin API doesn't cover, through the S3 API. The signing key must own the bucket. Website access is enabled along with the configuration, and destroying this resource disables it, so `manage_website` of the `garage_bucket` must be `false`. Garage doesn't implement `RedirectAllRequestsTo`, which this resource leaves out: a routing rule without condition redirects all requests instead.",
+		CreateContext: resourceBucketWebsitePut,
+		ReadContext:   resourceBucketWebsiteRead,
+		UpdateContext: resourceBucketWebsitePut,
+		DeleteContext: resourceBucketWebsiteDelete,
+		Schema:        schemaBucketWebsite(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffRequireFeatureIfSet("routing_rule", featureWebsiteRoutingRules),
+			customizeDiffWebsiteRoutingRules,
+		),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importS3StateFromID("bucket"),
+		},
+	}
+}
+
+// customizeDiffWebsiteRoutingRules fails the plan of routing rules Garage
+// would refuse, which the schema can't express within blocks.
+func customizeDiffWebsiteRoutingRules(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("routing_rule") {
+		return nil
+	}
+
+	for i, rule := range d.Get("routing_rule").([]interface{}) {
+		rule := rule.(map[string]interface{})
+
+		redirects := rule["redirect"].([]interface{})
+		if len(redirects) == 0 || redirects[0] == nil {
+			continue
+		}
+		redirect := redirects[0].(map[string]interface{})
+		if redirect["replace_key_prefix_with"].(string) != "" && redirect["replace_key_with"].(string) != "" {
+			return fmt.Errorf("routing_rule %d: replace_key_prefix_with and replace_key_with conflict", i)
+		}
+		if redirect["http_redirect_code"].(int) == 200 && redirect["host_name"].(string) != "" {
+			return fmt.Errorf("routing_rule %d: requests can only be rewritten to keys of the bucket, not to host_name", i)
+		}
+	}
+	return nil
+}
+
+// optionalString returns a pointer to value, or nil when it is empty, as
+// optional XML elements are sent.
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func optionalInt(value int) *int {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
+
+func expandWebsiteConfiguration(d *schema.ResourceData) *websiteConfiguration {
+	configuration := &websiteConfiguration{}
+
+	if indexDocument, ok := d.GetOk("index_document"); ok {
+		configuration.IndexDocument = &websiteIndexDocument{Suffix: indexDocument.(string)}
+	}
+	if errorDocument, ok := d.GetOk("error_document"); ok {
+		configuration.ErrorDocument = &websiteErrorDocument{Key: errorDocument.(string)}
+	}
+
+	for _, rule := range d.Get("routing_rule").([]interface{}) {
+		rule := rule.(map[string]interface{})
+
+		routingRule := websiteRoutingRule{}
+		if conditions := rule["condition"].([]interface{}); len(conditions) > 0 && conditions[0] != nil {
+			condition := conditions[0].(map[string]interface{})
+			routingRule.Condition = &websiteRoutingCondition{
+				KeyPrefixEquals:             optionalString(condition["key_prefix_equals"].(string)),
+				HTTPErrorCodeReturnedEquals: optionalInt(condition["http_error_code_returned_equals"].(int)),
+			}
+		}
+		if redirects := rule["redirect"].([]interface{}); len(redirects) > 0 && redirects[0] != nil {
+			redirect := redirects[0].(map[string]interface{})
+			routingRule.Redirect = websiteRedirect{
+				HostName:             optionalString(redirect["host_name"].(string)),
+				Protocol:             optionalString(redirect["protocol"].(string)),
+				HTTPRedirectCode:     optionalInt(redirect["http_redirect_code"].(int)),
+				ReplaceKeyPrefixWith: optionalString(redirect["replace_key_prefix_with"].(string)),
+				ReplaceKeyWith:       optionalString(redirect["replace_key_with"].(string)),
+			}
+		}
+		configuration.RoutingRules = append(configuration.RoutingRules, routingRule)
+	}
+
+	return configuration
+}
+
+func intValue(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func flattenWebsiteRoutingRule(rule websiteRoutingRule) interface{} {
+	r := map[string]interface{}{
+		"condition": []interface{}{},
+		"redirect": []interface{}{
+			map[string]interface{}{
+				"host_name":               stringValue(rule.Redirect.HostName),
+				"protocol":                stringValue(rule.Redirect.Protocol),
+				"http_redirect_code":      intValue(rule.Redirect.HTTPRedirectCode),
+				"replace_key_prefix_with": stringValue(rule.Redirect.ReplaceKeyPrefixWith),
+				"replace_key_with":        stringValue(rule.Redirect.ReplaceKeyWith),
+			},
+		},
+	}
+	if condition := rule.Condition; condition != nil {
+		r["condition"] = []interface{}{
+			map[string]interface{}{
+				"key_prefix_equals":               stringValue(condition.KeyPrefixEquals),
+				"http_error_code_returned_equals": intValue(condition.HTTPErrorCodeReturnedEquals),
+			},
+		}
+	}
+	return r
+}
+
+func flattenWebsiteConfiguration(configuration *websiteConfiguration) interface{} {
+	w := map[string]interface{}{
+		"index_document": "",
+		"error_document": "",
+	}
+	if configuration.IndexDocument != nil {
+		w["index_document"] = configuration.IndexDocument.Suffix
+	}
+	if configuration.ErrorDocument != nil {
+		w["error_document"] = configuration.ErrorDocument.Key
+	}
+
+	routingRules := make([]interface{}, len(configuration.RoutingRules))
+	for i, rule := range configuration.RoutingRules {
+		routingRules[i] = flattenWebsiteRoutingRule(rule)
+	}
+	w["routing_rule"] = routingRules
+
+	return w
+}
+
+// resourceBucketWebsitePut replaces the website configuration of the bucket,
+// as S3 has no way to change its parts separately.
+func resourceBucketWebsitePut(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	bucket := d.Get("bucket").(string)
+
+	client, bucketName, err := p.s3Bucket(ctx, bucket, d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = client.PutBucketWebsite(ctx, bucketName, expandWebsiteConfiguration(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(bucket)
+
+	return resourceBucketWebsiteRead(ctx, d, m)
+}
+
+func resourceBucketWebsiteRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	configuration, err := client.GetBucketWebsite(ctx, bucketName)
+	if isS3NotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	for key, value := range flattenWebsiteConfiguration(configuration).(map[string]interface{}) {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceBucketWebsiteDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.s3Bucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isBucketOrObjectNotFound(err) {
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = client.DeleteBucketWebsite(ctx, bucketName)
+	if err != nil && !isS3NotFound(err) {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
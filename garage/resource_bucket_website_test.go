@@ -0,0 +1,345 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// bucketWebsiteResourceConfig declares a website bucket owned by a key,
+// along with the website configuration signed with it.
+func bucketWebsiteResourceConfig(providerConfig string, alias string, quotaMaxObjects int, replaceKeyPrefixWith string) string {
+	return providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {
+  quota_max_objects = %d
+  manage_website    = false
+}
+
+resource "garage_bucket_global_alias" "test" {
+  bucket_id = garage_bucket.test.id
+  alias     = %q
+}
+
+resource "garage_key" "test" {
+  name = "settings"
+}
+
+resource "garage_bucket_key" "test" {
+  bucket_id     = garage_bucket.test.id
+  access_key_id = garage_key.test.access_key_id
+  read          = true
+  owner         = true
+}
+
+resource "garage_bucket_website" "test" {
+  bucket         = garage_bucket_global_alias.test.alias
+  access_key_id  = garage_bucket_key.test.access_key_id
+  index_document = "index.html"
+  error_document = "404.html"
+
+  routing_rule {
+    condition {
+      key_prefix_equals = "docs/"
+    }
+    redirect {
+      replace_key_prefix_with = %q
+      http_redirect_code      = 302
+    }
+  }
+
+  routing_rule {
+    condition {
+      http_error_code_returned_equals = 404
+    }
+    redirect {
+      host_name = "archive.example.com"
+      protocol  = "https"
+    }
+  }
+}
+`, quotaMaxObjects, alias, replaceKeyPrefixWith)
+}
+
+func TestUnitResourceBucketWebsite(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		checkReplaceKeyPrefixWith := func(prefix string) resource.TestCheckFunc {
+			return fake.withState(func() error {
+				for _, b := range fake.buckets {
+					if b.websiteRouting != nil && len(b.websiteRouting.RoutingRules) == 2 && *b.websiteRouting.RoutingRules[0].Redirect.ReplaceKeyPrefixWith == prefix {
+						return nil
+					}
+				}
+				return fmt.Errorf("no bucket redirects docs/ to %s", prefix)
+			})
+		}
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketWebsiteResourceConfig(fake.providerConfig(), "website", 1000, "documentation/"),
+					Check: resource.ComposeTestCheckFunc(
+						checkReplaceKeyPrefixWith("documentation/"),
+						resource.TestCheckResourceAttr("garage_bucket_website.test", "id", "website"),
+						resource.TestCheckResourceAttr("garage_bucket_website.test", "routing_rule.0.redirect.0.http_redirect_code", "302"),
+						resource.TestCheckResourceAttr("garage_bucket_website.test", "routing_rule.1.condition.0.http_error_code_returned_equals", "404"),
+					),
+				},
+				{
+					Config: bucketWebsiteResourceConfig(fake.providerConfig(), "website", 1000, "manual/"),
+					Check:  checkReplaceKeyPrefixWith("manual/"),
+				},
+				// Updating the bucket keeps the routing rules
+				{
+					Config: bucketWebsiteResourceConfig(fake.providerConfig(), "website", 2000, "manual/"),
+					Check: resource.ComposeTestCheckFunc(
+						checkReplaceKeyPrefixWith("manual/"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "website_access_enabled", "true"),
+						resource.TestCheckResourceAttr("garage_bucket.test", "website_config_index_document", "index.html"),
+					),
+				},
+				// Change out of band
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, b := range fake.buckets {
+							if b.websiteRouting != nil {
+								b.websiteRouting.RoutingRules = b.websiteRouting.RoutingRules[:1]
+							}
+						}
+					},
+					Config:             bucketWebsiteResourceConfig(fake.providerConfig(), "website", 2000, "manual/"),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: bucketWebsiteResourceConfig(fake.providerConfig(), "website", 2000, "manual/"),
+					Check:  checkReplaceKeyPrefixWith("manual/"),
+				},
+				{
+					ResourceName:      "garage_bucket_website.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+func TestUnitResourceBucketWebsiteRequiresGarage2(t *testing.T) {
+	unitTest(t, []int{1}, func(fake *fakeGarage) resource.TestCase {
+		fake.garageVersion = "v1.0.1"
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config:      bucketWebsiteResourceConfig(fake.providerConfig(), "website", 1000, "documentation/"),
+					ExpectError: regexp.MustCompile("website redirects and routing rules requires Garage 2.0.0"),
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceBucketWebsite(t *testing.T) {
+	alias := "tf-acc-" + randomHex(10)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		garage.skipWithoutS3(t)
+
+		var accessKeyID string
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: bucketWebsiteResourceConfig(garage.providerConfig, alias, 1000, "documentation/"),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						resource.TestCheckResourceAttr("garage_bucket_website.test", "routing_rule.#", "2"),
+						resource.TestCheckResourceAttr("garage_bucket_website.test", "routing_rule.0.redirect.0.replace_key_prefix_with", "documentation/"),
+						resource.TestCheckResourceAttr("garage_bucket_website.test", "routing_rule.1.redirect.0.host_name", "archive.example.com"),
+					),
+				},
+				// Updating the bucket keeps the routing rules
+				{
+					Config: bucketWebsiteResourceConfig(garage.providerConfig, alias, 2000, "documentation/"),
+					Check: func(*terraform.State) error {
+						client, bucketName, err := garage.provider.s3Bucket(context.Background(), alias, accessKeyID)
+						if err != nil {
+							return err
+						}
+						configuration, err := client.GetBucketWebsite(context.Background(), bucketName)
+						if err != nil {
+							return err
+						}
+						if len(configuration.RoutingRules) != 2 {
+							return fmt.Errorf("expected 2 routing rules, got %+v", configuration.RoutingRules)
+						}
+						return nil
+					},
+				},
+				{
+					Config: bucketWebsiteResourceConfig(garage.providerConfig, alias, 2000, "manual/"),
+					Check:  resource.TestCheckResourceAttr("garage_bucket_website.test", "routing_rule.0.redirect.0.replace_key_prefix_with", "manual/"),
+				},
+				{
+					ResourceName:            "garage_bucket_website.test",
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"access_key_id"},
+				},
+			},
+		}
+	})
+}
+
+// TestAccFeatureWebsiteRoutingRules checks that the Garage node acceptance
+// tests run against takes routing rules exactly when featureWebsiteRoutingRules
+// says it does.
+func TestAccFeatureWebsiteRoutingRules(t *testing.T) {
+	garage := newTestGarage(t)
+	garage.skipWithoutS3(t)
+	if garage.providerConfig != "" {
+		t.Skip("the fake node takes routing rules whatever its version")
+	}
+	ctx := context.Background()
+
+	bucketInfo, err := garage.client.CreateBucket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = garage.client.DeleteBucket(ctx, bucketInfo.ID)
+	})
+	key, err := garage.client.CreateKey(ctx, "tf-acc-"+randomHex(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = garage.client.DeleteKey(ctx, key.AccessKeyID)
+	})
+	if err := garage.client.GrantKey(ctx, bucketInfo.ID, key.AccessKeyID, bucketKeyPermissions{Read: true, Owner: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	client, bucketName, err := garage.provider.s3Bucket(ctx, bucketInfo.ID, key.AccessKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.PutBucketWebsite(ctx, bucketName, &websiteConfiguration{
+		IndexDocument: &websiteIndexDocument{Suffix: "index.html"},
+		RoutingRules: []websiteRoutingRule{{
+			Condition: &websiteRoutingCondition{KeyPrefixEquals: optionalString("docs/")},
+			Redirect:  websiteRedirect{ReplaceKeyPrefixWith: optionalString("manual/")},
+		}},
+	})
+	supported := garage.provider.supports(featureWebsiteRoutingRules)
+	if supported && err != nil {
+		t.Errorf("expected Garage %s to take routing rules, got %v", garage.provider.garageVersion, err)
+	}
+	if !supported && err == nil {
+		t.Errorf("expected Garage %s to refuse routing rules, before %s", garage.provider.garageVersion, featureWebsiteRoutingRules.since)
+	}
+}
+
+func TestResourceBucketWebsite(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "website")
+	ctx := context.Background()
+	endpoint, _ := url.Parse(fake.s3.URL)
+	p := &garageProvider{client: fake.client(), s3: &s3Config{endpoint: endpoint, region: defaultS3Region, pathStyle: true, httpClient: fake.s3.Client()}}
+
+	d := schema.TestResourceDataRaw(t, schemaBucketWebsite(), map[string]interface{}{
+		"bucket":         "website",
+		"access_key_id":  client.credentials.AccessKeyID,
+		"index_document": "index.html",
+		"error_document": "404.html",
+	})
+	if diags := resourceBucketWebsitePut(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if d.Id() != "website" || d.Get("index_document") != "index.html" || d.Get("error_document") != "404.html" || d.Get("routing_rule.#") != 0 {
+		t.Errorf("unexpected state %v", d.State())
+	}
+
+	d = schema.TestResourceDataRaw(t, schemaBucketWebsite(), map[string]interface{}{
+		"bucket":         "website",
+		"access_key_id":  client.credentials.AccessKeyID,
+		"index_document": "index.html",
+		"routing_rule": []interface{}{
+			map[string]interface{}{
+				"redirect": []interface{}{
+					map[string]interface{}{"replace_key_with": "index.html", "http_redirect_code": 200},
+				},
+			},
+		},
+	})
+	if diags := resourceBucketWebsitePut(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if d.Get("error_document") != "" || d.Get("routing_rule.0.condition.#") != 0 || d.Get("routing_rule.0.redirect.0.replace_key_with") != "index.html" || d.Get("routing_rule.0.redirect.0.http_redirect_code") != 200 {
+		t.Errorf("unexpected state %v", d.State())
+	}
+
+	if diags := resourceBucketWebsiteDelete(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceBucketWebsiteRead(ctx, d, p); diags.HasError() || d.Id() != "" {
+		t.Errorf("expected the website configuration to be gone, got %v, %v", d.Id(), diags)
+	}
+	bucketInfo, err := fake.client().FindBucket(ctx, "website")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucketInfo.WebsiteAccess {
+		t.Error("expected website access to be disabled")
+	}
+}
+
+func TestCustomizeDiffWebsiteRoutingRules(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		config        map[string]interface{}
+		garageVersion string
+		planError     string
+	}{
+		{"index", map[string]interface{}{"index_document": "index.html", "error_document": "404.html"}, "v1.0.0", ""},
+		{"no index", map[string]interface{}{"error_document": "404.html"}, "v2.0.0", `"index_document" is required`},
+		{"redirect all", map[string]interface{}{"index_document": "index.html", "routing_rule": []interface{}{map[string]interface{}{"redirect": []interface{}{map[string]interface{}{"host_name": "example.com"}}}}}, "v2.0.0", ""},
+		{"routing rule", map[string]interface{}{"index_document": "index.html", "routing_rule": []interface{}{map[string]interface{}{"redirect": []interface{}{map[string]interface{}{"replace_key_with": "index.html"}}}}}, "v2.0.0", ""},
+		{"routing rule on Garage 1", map[string]interface{}{"index_document": "index.html", "routing_rule": []interface{}{map[string]interface{}{"redirect": []interface{}{map[string]interface{}{"replace_key_with": "index.html"}}}}}, "v1.0.0", "requires Garage 2.0.0"},
+		{"replacements", map[string]interface{}{"index_document": "index.html", "routing_rule": []interface{}{map[string]interface{}{"redirect": []interface{}{map[string]interface{}{"replace_key_with": "index.html", "replace_key_prefix_with": "docs/"}}}}}, "v2.0.0", "conflict"},
+		{"rewrite to host", map[string]interface{}{"index_document": "index.html", "routing_rule": []interface{}{map[string]interface{}{"redirect": []interface{}{map[string]interface{}{"host_name": "example.com", "http_redirect_code": 200}}}}}, "v2.0.0", "not to host_name"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			raw := map[string]interface{}{"bucket": "website"}
+			for name, value := range c.config {
+				raw[name] = value
+			}
+			config := terraform.NewResourceConfigRaw(raw)
+			p := &garageProvider{garageVersion: version.Must(version.NewVersion(c.garageVersion))}
+
+			r := resourceBucketWebsite()
+			diags := r.Validate(config)
+			var err error
+			if diags.HasError() {
+				err = fmt.Errorf("%v", diags)
+			} else {
+				_, err = r.Diff(context.Background(), nil, config, p)
+			}
+			if c.planError == "" && err != nil {
+				t.Fatal(err)
+			}
+			if c.planError != "" && (err == nil || !strings.Contains(err.Error(), c.planError)) {
+				t.Fatalf("expected an error containing %q, got %v", c.planError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package garage
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const clusterLayoutID = "cluster_layout"
+
+func schemaClusterLayout() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"role": {
+			Description: "Role of a node in the cluster. Nodes without a role block are removed from the layout.",
+			Type:        schema.TypeSet,
+			Required:    true,
+			MinItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"node_id": {
+						Description: "The ID of the node.",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+					"zone": {
+						Description: "The zone the node is in.",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+					"capacity": {
+						Description: "The storage capacity of the node, in bytes. Nodes without capacity are gateways.",
+						Type:        schema.TypeInt,
+						Optional:    true,
+					},
+					"tags": {
+						Type: schema.TypeList,
+						Elem: &schema.Schema{
+							Type: schema.TypeString,
+						},
+						Optional: true,
+					},
+				},
+			},
+		},
+		// Computed
+		"version": {
+			Description: "The version of the applied cluster layout.",
+			Type:        schema.TypeInt,
+			Computed:    true,
+		},
+	}
+}
+
+func resourceClusterLayout() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to manage the layout of a Garage cluster, through the v1 or v2 admin API. Destroying it leaves the layout untouched.",
+		CreateContext: resourceClusterLayoutCreateOrUpdate,
+		ReadContext:   resourceClusterLayoutRead,
+		UpdateContext: resourceClusterLayoutCreateOrUpdate,
+		DeleteContext: resourceClusterLayoutDelete,
+		Schema:        schemaClusterLayout(),
+		CustomizeDiff: customizeDiffRequireAPIVersion("garage_cluster_layout", 1, featureAdminAPIv1),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+	}
+}
+
+func expandNodeRoles(roles *schema.Set) map[string]nodeRole {
+	nodeRoles := map[string]nodeRole{}
+	for _, role := range roles.List() {
+		role := role.(map[string]interface{})
+
+		nodeRole := nodeRole{
+			ID:   role["node_id"].(string),
+			Zone: role["zone"].(string),
+			Tags: []string{},
+		}
+		if capacity := int64(role["capacity"].(int)); capacity > 0 {
+			nodeRole.Capacity = &capacity
+		}
+		for _, tag := range role["tags"].([]interface{}) {
+			nodeRole.Tags = append(nodeRole.Tags, tag.(string))
+		}
+
+		nodeRoles[nodeRole.ID] = nodeRole
+	}
+	return nodeRoles
+}
+
+func flattenNodeRole(role nodeRole) interface{} {
+	capacity := 0
+	if role.Capacity != nil {
+		capacity = int(*role.Capacity)
+	}
+	return map[string]interface{}{
+		"node_id":  role.ID,
+		"zone":     role.Zone,
+		"capacity": capacity,
+		"tags":     role.Tags,
+	}
+}
+
+func nodeRoleEqual(a, b nodeRole) bool {
+	if a.Zone != b.Zone || !equalInt64Ptr(a.Capacity, b.Capacity) {
+		return false
+	}
+	return len(a.Tags) == len(b.Tags) && (len(a.Tags) == 0 || reflect.DeepEqual(a.Tags, b.Tags))
+}
+
+// clusterLayoutChanges returns the role changes turning current into
+// desired.
+func clusterLayoutChanges(current []nodeRole, desired map[string]nodeRole) []nodeRoleChange {
+	var changes []nodeRoleChange
+
+	currentRoles := map[string]nodeRole{}
+	for _, role := range current {
+		currentRoles[role.ID] = role
+		if _, ok := desired[role.ID]; !ok {
+			changes = append(changes, nodeRoleChange{
+				ID:     role.ID,
+				Remove: true,
+			})
+		}
+	}
+
+	for id, role := range desired {
+		if currentRole, ok := currentRoles[id]; ok && nodeRoleEqual(currentRole, role) {
+			continue
+		}
+		changes = append(changes, nodeRoleChange{
+			ID:       id,
+			Zone:     role.Zone,
+			Capacity: role.Capacity,
+			Tags:     role.Tags,
+		})
+	}
+
+	return changes
+}
+
+func resourceClusterLayoutCreateOrUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	layout, err := p.client.GetClusterLayout(ctx)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Applying the layout would also apply changes staged outside of
+	// Terraform.
+	if len(layout.StagedRoleChanges) > 0 {
+		return diag.Errorf("the cluster layout has %d staged role changes: apply or revert them before managing the layout with Terraform", len(layout.StagedRoleChanges))
+	}
+
+	changes := clusterLayoutChanges(layout.Roles, expandNodeRoles(d.Get("role").(*schema.Set)))
+	if len(changes) > 0 {
+		_, err = p.client.StageClusterLayoutChanges(ctx, changes)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		_, err = p.client.ApplyClusterLayout(ctx, layout.Version+1)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(clusterLayoutID)
+
+	return resourceClusterLayoutRead(ctx, d, m)
+}
+
+func resourceClusterLayoutRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	layout, err := p.client.GetClusterLayout(ctx)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	roles := make([]interface{}, len(layout.Roles))
+	for i, role := range layout.Roles {
+		roles[i] = flattenNodeRole(role)
+	}
+
+	err = d.Set("role", roles)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = d.Set("version", layout.Version)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceClusterLayoutDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	// Noop: removing every role of the cluster would make its data
+	// unavailable.
+	return diags
+}
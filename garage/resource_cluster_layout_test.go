@@ -0,0 +1,54 @@
+package garage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func testClusterLayoutConfig(fake *fakeGarage, zone string) string {
+	return fake.providerConfig() + fmt.Sprintf(`
+resource "garage_cluster_layout" "test" {
+  role {
+    node_id  = %q
+    zone     = %q
+    capacity = 2147483648
+    tags     = ["test"]
+  }
+}
+`, fake.nodeID, zone)
+}
+
+func TestUnitResourceClusterLayout(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: testClusterLayoutConfig(fake, "dc1"),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_cluster_layout.test", "version", "2"),
+						resource.TestCheckResourceAttr("garage_cluster_layout.test", "role.#", "1"),
+					),
+				},
+				{
+					Config: testClusterLayoutConfig(fake, "dc2"),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_cluster_layout.test", "version", "3"),
+						fake.withState(func() error {
+							if zone := fake.layout.Roles[0].Zone; zone != "dc2" {
+								return fmt.Errorf("expected the node to move to dc2, got %s", zone)
+							}
+							return nil
+						}),
+					),
+				},
+				{
+					ResourceName:      "garage_cluster_layout.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
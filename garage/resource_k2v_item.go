@@ -0,0 +1,211 @@
+package garage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// k2vValueAttributes are the attributes the value of an item is set from, of
+// which exactly one is set.
+var k2vValueAttributes = []string{"value", "value_base64"}
+
+func schemaK2VItem() map[string]*schema.Schema {
+	return withK2VBucket(map[string]*schema.Schema{
+		"partition_key": {
+			Description:  "Key of the partition of the item.",
+			Type:         schema.TypeString,
+			Required:     true,
+			ForceNew:     true,
+			ValidateFunc: validation.StringIsNotEmpty,
+		},
+		"sort_key": {
+			Description: "Key of the item in its partition.",
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+		},
+		"value": {
+			Description:  "Value of the item, as UTF-8 text.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			ExactlyOneOf: k2vValueAttributes,
+		},
+		"value_base64": {
+			Description:  "Value of the item, base64-encoded, for binary values.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			ExactlyOneOf: k2vValueAttributes,
+			ValidateFunc: validation.StringIsBase64,
+		},
+		// Computed
+		"causality_token": {
+			Description: "Causality token of the item when it was last read. Updates and deletion only supersede the values it was read along with, so values written concurrently outside of Terraform are kept, and show as changes.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	})
+}
+
+func resourceK2VItem() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to manage an item of the K2V API of Garage, the key-value store of its buckets. The signing key must be allowed to read and write to the bucket. An existing item is overwritten on creation.",
+		CreateContext: resourceK2VItemCreate,
+		ReadContext:   resourceK2VItemRead,
+		UpdateContext: resourceK2VItemUpdate,
+		DeleteContext: resourceK2VItemDelete,
+		Schema:        schemaK2VItem(),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importK2VStateFromID("bucket", "partition_key", "sort_key"),
+		},
+	}
+}
+
+// k2vItemValue returns the value of the item set in the configuration.
+func k2vItemValue(d *schema.ResourceData) ([]byte, error) {
+	if valueBase64, ok := d.GetOk("value_base64"); ok {
+		return base64.StdEncoding.DecodeString(valueBase64.(string))
+	}
+	return []byte(d.Get("value").(string)), nil
+}
+
+// resourceK2VItemWrite writes the configured value to the item, superseding
+// the values causalityToken was read along with, or every value of the item
+// when overwrite is set.
+func resourceK2VItemWrite(ctx context.Context, d *schema.ResourceData, m interface{}, causalityToken string, overwrite bool) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	client, bucketName, err := p.k2vBucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	value, err := k2vItemValue(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	partitionKey := d.Get("partition_key").(string)
+	sortKey := d.Get("sort_key").(string)
+	if overwrite {
+		item, err := client.ReadItem(ctx, bucketName, partitionKey, sortKey)
+		if err != nil && !isK2VNotFound(err) {
+			return diag.FromErr(err)
+		}
+		if err == nil {
+			causalityToken = item.CausalityToken
+		}
+	}
+
+	err = client.InsertItem(ctx, bucketName, partitionKey, sortKey, causalityToken, value)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%s", d.Get("bucket").(string), partitionKey, sortKey))
+
+	return resourceK2VItemRead(ctx, d, m)
+}
+
+func resourceK2VItemCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return resourceK2VItemWrite(ctx, d, m, "", true)
+}
+
+func resourceK2VItemUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return resourceK2VItemWrite(ctx, d, m, d.Get("causality_token").(string), false)
+}
+
+func resourceK2VItemRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.k2vBucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isNotFound(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	partitionKey := d.Get("partition_key").(string)
+	sortKey := d.Get("sort_key").(string)
+	item, err := client.ReadItem(ctx, bucketName, partitionKey, sortKey)
+	if isK2VNotFound(err) || (err == nil && len(item.Values) == 0) {
+		// Deleted outside of Terraform
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Among concurrent values, the one differing from the state is shown,
+	// so that applying the configured value again replaces them all.
+	known, _ := k2vItemValue(d)
+	value := item.Values[0]
+	for _, v := range item.Values {
+		if !bytes.Equal(v, known) {
+			value = v
+			break
+		}
+	}
+	if len(item.Values) > 1 {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "K2V item has concurrent values",
+			Detail:   fmt.Sprintf("Item %q of partition %q has %d values written concurrently. Applying replaces them with the configured value.", sortKey, partitionKey, len(item.Values)),
+		})
+	}
+
+	values := map[string]interface{}{
+		"value":           "",
+		"value_base64":    "",
+		"causality_token": item.CausalityToken,
+	}
+	if _, ok := d.GetOk("value_base64"); ok || !utf8.Valid(value) {
+		values["value_base64"] = base64.StdEncoding.EncodeToString(value)
+	} else {
+		values["value"] = string(value)
+	}
+	for key, value := range values {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceK2VItemDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	client, bucketName, err := p.k2vBucket(ctx, d.Get("bucket").(string), d.Get("access_key_id").(string))
+	if isNotFound(err) {
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = client.DeleteItem(ctx, bucketName, d.Get("partition_key").(string), d.Get("sort_key").(string), d.Get("causality_token").(string))
+	if err != nil && !isK2VNotFound(err) {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
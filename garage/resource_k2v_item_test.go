@@ -0,0 +1,205 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// k2vBucketConfig declares a bucket with a global alias and a key allowed to
+// read and write to it, along with the K2V resources and data sources.
+func k2vBucketConfig(providerConfig string, alias string, items string) string {
+	return providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {}
+
+resource "garage_bucket_global_alias" "test" {
+  bucket_id = garage_bucket.test.id
+  alias     = %q
+}
+
+resource "garage_key" "test" {
+  name = "k2v"
+}
+
+resource "garage_bucket_key" "test" {
+  bucket_id     = garage_bucket.test.id
+  access_key_id = garage_key.test.access_key_id
+  read          = true
+  write         = true
+}
+`, alias) + items
+}
+
+func k2vItemConfig(value string) string {
+	return fmt.Sprintf(`
+resource "garage_k2v_item" "test" {
+  bucket        = garage_bucket_global_alias.test.alias
+  access_key_id = garage_bucket_key.test.access_key_id
+  partition_key = "users"
+  sort_key      = "alice"
+  value         = %q
+}
+`, value)
+}
+
+func TestUnitResourceK2VItem(t *testing.T) {
+	unitTest(t, []int{1, 2}, func(fake *fakeGarage) resource.TestCase {
+		checkValue := func(value string) resource.TestCheckFunc {
+			return fake.withState(func() error {
+				for _, b := range fake.buckets {
+					if item := b.k2v["users"]["alice"]; item != nil && len(item.values) == 1 && string(item.values[0].value) == value {
+						return nil
+					}
+				}
+				return fmt.Errorf("no item users/alice with value %q", value)
+			})
+		}
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: k2vBucketConfig(fake.providerConfig(), "app", k2vItemConfig(`{"role":"admin"}`)),
+					Check: resource.ComposeTestCheckFunc(
+						checkValue(`{"role":"admin"}`),
+						resource.TestCheckResourceAttr("garage_k2v_item.test", "id", "app/users/alice"),
+						resource.TestCheckResourceAttrSet("garage_k2v_item.test", "causality_token"),
+					),
+				},
+				{
+					Config: k2vBucketConfig(fake.providerConfig(), "app", k2vItemConfig(`{"role":"viewer"}`)),
+					Check:  checkValue(`{"role":"viewer"}`),
+				},
+				// Concurrent write out of band
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, b := range fake.buckets {
+							if item := b.k2v["users"]["alice"]; item != nil {
+								_ = item.write("", []byte(`{"role":"owner"}`))
+							}
+						}
+					},
+					Config:             k2vBucketConfig(fake.providerConfig(), "app", k2vItemConfig(`{"role":"viewer"}`)),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: k2vBucketConfig(fake.providerConfig(), "app", k2vItemConfig(`{"role":"viewer"}`)),
+					Check:  checkValue(`{"role":"viewer"}`),
+				},
+				{
+					ResourceName:      "garage_k2v_item.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceK2VItem(t *testing.T) {
+	alias := "tf-acc-" + randomHex(10)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		garage.skipWithoutK2V(t)
+
+		var accessKeyID string
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: k2vBucketConfig(garage.providerConfig, alias, k2vItemConfig("v1")),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						resource.TestCheckResourceAttr("garage_k2v_item.test", "value", "v1"),
+					),
+				},
+				// Deletion out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, _ garageClient) error {
+						client, bucketName, err := garage.provider.k2vBucket(ctx, alias, accessKeyID)
+						if err != nil {
+							return err
+						}
+						item, err := client.ReadItem(ctx, bucketName, "users", "alice")
+						if err != nil {
+							return err
+						}
+						return client.DeleteItem(ctx, bucketName, "users", "alice", item.CausalityToken)
+					}),
+					Config:             k2vBucketConfig(garage.providerConfig, alias, k2vItemConfig("v1")),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: k2vBucketConfig(garage.providerConfig, alias, k2vItemConfig("v2")),
+					Check:  resource.TestCheckResourceAttr("garage_k2v_item.test", "value", "v2"),
+				},
+				{
+					ResourceName:      "garage_k2v_item.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+func TestResourceK2VItem(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeK2VBucket(t, fake, "app")
+	ctx := context.Background()
+	endpoint, _ := url.Parse(fake.k2v.URL)
+	p := &garageProvider{client: fake.client(), k2v: &k2vConfig{endpoint: endpoint, region: defaultS3Region, httpClient: fake.k2v.Client()}}
+
+	// An item written by a script, with concurrent values
+	for _, value := range []string{"script 1", "script 2"} {
+		if err := client.InsertItem(ctx, "app", "config", "flags", "", []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := schema.TestResourceDataRaw(t, schemaK2VItem(), map[string]interface{}{
+		"bucket":        "app",
+		"access_key_id": client.credentials.AccessKeyID,
+		"partition_key": "config",
+		"sort_key":      "flags",
+		"value_base64":  "AAEC",
+	})
+	if diags := resourceK2VItemCreate(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	item, err := client.ReadItem(ctx, "app", "config", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(item.Values) != 1 || string(item.Values[0]) != "\x00\x01\x02" {
+		t.Errorf("expected the existing values to be overwritten, got %q", item.Values)
+	}
+	if d.Id() != "app/config/flags" || d.Get("value_base64") != "AAEC" || d.Get("value") != "" || d.Get("causality_token") != item.CausalityToken {
+		t.Errorf("unexpected state %v", d.State())
+	}
+
+	// A concurrent value shows as a change, and a warning
+	if err := client.InsertItem(ctx, "app", "config", "flags", "", []byte("script 3")); err != nil {
+		t.Fatal(err)
+	}
+	diags := resourceK2VItemRead(ctx, d, p)
+	if diags.HasError() || len(diags) != 1 {
+		t.Fatalf("expected a warning, got %v", diags)
+	}
+	if d.Get("value_base64") != "c2NyaXB0IDM=" {
+		t.Errorf("expected the concurrent value to be read, got %v", d.Get("value_base64"))
+	}
+
+	if diags := resourceK2VItemDelete(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceK2VItemRead(ctx, d, p); diags.HasError() || d.Id() != "" {
+		t.Errorf("expected the item to be gone, got %v, %v", d.Id(), diags)
+	}
+}
@@ -2,12 +2,24 @@ package garage
 
 import (
 	"context"
+	"fmt"
+	"time"
 
-	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
+// keyExpirationWarningPeriod is how long before their expiration keys are
+// warned about.
+const keyExpirationWarningPeriod = 7 * 24 * time.Hour
+
+// keyExpirationNow is the clock expirations are warned about against, which
+// tests move.
+var keyExpirationNow = time.Now
+
 func schemaKey() map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		// Properties
@@ -40,7 +52,27 @@ func schemaKey() map[string]*schema.Schema {
 				"create_bucket": false,
 			},
 		},
+		"expiration": {
+			Description:      "The expiration date of the key, in RFC 3339 format. The key never expires when it is unset. Requires Garage 2.0 and the v2 admin API.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validateKeyExpiration,
+			DiffSuppressFunc: suppressSameInstant,
+			ConflictsWith:    []string{"never_expires"},
+		},
+		"never_expires": {
+			Description:   "Remove the expiration date of the key, as leaving `expiration` unset does.",
+			Type:          schema.TypeBool,
+			Optional:      true,
+			Default:       false,
+			ConflictsWith: []string{"expiration"},
+		},
 		// Computed
+		"expired": {
+			Description: "Whether the key has expired.",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
 		// TODO: buckets
 	}
 }
@@ -53,106 +85,230 @@ func resourceKey() *schema.Resource {
 		UpdateContext: resourceKeyUpdate,
 		DeleteContext: resourceKeyDelete,
 		Schema:        schemaKey(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffRequireAdminAPI,
+			customizeDiffKeyExpiration,
+		),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
 	}
 }
 
-func flattenKeyInfo(keyInfo *garage.KeyInfo) interface{} {
+// customizeDiffKeyExpiration fails the plan of keys with an expiration when
+// the provider or the connected Garage doesn't support it.
+func customizeDiffKeyExpiration(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	p, _ := m.(*garageProvider)
+	_, hasExpiration := d.GetOk("expiration")
+	neverExpires := d.Get("never_expires").(bool)
+	if !hasExpiration && !neverExpires {
+		return nil
+	}
+
+	if p != nil && p.apiVersion < 2 {
+		return fmt.Errorf("key expiration requires the v2 admin API, but the provider uses v%d: set admin_api_version to v2", p.apiVersion)
+	}
+	return p.requireFeature(featureKeyExpiration)
+}
+
+// suppressSameInstant suppresses the diff of RFC 3339 dates denoting the same
+// instant, as Garage answers them in UTC.
+func suppressSameInstant(k, old, new string, d *schema.ResourceData) bool {
+	return sameInstant(old, new)
+}
+
+// sameInstant reports whether a and b are the same RFC 3339 date, or the same
+// string when either isn't one.
+func sameInstant(a string, b string) bool {
+	aTime, aErr := time.Parse(time.RFC3339, a)
+	bTime, bErr := time.Parse(time.RFC3339, b)
+	if aErr != nil || bErr != nil {
+		return a == b
+	}
+	return aTime.Equal(bTime)
+}
+
+func flattenKeyInfo(keyInfo *accessKey) interface{} {
+	expiration := ""
+	if keyInfo.Expiration != nil {
+		expiration = *keyInfo.Expiration
+	}
 	return map[string]interface{}{
 		"name":              keyInfo.Name,
-		"access_key_id":     keyInfo.AccessKeyId,
+		"access_key_id":     keyInfo.AccessKeyID,
 		"secret_access_key": keyInfo.SecretAccessKey,
 		"permissions": map[string]interface{}{
 			"create_bucket": keyInfo.Permissions.CreateBucket,
 		},
+		"expiration": expiration,
+		"expired":    keyInfo.Expired,
+	}
+}
+
+// expandKeyExpiration sets the expiration of the key on update, removing it
+// when it was unset.
+func expandKeyExpiration(d *schema.ResourceData, update *keyUpdate) {
+	if expiration, ok := d.GetOk("expiration"); ok {
+		expiration := expiration.(string)
+		update.Expiration = &expiration
+		return
+	}
+	if d.Get("never_expires").(bool) || d.HasChange("expiration") {
+		update.NeverExpires = true
+	}
+}
+
+// expirationWarning warns about the key, named by subject, when its
+// expiration is within keyExpirationWarningPeriod of now, or past.
+func expirationWarning(subject string, expiration string, expired bool, now time.Time) diag.Diagnostics {
+	expirationTime, err := time.Parse(time.RFC3339, expiration)
+	if err != nil {
+		return nil
+	}
+
+	if expired || !expirationTime.After(now) {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Key has expired",
+			Detail:   fmt.Sprintf("%s expired on %s and can't be used anymore. Set a later expiration, or never_expires, to use it again.", subject, expiration),
+		}}
+	}
+	if expirationTime.Sub(now) < keyExpirationWarningPeriod {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Key expires soon",
+			Detail:   fmt.Sprintf("%s expires on %s, in %s.", subject, expiration, expirationTime.Sub(now).Round(time.Minute)),
+		}}
+	}
+	return nil
+}
+
+// keyExpirationWarning warns about keys expiring within
+// keyExpirationWarningPeriod of now, or expired.
+func keyExpirationWarning(keyInfo *accessKey, now time.Time) diag.Diagnostics {
+	if keyInfo.Expiration == nil {
+		return nil
+	}
+	return expirationWarning("Key "+keyInfo.AccessKeyID, *keyInfo.Expiration, keyInfo.Expired, now)
+}
+
+// validateKeyExpiration is a ValidateDiagFunc requiring RFC 3339 dates, which
+// warns about expirations the key would reach soon, or already be past, once
+// applied, as CustomizeDiff can't warn.
+func validateKeyExpiration(v interface{}, path cty.Path) diag.Diagnostics {
+	diags := validation.ToDiagFunc(validation.IsRFC3339Time)(v, path)
+	if diags.HasError() {
+		return diags
+	}
+
+	for _, warning := range expirationWarning("The key", v.(string), false, keyExpirationNow()) {
+		warning.AttributePath = path
+		diags = append(diags, warning)
+	}
+	return diags
+}
+
+// expandKeyPermissions returns the update allowing and denying the
+// permissions of the key.
+func expandKeyPermissions(permissions map[string]interface{}) keyUpdate {
+	createBucket := permissions["create_bucket"].(bool)
+
+	return keyUpdate{
+		Allow: &keyPermissions{
+			CreateBucket: createBucket,
+		},
+		Deny: &keyPermissions{
+			CreateBucket: !createBucket,
+		},
 	}
 }
 
 func resourceKeyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	p := m.(*garageProvider)
-	var diags diag.Diagnostics
 
-	var name *string
-	name = nil
+	name := ""
 	accessKeyID := ""
 	secretAccessKey := ""
 
 	if nameVal, ok := d.GetOk("name"); ok {
-		nameVal := nameVal.(string)
-		name = &nameVal
+		name = nameVal.(string)
 	}
 
 	if accessKeyIDVal, ok := d.GetOk("access_key_id"); ok {
 		accessKeyID = accessKeyIDVal.(string)
 	}
 
-	if secretAccessKeyVal, ok := d.GetOk("access_key_id"); ok {
+	if secretAccessKeyVal, ok := d.GetOk("secret_access_key"); ok {
 		secretAccessKey = secretAccessKeyVal.(string)
 	}
 
-	var keyInfo *garage.KeyInfo
+	var keyInfo *accessKey
+	var err error
 
 	if accessKeyID != "" || secretAccessKey != "" {
-		importKeyRequest := *garage.NewImportKeyRequest(*name, accessKeyID, secretAccessKey)
-		resp, _, err := p.client.KeyApi.ImportKey(updateContext(ctx, p)).ImportKeyRequest(importKeyRequest).Execute()
-		if err != nil {
-			return diag.FromErr(err)
-		}
-		keyInfo = resp
+		keyInfo, err = p.client.ImportKey(ctx, name, accessKeyID, secretAccessKey)
 	} else {
-		addKeyRequest := *garage.NewAddKeyRequest()
-		addKeyRequest.Name = name
-		resp, _, err := p.client.KeyApi.AddKey(updateContext(ctx, p)).AddKeyRequest(addKeyRequest).Execute()
-		if err != nil {
-			return diag.FromErr(err)
-		}
-		keyInfo = resp
+		keyInfo, err = p.client.CreateKey(ctx, name)
+	}
+	if err != nil {
+		return diag.FromErr(err)
 	}
 
-	d.SetId(*keyInfo.AccessKeyId)
-
-	if permissions, ok := d.GetOk("permissions"); ok {
-		permissions := permissions.(map[string]interface{})
+	d.SetId(keyInfo.AccessKeyID)
 
-		allowCreateBucket := permissions["create_bucket"].(bool)
-		denyCreateBucket := !permissions["create_bucket"].(bool)
+	keyInfo, err = waitForKey(ctx, p, d.Id(), keyExists)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
-		allow := garage.UpdateKeyRequestAllow{
-			CreateBucket: &allowCreateBucket,
-		}
-		deny := garage.UpdateKeyRequestDeny{
-			CreateBucket: &denyCreateBucket,
-		}
+	update := keyUpdate{}
+	if permissions, ok := d.GetOk("permissions"); ok {
+		update = expandKeyPermissions(permissions.(map[string]interface{}))
+	}
+	expandKeyExpiration(d, &update)
 
-		updateKeyRequest := garage.UpdateKeyRequest{
-			Allow: &allow,
-			Deny:  &deny,
+	if update != (keyUpdate{}) {
+		_, err := p.client.UpdateKey(ctx, d.Id(), update)
+		if err != nil {
+			return diag.FromErr(err)
 		}
 
-		_, _, err := p.client.KeyApi.UpdateKey(updateContext(ctx, p), d.Id()).UpdateKeyRequest(updateKeyRequest).Execute()
+		keyInfo, err = waitForKey(ctx, p, d.Id(), keyUpdateApplied(update))
 		if err != nil {
 			return diag.FromErr(err)
 		}
 	}
 
-	diags = resourceKeyRead(ctx, d, m)
-
-	return diags
+	return setKeyInfo(d, keyInfo)
 }
 
 func resourceKeyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	p := m.(*garageProvider)
-	var diags diag.Diagnostics
 
 	accessKeyID := d.Id()
 
-	keyInfo, _, err := p.client.KeyApi.GetKey(updateContext(ctx, p), accessKeyID).Execute()
+	keyInfo, err := p.client.GetKey(ctx, accessKeyID)
+	if isNotFound(err) {
+		// Deleted outside of Terraform
+		d.SetId("")
+		return nil
+	}
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
+	return append(setKeyInfo(d, keyInfo), keyExpirationWarning(keyInfo, keyExpirationNow())...)
+}
+
+func setKeyInfo(d *schema.ResourceData, keyInfo *accessKey) diag.Diagnostics {
+	var diags diag.Diagnostics
+
 	for key, value := range flattenKeyInfo(keyInfo).(map[string]interface{}) {
 		err := d.Set(key, value)
 		if err != nil {
@@ -165,48 +321,31 @@ func resourceKeyRead(ctx context.Context, d *schema.ResourceData, m interface{})
 
 func resourceKeyUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	p := m.(*garageProvider)
-	var diags diag.Diagnostics
 
-	var name *string
-	name = nil
-	var allow *garage.UpdateKeyRequestAllow
-	allow = nil
-	var deny *garage.UpdateKeyRequestDeny
-	deny = nil
+	update := keyUpdate{}
+
+	if permissions, ok := d.GetOk("permissions"); ok {
+		update = expandKeyPermissions(permissions.(map[string]interface{}))
+	}
 
 	if nameVal, ok := d.GetOk("name"); ok {
 		nameVal := nameVal.(string)
-		name = &nameVal
+		update.Name = &nameVal
 	}
 
-	if permissions, ok := d.GetOk("permissions"); ok {
-		permissions := permissions.(map[string]interface{})
-
-		allowCreateBucket := permissions["create_bucket"].(bool)
-		denyCreateBucket := !permissions["create_bucket"].(bool)
+	expandKeyExpiration(d, &update)
 
-		allow = &garage.UpdateKeyRequestAllow{
-			CreateBucket: &allowCreateBucket,
-		}
-		deny = &garage.UpdateKeyRequestDeny{
-			CreateBucket: &denyCreateBucket,
-		}
-	}
-
-	updateKeyRequest := garage.UpdateKeyRequest{
-		Name:  name,
-		Allow: allow,
-		Deny:  deny,
+	_, err := p.client.UpdateKey(ctx, d.Id(), update)
+	if err != nil {
+		return diag.FromErr(err)
 	}
 
-	_, _, err := p.client.KeyApi.UpdateKey(updateContext(ctx, p), d.Id()).UpdateKeyRequest(updateKeyRequest).Execute()
+	keyInfo, err := waitForKey(ctx, p, d.Id(), keyUpdateApplied(update))
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	diags = resourceKeyRead(ctx, d, m)
-
-	return diags
+	return setKeyInfo(d, keyInfo)
 }
 
 func resourceKeyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -215,7 +354,7 @@ func resourceKeyDelete(ctx context.Context, d *schema.ResourceData, m interface{
 
 	accessKeyID := d.Id()
 
-	_, err := p.client.KeyApi.DeleteKey(updateContext(ctx, p), accessKeyID).Execute()
+	err := p.client.DeleteKey(ctx, accessKeyID)
 	if err != nil {
 		return diag.FromErr(err)
 	}
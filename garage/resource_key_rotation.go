@@ -0,0 +1,477 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// keyRotationNameFormat suffixes the name of rotated keys with their creation
+// date, telling them apart in Garage.
+const keyRotationNameFormat = "20060102T150405Z"
+
+// keyRotationNow returns the time periods of key rotations are measured
+// against, which tests move forward instead of waiting.
+var keyRotationNow = time.Now
+
+// keyRotationComputed are the attributes a rotation changes.
+var keyRotationComputed = []string{
+	"access_key_id",
+	"secret_access_key",
+	"previous_access_key_id",
+	"previous_secret_access_key",
+	"rotated_at",
+	"rotates_at",
+}
+
+func schemaKeyRotation() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"name": {
+			Description:  "Logical name of the key. The keys created are named after it, suffixed with their creation date.",
+			Type:         schema.TypeString,
+			Required:     true,
+			ForceNew:     true,
+			ValidateFunc: validation.StringIsNotEmpty,
+		},
+		"rotation_period": {
+			Description:  "How long a key is used before a new one replaces it, as a duration such as `720h`. The rotation happens on the first apply after the period has elapsed.",
+			Type:         schema.TypeString,
+			Required:     true,
+			ValidateFunc: validateDuration,
+		},
+		"overlap_period": {
+			Description:  "How long the previous key is kept after a rotation, shorter than `rotation_period`, so that consumers can switch to the new one. It is deleted on the first apply after the period has elapsed.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "24h",
+			ValidateFunc: validateDuration,
+		},
+		"bucket": {
+			Description: "Bucket granted to the current key, and to every key replacing it.",
+			Type:        schema.TypeSet,
+			Optional:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"bucket_id": {
+						Description: "ID of the bucket.",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+					"read": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  false,
+					},
+					"write": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  false,
+					},
+					"owner": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  false,
+					},
+				},
+			},
+		},
+		// Computed
+		"access_key_id": {
+			Description: "The access key ID of the current key.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"secret_access_key": {
+			Description: "The secret access key of the current key.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"previous_access_key_id": {
+			Description: "The access key ID of the previous key during the overlap period, empty otherwise.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"previous_secret_access_key": {
+			Description: "The secret access key of the previous key during the overlap period, empty otherwise.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"rotated_at": {
+			Description: "The creation date of the current key, in RFC 3339 format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"rotates_at": {
+			Description: "The date from which the next apply rotates the key, in RFC 3339 format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+}
+
+func resourceKeyRotation() *schema.Resource {
+	return &schema.Resource{
+		Description:   "This resource can be used to rotate a Garage key periodically. Each `rotation_period`, the next apply creates a new key with the same bucket grants, keeps the previous key during `overlap_period` so that both sets of credentials work, and then deletes it. Grant the buckets through `bucket` blocks rather than `garage_bucket_key` resources following `access_key_id`, which would revoke the previous key on rotation.",
+		CreateContext: resourceKeyRotationCreate,
+		ReadContext:   resourceKeyRotationRead,
+		UpdateContext: resourceKeyRotationUpdate,
+		DeleteContext: resourceKeyRotationDelete,
+		Schema:        schemaKeyRotation(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffRequireAdminAPI,
+			customizeDiffKeyRotation,
+		),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultCreateTimeout),
+			Read:   schema.DefaultTimeout(defaultReadTimeout),
+			Update: schema.DefaultTimeout(defaultUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultDeleteTimeout),
+		},
+	}
+}
+
+// keyRotationPeriods returns the rotation and overlap periods, already
+// validated by the schema.
+func keyRotationPeriods(d interface{ Get(string) interface{} }) (time.Duration, time.Duration) {
+	rotation, _ := time.ParseDuration(d.Get("rotation_period").(string))
+	overlap, _ := time.ParseDuration(d.Get("overlap_period").(string))
+	return rotation, overlap
+}
+
+// customizeDiffKeyRotation plans the rotation of the key once rotation_period
+// has elapsed since the last one, and the deletion of the previous key once
+// overlap_period has.
+func customizeDiffKeyRotation(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("rotation_period") || !d.NewValueKnown("overlap_period") {
+		return nil
+	}
+	rotation, overlap := keyRotationPeriods(d)
+	if overlap >= rotation {
+		return fmt.Errorf("overlap_period (%s) must be shorter than rotation_period (%s)", overlap, rotation)
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+	rotatedAt, err := time.Parse(time.RFC3339, d.Get("rotated_at").(string))
+	if err != nil {
+		return nil
+	}
+	now := keyRotationNow()
+
+	if !now.Before(rotatedAt.Add(rotation)) {
+		for _, attribute := range keyRotationComputed {
+			if err := d.SetNewComputed(attribute); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if d.HasChange("rotation_period") {
+		if err := d.SetNew("rotates_at", rotatedAt.Add(rotation).UTC().Format(time.RFC3339)); err != nil {
+			return err
+		}
+	}
+	if d.Get("previous_access_key_id").(string) != "" && !now.Before(rotatedAt.Add(overlap)) {
+		if err := d.SetNew("previous_access_key_id", ""); err != nil {
+			return err
+		}
+		return d.SetNew("previous_secret_access_key", "")
+	}
+	return nil
+}
+
+// expandKeyRotationGrants returns the permissions of the bucket blocks by
+// bucket ID.
+func expandKeyRotationGrants(buckets *schema.Set) map[string]bucketKeyPermissions {
+	grants := map[string]bucketKeyPermissions{}
+	for _, bucket := range buckets.List() {
+		bucket := bucket.(map[string]interface{})
+		grants[bucket["bucket_id"].(string)] = bucketKeyPermissions{
+			Read:  bucket["read"].(bool),
+			Write: bucket["write"].(bool),
+			Owner: bucket["owner"].(bool),
+		}
+	}
+	return grants
+}
+
+// grantKeyRotationBuckets gives the key exactly the permissions of grants on
+// their buckets, and none on the revoked ones.
+func grantKeyRotationBuckets(ctx context.Context, p *garageProvider, accessKeyID string, grants map[string]bucketKeyPermissions, revoked []string) error {
+	for bucketID, permissions := range grants {
+		err := p.client.GrantKey(ctx, bucketID, accessKeyID, permissions)
+		if err != nil {
+			return err
+		}
+		err = p.client.RevokeKey(ctx, bucketID, accessKeyID, bucketKeyPermissions{
+			Read:  !permissions.Read,
+			Write: !permissions.Write,
+			Owner: !permissions.Owner,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, bucketID := range revoked {
+		err := p.client.RevokeKey(ctx, bucketID, accessKeyID, bucketKeyPermissions{Read: true, Write: true, Owner: true})
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// createRotatedKey creates a key granted the buckets of the configuration.
+// The key is deleted when it can't be granted them, as it isn't recorded in
+// the state yet.
+func createRotatedKey(ctx context.Context, d *schema.ResourceData, p *garageProvider, now time.Time) (*accessKey, error) {
+	name := fmt.Sprintf("%s-%s", d.Get("name").(string), now.UTC().Format(keyRotationNameFormat))
+	created, err := p.client.CreateKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo, err := waitForKey(ctx, p, created.AccessKeyID, keyExists)
+	if err == nil {
+		err = grantKeyRotationBuckets(ctx, p, keyInfo.AccessKeyID, expandKeyRotationGrants(d.Get("bucket").(*schema.Set)), nil)
+	}
+	if err != nil {
+		if deleteErr := deleteRotatedKey(ctx, p, created.AccessKeyID); deleteErr != nil {
+			return nil, fmt.Errorf("%w; the new key %s couldn't be deleted either: %s", err, created.AccessKeyID, deleteErr)
+		}
+		return nil, err
+	}
+	return keyInfo, nil
+}
+
+// setKeyRotated makes keyInfo the current key of the rotation, created at
+// now, and previous the previous one, if any.
+func setKeyRotated(d *schema.ResourceData, keyInfo *accessKey, previous *accessKey, now time.Time) diag.Diagnostics {
+	rotation, _ := keyRotationPeriods(d)
+	values := map[string]interface{}{
+		"access_key_id":              keyInfo.AccessKeyID,
+		"secret_access_key":          keyInfo.SecretAccessKey,
+		"previous_access_key_id":     "",
+		"previous_secret_access_key": "",
+		"rotated_at":                 now.UTC().Format(time.RFC3339),
+		"rotates_at":                 now.Add(rotation).UTC().Format(time.RFC3339),
+	}
+	if previous != nil {
+		values["previous_access_key_id"] = previous.AccessKeyID
+		values["previous_secret_access_key"] = previous.SecretAccessKey
+	}
+
+	for key, value := range values {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
+
+// deleteRotatedKey deletes a key of the rotation, unless it is already gone.
+func deleteRotatedKey(ctx context.Context, p *garageProvider, accessKeyID string) error {
+	if accessKeyID == "" {
+		return nil
+	}
+	err := p.client.DeleteKey(ctx, accessKeyID)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func resourceKeyRotationCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	now := keyRotationNow().Truncate(time.Second)
+	keyInfo, err := createRotatedKey(ctx, d, p, now)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(d.Get("name").(string))
+
+	if diags := setKeyRotated(d, keyInfo, nil, now); diags.HasError() {
+		return diags
+	}
+
+	return resourceKeyRotationRead(ctx, d, m)
+}
+
+func resourceKeyRotationRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	keyInfo, err := p.client.GetKey(ctx, d.Get("access_key_id").(string))
+	if isNotFound(err) {
+		// Deleted outside of Terraform. The previous key is no longer
+		// managed once the resource is gone, which is only reported, as
+		// refreshing shouldn't delete keys.
+		d.SetId("")
+		if previousAccessKeyID := d.Get("previous_access_key_id").(string); previousAccessKeyID != "" {
+			if _, err := p.client.GetKey(ctx, previousAccessKeyID); err == nil {
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Warning,
+					Summary:  "Previous key left behind",
+					Detail:   fmt.Sprintf("The current key of the rotation was deleted outside of Terraform, so the rotation is created again, without deleting its previous key %s. Delete it once it is no longer used.", previousAccessKeyID),
+				})
+			}
+		}
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	previousAccessKeyID := d.Get("previous_access_key_id").(string)
+	previousSecretAccessKey := ""
+	if previousAccessKeyID != "" {
+		previous, err := p.client.GetKey(ctx, previousAccessKeyID)
+		if isNotFound(err) {
+			previousAccessKeyID = ""
+		} else if err != nil {
+			return diag.FromErr(err)
+		} else {
+			previousSecretAccessKey = previous.SecretAccessKey
+		}
+	}
+
+	// Only the buckets of the configuration are checked, as keys aren't
+	// listed on buckets they have no permission on.
+	buckets := []interface{}{}
+	for _, bucket := range d.Get("bucket").(*schema.Set).List() {
+		bucketID := bucket.(map[string]interface{})["bucket_id"].(string)
+		bucketInfo, err := p.client.GetBucket(ctx, bucketID)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		permissions := bucketKeyPermissions{}
+		if key := findBucketKey(bucketInfo, keyInfo.AccessKeyID); key != nil {
+			permissions = key.Permissions
+		}
+		buckets = append(buckets, map[string]interface{}{
+			"bucket_id": bucketID,
+			"read":      permissions.Read,
+			"write":     permissions.Write,
+			"owner":     permissions.Owner,
+		})
+	}
+
+	values := map[string]interface{}{
+		"secret_access_key":          keyInfo.SecretAccessKey,
+		"previous_access_key_id":     previousAccessKeyID,
+		"previous_secret_access_key": previousSecretAccessKey,
+		"bucket":                     buckets,
+	}
+	for key, value := range values {
+		err := d.Set(key, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceKeyRotationUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+
+	oldAccessKeyID, _ := d.GetChange("access_key_id")
+	oldPreviousAccessKeyID, _ := d.GetChange("previous_access_key_id")
+	current := &accessKey{AccessKeyID: oldAccessKeyID.(string)}
+	previousAccessKeyID := oldPreviousAccessKeyID.(string)
+
+	// The grants of the previous key follow the configuration too, so that
+	// both keys work the same during the overlap period.
+	if d.HasChange("bucket") {
+		oldBuckets, newBuckets := d.GetChange("bucket")
+		grants := expandKeyRotationGrants(newBuckets.(*schema.Set))
+		var revoked []string
+		for bucketID := range expandKeyRotationGrants(oldBuckets.(*schema.Set)) {
+			if _, ok := grants[bucketID]; !ok {
+				revoked = append(revoked, bucketID)
+			}
+		}
+
+		for _, accessKeyID := range []string{current.AccessKeyID, previousAccessKeyID} {
+			if accessKeyID == "" {
+				continue
+			}
+			err := grantKeyRotationBuckets(ctx, p, accessKeyID, grants, revoked)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+
+	// The rotation and the deletion of the previous key were planned by
+	// customizeDiffKeyRotation. Everything that may fail is done before
+	// creating the new key, which would be left behind otherwise, and the
+	// state is kept as it was when it fails.
+	if d.HasChange("rotated_at") {
+		d.Partial(true)
+
+		err := deleteRotatedKey(ctx, p, previousAccessKeyID)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		current, err = p.client.GetKey(ctx, current.AccessKeyID)
+		if isNotFound(err) {
+			current = nil
+		} else if err != nil {
+			return diag.FromErr(err)
+		}
+
+		now := keyRotationNow().Truncate(time.Second)
+		keyInfo, err := createRotatedKey(ctx, d, p, now)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		d.Partial(false)
+		if diags := setKeyRotated(d, keyInfo, current, now); diags.HasError() {
+			return diags
+		}
+		return resourceKeyRotationRead(ctx, d, m)
+	}
+
+	if previousAccessKeyID != "" && d.Get("previous_access_key_id").(string) == "" {
+		err := deleteRotatedKey(ctx, p, previousAccessKeyID)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceKeyRotationRead(ctx, d, m)
+}
+
+func resourceKeyRotationDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	p := m.(*garageProvider)
+	var diags diag.Diagnostics
+
+	for _, attribute := range []string{"previous_access_key_id", "access_key_id"} {
+		err := deleteRotatedKey(ctx, p, d.Get(attribute).(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
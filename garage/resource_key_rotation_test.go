@@ -0,0 +1,407 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func keyRotationConfig(providerConfig string, rotationPeriod string, overlapPeriod string) string {
+	return providerConfig + fmt.Sprintf(`
+resource "garage_bucket" "test" {}
+
+resource "garage_key_rotation" "test" {
+  name            = "ci"
+  rotation_period = %q
+  overlap_period  = %q
+
+  bucket {
+    bucket_id = garage_bucket.test.id
+    read      = true
+    write     = true
+  }
+}
+`, rotationPeriod, overlapPeriod)
+}
+
+// checkKeyRotationGrants checks that the keys stored in accessKeyIDs are
+// allowed to read and write to the bucket of the configuration.
+func checkKeyRotationGrants(client func() garageClient, bucketID *string, accessKeyIDs ...*string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		bucketInfo, err := client().GetBucket(context.Background(), *bucketID)
+		if err != nil {
+			return err
+		}
+		for _, accessKeyID := range accessKeyIDs {
+			key := findBucketKey(bucketInfo, *accessKeyID)
+			if key == nil || !key.Permissions.Read || !key.Permissions.Write || key.Permissions.Owner {
+				return fmt.Errorf("key %s isn't granted the bucket: %+v", *accessKeyID, key)
+			}
+		}
+		return nil
+	}
+}
+
+// fakeKeyRotationClock stops the clock of key rotations for the duration of
+// the test, returning a function moving it forward.
+func fakeKeyRotationClock(t *testing.T) func(time.Duration) {
+	var mu sync.Mutex
+	now := time.Now()
+	keyRotationNow = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	t.Cleanup(func() { keyRotationNow = time.Now })
+
+	return func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func TestUnitResourceKeyRotation(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		var bucketID, accessKeyID, rotatedAccessKeyID string
+		client := func() garageClient { return fake.client() }
+		advance := fakeKeyRotationClock(t)
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: keyRotationConfig(fake.providerConfig(), "720h", "24h"),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_bucket.test", "id", &bucketID),
+						storeAttr("garage_key_rotation.test", "access_key_id", &accessKeyID),
+						resource.TestMatchResourceAttr("garage_key_rotation.test", "secret_access_key", fakeSecretAccessKey),
+						resource.TestCheckResourceAttr("garage_key_rotation.test", "previous_access_key_id", ""),
+						resource.TestCheckResourceAttrSet("garage_key_rotation.test", "rotates_at"),
+						checkKeyRotationGrants(client, &bucketID, &accessKeyID),
+					),
+				},
+				{
+					Config:   keyRotationConfig(fake.providerConfig(), "720h", "24h"),
+					PlanOnly: true,
+				},
+				// The rotation period elapsed: a new key is created, and the
+				// previous one kept.
+				{
+					PreConfig: func() { advance(720 * time.Hour) },
+					Config:    keyRotationConfig(fake.providerConfig(), "720h", "24h"),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key_rotation.test", "access_key_id", &rotatedAccessKeyID),
+						resource.TestCheckResourceAttrPtr("garage_key_rotation.test", "previous_access_key_id", &accessKeyID),
+						resource.TestMatchResourceAttr("garage_key_rotation.test", "previous_secret_access_key", fakeSecretAccessKey),
+						checkKeyRotationGrants(client, &bucketID, &accessKeyID, &rotatedAccessKeyID),
+						func(*terraform.State) error {
+							if rotatedAccessKeyID == accessKeyID {
+								return fmt.Errorf("key %s wasn't rotated", accessKeyID)
+							}
+							return nil
+						},
+					),
+				},
+				// The overlap period elapsed: the previous key is deleted.
+				{
+					PreConfig: func() { advance(24 * time.Hour) },
+					Config:    keyRotationConfig(fake.providerConfig(), "720h", "24h"),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttrPtr("garage_key_rotation.test", "access_key_id", &rotatedAccessKeyID),
+						resource.TestCheckResourceAttr("garage_key_rotation.test", "previous_access_key_id", ""),
+						fake.withState(func() error {
+							if _, ok := fake.keys[accessKeyID]; ok {
+								return fmt.Errorf("previous key %s wasn't deleted", accessKeyID)
+							}
+							return nil
+						}),
+					),
+				},
+				// Deletion out of band
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						delete(fake.keys, rotatedAccessKeyID)
+					},
+					Config:             keyRotationConfig(fake.providerConfig(), "720h", "24h"),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceKeyRotation(t *testing.T) {
+	var bucketID, accessKeyID, rotatedAccessKeyID string
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		client := func() garageClient { return garage.client }
+		advance := fakeKeyRotationClock(t)
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: keyRotationConfig(garage.providerConfig, "720h", "24h"),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_bucket.test", "id", &bucketID),
+						storeAttr("garage_key_rotation.test", "access_key_id", &accessKeyID),
+						resource.TestCheckResourceAttrSet("garage_key_rotation.test", "secret_access_key"),
+						checkKeyRotationGrants(client, &bucketID, &accessKeyID),
+					),
+				},
+				{
+					PreConfig: func() { advance(720 * time.Hour) },
+					Config:    keyRotationConfig(garage.providerConfig, "720h", "24h"),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key_rotation.test", "access_key_id", &rotatedAccessKeyID),
+						resource.TestCheckResourceAttrPtr("garage_key_rotation.test", "previous_access_key_id", &accessKeyID),
+						checkKeyRotationGrants(client, &bucketID, &accessKeyID, &rotatedAccessKeyID),
+					),
+				},
+				{
+					PreConfig: func() { advance(24 * time.Hour) },
+					Config:    keyRotationConfig(garage.providerConfig, "720h", "24h"),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_key_rotation.test", "previous_access_key_id", ""),
+						func(*terraform.State) error {
+							_, err := garage.client.GetKey(context.Background(), accessKeyID)
+							if !isNotFound(err) {
+								return fmt.Errorf("previous key %s wasn't deleted: %v", accessKeyID, err)
+							}
+							return nil
+						},
+					),
+				},
+			},
+		}
+	})
+}
+
+// TestResourceKeyRotation plans and applies rotations offline, moving the
+// rotation date of the state back instead of waiting.
+func TestResourceKeyRotation(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	p := &garageProvider{client: fake.client(), apiVersion: 2, consistencyTimeout: time.Second}
+
+	bucketInfo, err := fake.client().CreateBucket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := resourceKeyRotation()
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":            "ci",
+		"rotation_period": "720h",
+		"bucket": []interface{}{
+			map[string]interface{}{"bucket_id": bucketInfo.ID, "read": true},
+		},
+	})
+	apply := func(state *terraform.InstanceState) *terraform.InstanceState {
+		t.Helper()
+		instanceDiff, err := r.Diff(ctx, state, config, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, diags := r.Apply(ctx, state, instanceDiff, p)
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return state
+	}
+	checkGranted := func(accessKeyIDs ...string) {
+		t.Helper()
+		bucketInfo, err := fake.client().GetBucket(ctx, bucketInfo.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, accessKeyID := range accessKeyIDs {
+			key := findBucketKey(bucketInfo, accessKeyID)
+			if key == nil || key.Permissions != (bucketKeyPermissions{Read: true}) {
+				t.Errorf("key %s isn't granted the bucket: %+v", accessKeyID, key)
+			}
+		}
+	}
+
+	state := apply(nil)
+	accessKeyID := state.Attributes["access_key_id"]
+	keyInfo, err := fake.client().GetKey(ctx, accessKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(keyInfo.Name, "ci-") || state.Attributes["previous_access_key_id"] != "" {
+		t.Errorf("unexpected key %+v and state %v", keyInfo, state.Attributes)
+	}
+	checkGranted(accessKeyID)
+
+	// Nothing is planned before the rotation period elapses.
+	instanceDiff, err := r.Diff(ctx, state, config, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !instanceDiff.Empty() {
+		t.Errorf("expected no changes, got %v", instanceDiff)
+	}
+
+	state.Attributes["rotated_at"] = time.Now().Add(-721 * time.Hour).UTC().Format(time.RFC3339)
+	state = apply(state)
+	rotatedAccessKeyID := state.Attributes["access_key_id"]
+	if rotatedAccessKeyID == accessKeyID || state.Attributes["previous_access_key_id"] != accessKeyID || state.Attributes["previous_secret_access_key"] != keyInfo.SecretAccessKey {
+		t.Errorf("expected key %s to be rotated, got %v", accessKeyID, state.Attributes)
+	}
+	checkGranted(accessKeyID, rotatedAccessKeyID)
+
+	state.Attributes["rotated_at"] = time.Now().Add(-25 * time.Hour).UTC().Format(time.RFC3339)
+	state = apply(state)
+	if state.Attributes["access_key_id"] != rotatedAccessKeyID || state.Attributes["previous_access_key_id"] != "" {
+		t.Errorf("expected the previous key to be dropped, got %v", state.Attributes)
+	}
+	if _, err := fake.client().GetKey(ctx, accessKeyID); !isNotFound(err) {
+		t.Errorf("expected previous key %s to be deleted, got %v", accessKeyID, err)
+	}
+
+	state, diags := r.Apply(ctx, state, &terraform.InstanceDiff{Destroy: true}, p)
+	if diags.HasError() || state != nil {
+		t.Fatalf("failed to destroy: %v, %v", state, diags)
+	}
+	if len(fake.keys) != 0 {
+		t.Errorf("expected every key to be deleted, got %v", fake.keys)
+	}
+}
+
+func TestResourceKeyRotationLeftovers(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	p := &garageProvider{client: fake.client(), apiVersion: 2, consistencyTimeout: time.Second}
+	r := resourceKeyRotation()
+
+	// The new key is deleted when it can't be granted the buckets
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":            "ci",
+		"rotation_period": "720h",
+		"bucket": []interface{}{
+			map[string]interface{}{"bucket_id": "missing", "read": true},
+		},
+	})
+	instanceDiff, err := r.Diff(ctx, nil, config, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := r.Apply(ctx, nil, instanceDiff, p); !diags.HasError() {
+		t.Fatal("expected granting a missing bucket to fail")
+	}
+	if len(fake.keys) != 0 {
+		t.Errorf("expected the new key to be deleted, got %v", fake.keys)
+	}
+
+	// The previous key is reported when the current one is deleted
+	config = terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":            "ci",
+		"rotation_period": "720h",
+	})
+	apply := func(state *terraform.InstanceState) *terraform.InstanceState {
+		t.Helper()
+		instanceDiff, err := r.Diff(ctx, state, config, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, diags := r.Apply(ctx, state, instanceDiff, p)
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return state
+	}
+	state := apply(nil)
+	state.Attributes["rotated_at"] = time.Now().Add(-721 * time.Hour).UTC().Format(time.RFC3339)
+	state = apply(state)
+	if err := fake.client().DeleteKey(ctx, state.Attributes["access_key_id"]); err != nil {
+		t.Fatal(err)
+	}
+	state, diags := r.RefreshWithoutUpgrade(ctx, state, p)
+	if state != nil || len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != "Previous key left behind" {
+		t.Errorf("expected a warning about the previous key, got %v, %v", state, diags)
+	}
+}
+
+func TestResourceKeyRotationFailures(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	p := &garageProvider{client: fake.client(), apiVersion: 2, consistencyTimeout: time.Second}
+	r := resourceKeyRotation()
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":            "ci",
+		"rotation_period": "720h",
+	})
+	plan := func(state *terraform.InstanceState) *terraform.InstanceDiff {
+		t.Helper()
+		instanceDiff, err := r.Diff(ctx, state, config, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return instanceDiff
+	}
+
+	state, diags := r.Apply(ctx, nil, plan(nil), p)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	state.Attributes["rotated_at"] = time.Now().Add(-721 * time.Hour).UTC().Format(time.RFC3339)
+	state, diags = r.Apply(ctx, state, plan(state), p)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	accessKeyID := state.Attributes["access_key_id"]
+	previousAccessKeyID := state.Attributes["previous_access_key_id"]
+
+	// No new key is left behind when the previous one can't be deleted.
+	fake.mu.Lock()
+	fake.failures["deleteKey"] = errInternal("injected failure")
+	fake.mu.Unlock()
+	state.Attributes["rotated_at"] = time.Now().Add(-721 * time.Hour).UTC().Format(time.RFC3339)
+	failed, diags := r.Apply(ctx, state, plan(state), p)
+	if !diags.HasError() {
+		t.Fatal("expected deleting the previous key to fail")
+	}
+	if len(fake.keys) != 2 {
+		t.Errorf("expected no new key, got %v", fake.keys)
+	}
+	if failed.Attributes["access_key_id"] != accessKeyID || failed.Attributes["previous_access_key_id"] != previousAccessKeyID || failed.Attributes["rotated_at"] != state.Attributes["rotated_at"] {
+		t.Errorf("expected the state to be kept, got %v", failed.Attributes)
+	}
+
+	fake.mu.Lock()
+	delete(fake.failures, "deleteKey")
+	fake.mu.Unlock()
+	state, diags = r.Apply(ctx, failed, plan(failed), p)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if state.Attributes["previous_access_key_id"] != accessKeyID || len(fake.keys) != 2 {
+		t.Errorf("expected key %s to be rotated, got %v and keys %v", accessKeyID, state.Attributes, fake.keys)
+	}
+	if _, err := fake.client().GetKey(ctx, previousAccessKeyID); !isNotFound(err) {
+		t.Errorf("expected previous key %s to be deleted, got %v", previousAccessKeyID, err)
+	}
+}
+
+func TestCustomizeDiffKeyRotation(t *testing.T) {
+	r := resourceKeyRotation()
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":            "ci",
+		"rotation_period": "24h",
+		"overlap_period":  "24h",
+	})
+	_, err := r.Diff(context.Background(), nil, config, &garageProvider{})
+	if err == nil || !strings.Contains(err.Error(), "must be shorter than rotation_period") {
+		t.Errorf("expected an error about the overlap period, got %v", err)
+	}
+}
@@ -0,0 +1,597 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestUnitResourceKey(t *testing.T) {
+	unitTest(t, testAPIVersions, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name = "test"
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_key.test", "name", "test"),
+						resource.TestMatchResourceAttr("garage_key.test", "access_key_id", fakeAccessKeyID),
+						resource.TestMatchResourceAttr("garage_key.test", "secret_access_key", fakeSecretAccessKey),
+						resource.TestCheckResourceAttr("garage_key.test", "permissions.create_bucket", "false"),
+					),
+				},
+				{
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name = "renamed"
+
+  permissions = {
+    create_bucket = true
+  }
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_key.test", "name", "renamed"),
+						resource.TestCheckResourceAttr("garage_key.test", "permissions.create_bucket", "true"),
+						fake.withState(func() error {
+							for _, key := range fake.keys {
+								if key.Name != "renamed" || !key.Permissions.CreateBucket {
+									return fmt.Errorf("key %s wasn't updated: %+v", key.AccessKeyID, key)
+								}
+							}
+							return nil
+						}),
+					),
+				},
+				{
+					ResourceName:      "garage_key.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+func testAccKeyConfig(garage *testGarage, name string, createBucket bool) string {
+	return garage.providerConfig + fmt.Sprintf(`
+resource "garage_key" "test" {
+  name = %q
+
+  permissions = {
+    create_bucket = %t
+  }
+}
+`, name, createBucket)
+}
+
+func TestAccResourceKey(t *testing.T) {
+	var accessKeyID string
+	var deletedAccessKeyID string
+	name := "tf-acc-" + randomHex(10)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: testAccKeyConfig(garage, name, false),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						resource.TestCheckResourceAttr("garage_key.test", "name", name),
+						resource.TestCheckResourceAttrSet("garage_key.test", "secret_access_key"),
+						resource.TestCheckResourceAttr("garage_key.test", "permissions.create_bucket", "false"),
+					),
+				},
+				// Drift of the name and permissions
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, client garageClient) error {
+						drifted := name + "-drifted"
+						_, err := client.UpdateKey(ctx, accessKeyID, keyUpdate{Name: &drifted, Allow: &keyPermissions{CreateBucket: true}})
+						return err
+					}),
+					Config:             testAccKeyConfig(garage, name, false),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: testAccKeyConfig(garage, name, false),
+					Check: garage.checkKey(&accessKeyID, func(keyInfo *accessKey) error {
+						if keyInfo.Name != name || keyInfo.Permissions.CreateBucket {
+							return fmt.Errorf("key wasn't restored: %+v", keyInfo)
+						}
+						return nil
+					}),
+				},
+				{
+					Config: testAccKeyConfig(garage, name+"-renamed", true),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_key.test", "name", name+"-renamed"),
+						resource.TestCheckResourceAttr("garage_key.test", "permissions.create_bucket", "true"),
+						garage.checkKey(&accessKeyID, func(keyInfo *accessKey) error {
+							if keyInfo.Name != name+"-renamed" || !keyInfo.Permissions.CreateBucket {
+								return fmt.Errorf("key wasn't updated: %+v", keyInfo)
+							}
+							return nil
+						}),
+					),
+				},
+				// Deletion out of band
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, client garageClient) error {
+						deletedAccessKeyID = accessKeyID
+						return client.DeleteKey(ctx, accessKeyID)
+					}),
+					Config:             testAccKeyConfig(garage, name+"-renamed", true),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: testAccKeyConfig(garage, name+"-renamed", true),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						func(*terraform.State) error {
+							if accessKeyID == deletedAccessKeyID {
+								return fmt.Errorf("key %s wasn't recreated", accessKeyID)
+							}
+							return nil
+						},
+					),
+				},
+				{
+					ResourceName:      "garage_key.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+// TestAccResourceKeyImported covers keys created from an existing access key
+// ID and secret.
+func TestAccResourceKeyImported(t *testing.T) {
+	accessKeyID := "GK" + randomHex(24)
+	secretAccessKey := randomHex(64)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: garage.providerConfig + fmt.Sprintf(`
+resource "garage_key" "test" {
+  name              = "imported"
+  access_key_id     = %q
+  secret_access_key = %q
+}
+`, accessKeyID, secretAccessKey),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_key.test", "id", accessKeyID),
+						resource.TestCheckResourceAttr("garage_key.test", "secret_access_key", secretAccessKey),
+						garage.checkKey(&accessKeyID, func(keyInfo *accessKey) error {
+							if keyInfo.SecretAccessKey != secretAccessKey {
+								return fmt.Errorf("key wasn't imported with its secret")
+							}
+							return nil
+						}),
+					),
+				},
+				{
+					ResourceName:      "garage_key.test",
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		}
+	})
+}
+
+func TestUnitResourceKeyExpiration(t *testing.T) {
+	unitTest(t, []int{2}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name       = "ci"
+  expiration = "2099-01-01T02:00:00+02:00"
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_key.test", "expired", "false"),
+						fake.withState(func() error {
+							for _, key := range fake.keys {
+								if key.Expiration == nil || *key.Expiration != "2099-01-01T00:00:00Z" {
+									return fmt.Errorf("key %s doesn't expire: %+v", key.AccessKeyID, key)
+								}
+							}
+							return nil
+						}),
+					),
+				},
+				// Garage answering the date in UTC is no change
+				{
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name       = "ci"
+  expiration = "2099-01-01T02:00:00+02:00"
+}
+`,
+					PlanOnly: true,
+				},
+				// Drift of the expiration
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, key := range fake.keys {
+							drifted := "2098-01-01T00:00:00Z"
+							key.Expiration = &drifted
+						}
+					},
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name       = "ci"
+  expiration = "2099-01-01T02:00:00+02:00"
+}
+`,
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name          = "ci"
+  never_expires = true
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_key.test", "expiration", ""),
+						fake.withState(func() error {
+							for _, key := range fake.keys {
+								if key.Expiration != nil {
+									return fmt.Errorf("key %s still expires: %+v", key.AccessKeyID, key)
+								}
+							}
+							return nil
+						}),
+					),
+				},
+				// An expiration set out of band is a change with never_expires
+				{
+					PreConfig: func() {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						for _, key := range fake.keys {
+							drifted := "2098-01-01T00:00:00Z"
+							key.Expiration = &drifted
+						}
+					},
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name          = "ci"
+  never_expires = true
+}
+`,
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				// So is one set out of band without expiration
+				{
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name = "ci"
+}
+`,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("garage_key.test", "expiration", ""),
+						fake.withState(func() error {
+							for _, key := range fake.keys {
+								if key.Expiration != nil {
+									return fmt.Errorf("key %s still expires: %+v", key.AccessKeyID, key)
+								}
+							}
+							return nil
+						}),
+					),
+				},
+			},
+		}
+	})
+}
+
+func TestUnitResourceKeyExpirationRequiresV2(t *testing.T) {
+	unitTest(t, []int{1}, func(fake *fakeGarage) resource.TestCase {
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: fake.providerConfig() + `
+resource "garage_key" "test" {
+  name       = "ci"
+  expiration = "2099-01-01T00:00:00Z"
+}
+`,
+					ExpectError: regexp.MustCompile("v2 admin API"),
+				},
+			},
+		}
+	})
+}
+
+func TestAccResourceKeyExpiration(t *testing.T) {
+	var accessKeyID string
+	name := "tf-acc-" + randomHex(10)
+	expiration := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second).Format(time.RFC3339)
+
+	accTest(t, func(garage *testGarage) resource.TestCase {
+		if garage.provider.apiVersion < 2 || !garage.provider.supports(featureKeyExpiration) {
+			t.Skip("key expiration requires Garage 2.0 and the v2 admin API")
+		}
+
+		return resource.TestCase{
+			Steps: []resource.TestStep{
+				{
+					Config: garage.providerConfig + fmt.Sprintf(`
+resource "garage_key" "test" {
+  name       = %q
+  expiration = %q
+}
+`, name, expiration),
+					Check: resource.ComposeTestCheckFunc(
+						storeAttr("garage_key.test", "access_key_id", &accessKeyID),
+						resource.TestCheckResourceAttr("garage_key.test", "expired", "false"),
+						garage.checkKey(&accessKeyID, func(keyInfo *accessKey) error {
+							if keyInfo.Expiration == nil || !sameInstant(*keyInfo.Expiration, expiration) {
+								return fmt.Errorf("key doesn't expire on %s: %+v", expiration, keyInfo)
+							}
+							return nil
+						}),
+					),
+				},
+				// Drift of the expiration
+				{
+					PreConfig: garage.outOfBand(t, func(ctx context.Context, client garageClient) error {
+						_, err := client.UpdateKey(ctx, accessKeyID, keyUpdate{NeverExpires: true})
+						return err
+					}),
+					Config: garage.providerConfig + fmt.Sprintf(`
+resource "garage_key" "test" {
+  name       = %q
+  expiration = %q
+}
+`, name, expiration),
+					PlanOnly:           true,
+					ExpectNonEmptyPlan: true,
+				},
+				{
+					Config: garage.providerConfig + fmt.Sprintf(`
+resource "garage_key" "test" {
+  name          = %q
+  never_expires = true
+}
+`, name),
+					Check: garage.checkKey(&accessKeyID, func(keyInfo *accessKey) error {
+						if keyInfo.Expiration != nil {
+							return fmt.Errorf("key still expires: %+v", keyInfo)
+						}
+						return nil
+					}),
+				},
+			},
+		}
+	})
+}
+
+func TestResourceKeyExpiration(t *testing.T) {
+	// The fake node tells whether keys expired by the actual time.
+	now := time.Now().UTC().Truncate(time.Second)
+	fakeKeyExpirationClock(t, now)
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	p := &garageProvider{client: fake.client(), apiVersion: 2, consistencyTimeout: time.Second}
+
+	d := schema.TestResourceDataRaw(t, schemaKey(), map[string]interface{}{
+		"name":       "ci",
+		"expiration": "2099-01-01T00:00:00Z",
+	})
+	if diags := resourceKeyCreate(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if d.Get("expiration") != "2099-01-01T00:00:00Z" || d.Get("expired") != false {
+		t.Errorf("unexpected state %v", d.State())
+	}
+
+	// A key expiring soon is warned about when refreshed.
+	soon := now.Add(24 * time.Hour).Format(time.RFC3339)
+	if _, err := fake.client().UpdateKey(ctx, d.Id(), keyUpdate{Expiration: &soon}); err != nil {
+		t.Fatal(err)
+	}
+	diags := resourceKeyRead(ctx, d, p)
+	if diags.HasError() || len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != "Key expires soon" {
+		t.Errorf("expected a warning about the expiration, got %v", diags)
+	}
+	if d.Get("expiration") != soon {
+		t.Errorf("expected the expiration to be refreshed, got %v", d.Get("expiration"))
+	}
+
+	// never_expires plans the removal of the expiration.
+	r := resourceKey()
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{"name": "ci", "never_expires": true})
+	instanceDiff, err := r.Diff(ctx, d.State(), config, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr := instanceDiff.Attributes["expiration"]; attr == nil || attr.Old != soon || attr.New != "" {
+		t.Errorf("expected the expiration to be removed, got %v", instanceDiff)
+	}
+
+	accessKeyID := d.Id()
+	d = schema.TestResourceDataRaw(t, schemaKey(), map[string]interface{}{"name": "ci", "never_expires": true})
+	d.SetId(accessKeyID)
+	if diags := resourceKeyUpdate(ctx, d, p); diags.HasError() {
+		t.Fatal(diags)
+	}
+	keyInfo, err := fake.client().GetKey(ctx, d.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyInfo.Expiration != nil || d.Get("expiration") != "" {
+		t.Errorf("expected the key to never expire, got %+v", keyInfo)
+	}
+
+	// Unsetting the expiration removes it too.
+	config = terraform.NewResourceConfigRaw(map[string]interface{}{"name": "ci", "expiration": soon})
+	instanceDiff, err = r.Diff(ctx, d.State(), config, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, diags := r.Apply(ctx, d.State(), instanceDiff, p)
+	if diags.HasError() || state.Attributes["expiration"] != soon {
+		t.Fatalf("expected the key to expire on %s, got %v, %v", soon, state.Attributes, diags)
+	}
+	config = terraform.NewResourceConfigRaw(map[string]interface{}{"name": "ci"})
+	instanceDiff, err = r.Diff(ctx, state, config, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr := instanceDiff.Attributes["expiration"]; attr == nil || attr.Old != soon || attr.New != "" {
+		t.Errorf("expected the expiration to be removed, got %v", instanceDiff)
+	}
+	state, diags = r.Apply(ctx, state, instanceDiff, p)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	keyInfo, err = fake.client().GetKey(ctx, d.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyInfo.Expiration != nil || state.Attributes["expiration"] != "" {
+		t.Errorf("expected the key to never expire, got %+v", keyInfo)
+	}
+}
+
+func TestCustomizeDiffKeyExpiration(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		config        map[string]interface{}
+		apiVersion    int
+		garageVersion string
+		planError     string
+	}{
+		{"no expiration", map[string]interface{}{}, 1, "v1.0.0", ""},
+		{"expiration", map[string]interface{}{"expiration": "2099-01-01T00:00:00Z"}, 2, "v2.0.0", ""},
+		{"never expires", map[string]interface{}{"never_expires": true}, 2, "v2.0.0", ""},
+		{"both", map[string]interface{}{"expiration": "2099-01-01T00:00:00Z", "never_expires": true}, 2, "v2.0.0", "conflicts with"},
+		{"invalid date", map[string]interface{}{"expiration": "tomorrow"}, 2, "v2.0.0", "RFC3339"},
+		{"v1 admin API", map[string]interface{}{"expiration": "2099-01-01T00:00:00Z"}, 1, "v2.0.0", "v2 admin API"},
+		{"Garage 1", map[string]interface{}{"never_expires": true}, 2, "v1.0.0", "requires Garage 2.0.0"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			raw := map[string]interface{}{"name": "ci"}
+			for name, value := range c.config {
+				raw[name] = value
+			}
+			config := terraform.NewResourceConfigRaw(raw)
+			p := &garageProvider{apiVersion: c.apiVersion, garageVersion: version.Must(version.NewVersion(c.garageVersion))}
+
+			r := resourceKey()
+			diags := r.Validate(config)
+			var err error
+			if diags.HasError() {
+				err = fmt.Errorf("%v", diags)
+			} else {
+				_, err = r.Diff(context.Background(), nil, config, p)
+			}
+			if c.planError == "" && err != nil {
+				t.Fatal(err)
+			}
+			if c.planError != "" && (err == nil || !strings.Contains(err.Error(), c.planError)) {
+				t.Fatalf("expected an error containing %q, got %v", c.planError, err)
+			}
+		})
+	}
+}
+
+func TestKeyExpirationWarning(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name       string
+		expiration string
+		expired    bool
+		summary    string
+	}{
+		{"never expires", "", false, ""},
+		{"later", "2026-12-01T00:00:00Z", false, ""},
+		{"soon", "2026-10-20T12:00:00Z", false, "Key expires soon"},
+		{"expired", "2026-10-18T12:00:00Z", true, "Key has expired"},
+		{"expired by its clock", "2026-10-19T11:00:00Z", false, "Key has expired"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			keyInfo := &accessKey{AccessKeyID: "GK0123", Expired: c.expired}
+			if c.expiration != "" {
+				keyInfo.Expiration = &c.expiration
+			}
+
+			diags := keyExpirationWarning(keyInfo, now)
+			if c.summary == "" && len(diags) != 0 {
+				t.Fatalf("expected no warning, got %v", diags)
+			}
+			if c.summary != "" && (len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != c.summary) {
+				t.Fatalf("expected a warning %q, got %v", c.summary, diags)
+			}
+		})
+	}
+}
+
+// fakeKeyExpirationClock stops the clock of key expirations at now for the
+// duration of the test.
+func fakeKeyExpirationClock(t *testing.T, now time.Time) {
+	keyExpirationNow = func() time.Time { return now }
+	t.Cleanup(func() { keyExpirationNow = time.Now })
+}
+
+func TestValidateKeyExpiration(t *testing.T) {
+	fakeKeyExpirationClock(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	for _, c := range []struct {
+		name       string
+		expiration string
+		invalid    bool
+		summary    string
+	}{
+		{"later", "2026-11-18T12:00:00Z", false, ""},
+		{"soon", "2026-10-19T15:00:00+02:00", false, "Key expires soon"},
+		{"past", "2026-10-19T11:00:00Z", false, "Key has expired"},
+		{"invalid", "tomorrow", true, ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			path := cty.GetAttrPath("expiration")
+			diags := validateKeyExpiration(c.expiration, path)
+			switch {
+			case c.invalid:
+				if !diags.HasError() {
+					t.Fatalf("expected an error, got %v", diags)
+				}
+			case c.summary == "":
+				if len(diags) != 0 {
+					t.Fatalf("expected no warning, got %v", diags)
+				}
+			default:
+				if len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != c.summary || !diags[0].AttributePath.Equals(path) {
+					t.Fatalf("expected a warning %q, got %v", c.summary, diags)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package garage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+)
+
+// corsRule is a CORSRule of a CORSConfiguration.
+type corsRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  *int     `xml:"MaxAgeSeconds"`
+}
+
+type corsConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Rules   []corsRule `xml:"CORSRule"`
+}
+
+// lifecycleFilter selects the objects a lifecycleRule applies to. Filters
+// with several conditions nest them in And.
+type lifecycleFilter struct {
+	And                   *lifecycleFilter `xml:"And"`
+	Prefix                *string          `xml:"Prefix"`
+	ObjectSizeGreaterThan *int64           `xml:"ObjectSizeGreaterThan"`
+	ObjectSizeLessThan    *int64           `xml:"ObjectSizeLessThan"`
+}
+
+type lifecycleExpiration struct {
+	Days *int `xml:"Days"`
+	// Date is an RFC 3339 timestamp at midnight UTC.
+	Date *string `xml:"Date"`
+}
+
+type lifecycleAbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+// lifecycleRule is a Rule of a LifecycleConfiguration. Status is Enabled or
+// Disabled.
+type lifecycleRule struct {
+	ID                             string                                   `xml:"ID,omitempty"`
+	Status                         string                                   `xml:"Status"`
+	Filter                         *lifecycleFilter                         `xml:"Filter"`
+	Expiration                     *lifecycleExpiration                     `xml:"Expiration"`
+	AbortIncompleteMultipartUpload *lifecycleAbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload"`
+}
+
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Rules   []lifecycleRule `xml:"Rule"`
+}
+
+type websiteIndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type websiteErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+type websiteRoutingCondition struct {
+	KeyPrefixEquals             *string `xml:"KeyPrefixEquals"`
+	HTTPErrorCodeReturnedEquals *int    `xml:"HttpErrorCodeReturnedEquals"`
+}
+
+type websiteRedirect struct {
+	HostName             *string `xml:"HostName"`
+	Protocol             *string `xml:"Protocol"`
+	HTTPRedirectCode     *int    `xml:"HttpRedirectCode"`
+	ReplaceKeyPrefixWith *string `xml:"ReplaceKeyPrefixWith"`
+	ReplaceKeyWith       *string `xml:"ReplaceKeyWith"`
+}
+
+type websiteRoutingRule struct {
+	Condition *websiteRoutingCondition `xml:"Condition"`
+	Redirect  websiteRedirect          `xml:"Redirect"`
+}
+
+// websiteConfiguration is the WebsiteConfiguration of a bucket. Garage
+// doesn't implement RedirectAllRequestsTo, so it is left out.
+type websiteConfiguration struct {
+	XMLName       xml.Name              `xml:"WebsiteConfiguration"`
+	IndexDocument *websiteIndexDocument `xml:"IndexDocument"`
+	ErrorDocument *websiteErrorDocument `xml:"ErrorDocument"`
+	RoutingRules  []websiteRoutingRule  `xml:"RoutingRules>RoutingRule"`
+}
+
+// getBucketSubresource decodes the configuration of bucket stored under the
+// subresource, such as cors, into out.
+func (c *s3Client) getBucketSubresource(ctx context.Context, bucket string, subresource string, out interface{}) error {
+	_, err := c.call(ctx, s3Request{
+		Method: http.MethodGet,
+		Bucket: bucket,
+		Query:  url.Values{subresource: {""}},
+	}, out)
+	return err
+}
+
+// putBucketSubresource replaces the configuration of bucket stored under the
+// subresource with in, along with the Content-MD5 header S3 requires for
+// them.
+func (c *s3Client) putBucketSubresource(ctx context.Context, bucket string, subresource string, in interface{}) error {
+	body, err := xml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(body)
+	header := http.Header{}
+	header.Set("Content-Type", "application/xml")
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+
+	_, err = c.call(ctx, s3Request{
+		Method: http.MethodPut,
+		Bucket: bucket,
+		Query:  url.Values{subresource: {""}},
+		Header: header,
+		Body:   body,
+	}, nil)
+	return err
+}
+
+func (c *s3Client) deleteBucketSubresource(ctx context.Context, bucket string, subresource string) error {
+	_, err := c.call(ctx, s3Request{
+		Method: http.MethodDelete,
+		Bucket: bucket,
+		Query:  url.Values{subresource: {""}},
+	}, nil)
+	return err
+}
+
+// GetBucketCors returns the CORS rules of bucket, failing with a not found
+// error when it has none.
+func (c *s3Client) GetBucketCors(ctx context.Context, bucket string) ([]corsRule, error) {
+	var configuration corsConfiguration
+	if err := c.getBucketSubresource(ctx, bucket, "cors", &configuration); err != nil {
+		return nil, err
+	}
+	return configuration.Rules, nil
+}
+
+func (c *s3Client) PutBucketCors(ctx context.Context, bucket string, rules []corsRule) error {
+	return c.putBucketSubresource(ctx, bucket, "cors", corsConfiguration{Rules: rules})
+}
+
+func (c *s3Client) DeleteBucketCors(ctx context.Context, bucket string) error {
+	return c.deleteBucketSubresource(ctx, bucket, "cors")
+}
+
+// GetBucketLifecycle returns the lifecycle rules of bucket, failing with a
+// not found error when it has none.
+func (c *s3Client) GetBucketLifecycle(ctx context.Context, bucket string) ([]lifecycleRule, error) {
+	var configuration lifecycleConfiguration
+	if err := c.getBucketSubresource(ctx, bucket, "lifecycle", &configuration); err != nil {
+		return nil, err
+	}
+	return configuration.Rules, nil
+}
+
+func (c *s3Client) PutBucketLifecycle(ctx context.Context, bucket string, rules []lifecycleRule) error {
+	return c.putBucketSubresource(ctx, bucket, "lifecycle", lifecycleConfiguration{Rules: rules})
+}
+
+func (c *s3Client) DeleteBucketLifecycle(ctx context.Context, bucket string) error {
+	return c.deleteBucketSubresource(ctx, bucket, "lifecycle")
+}
+
+// GetBucketWebsite returns the website configuration of bucket, failing with
+// a not found error when website access is disabled.
+func (c *s3Client) GetBucketWebsite(ctx context.Context, bucket string) (*websiteConfiguration, error) {
+	var configuration websiteConfiguration
+	if err := c.getBucketSubresource(ctx, bucket, "website", &configuration); err != nil {
+		return nil, err
+	}
+	return &configuration, nil
+}
+
+// PutBucketWebsite replaces the website configuration of bucket, enabling
+// website access.
+func (c *s3Client) PutBucketWebsite(ctx context.Context, bucket string, configuration *websiteConfiguration) error {
+	return c.putBucketSubresource(ctx, bucket, "website", configuration)
+}
+
+// DeleteBucketWebsite disables website access to bucket.
+func (c *s3Client) DeleteBucketWebsite(ctx context.Context, bucket string) error {
+	return c.deleteBucketSubresource(ctx, bucket, "website")
+}
@@ -0,0 +1,156 @@
+package garage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestS3ClientBucketCors(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "website")
+	ctx := context.Background()
+
+	if _, err := client.GetBucketCors(ctx, "website"); !isS3NotFound(err) {
+		t.Errorf("expected no CORS configuration, got %v", err)
+	}
+
+	maxAgeSeconds := 3600
+	rules := []corsRule{
+		{
+			ID:             "app",
+			AllowedOrigins: []string{"https://app.example.com"},
+			AllowedMethods: []string{"GET", "PUT"},
+			AllowedHeaders: []string{"*"},
+			ExposeHeaders:  []string{"ETag"},
+			MaxAgeSeconds:  &maxAgeSeconds,
+		},
+		{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET"},
+		},
+	}
+	if err := client.PutBucketCors(ctx, "website", rules); err != nil {
+		t.Fatal(err)
+	}
+	read, err := client.GetBucketCors(ctx, "website")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, rules) {
+		t.Errorf("expected %+v, got %+v", rules, read)
+	}
+
+	if err := client.DeleteBucketCors(ctx, "website"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetBucketCors(ctx, "website"); !isS3NotFound(err) {
+		t.Errorf("expected the CORS configuration to be deleted, got %v", err)
+	}
+}
+
+func TestS3ClientBucketLifecycle(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "logs")
+	ctx := context.Background()
+
+	if _, err := client.GetBucketLifecycle(ctx, "logs"); !isS3NotFound(err) {
+		t.Errorf("expected no lifecycle configuration, got %v", err)
+	}
+
+	days := 30
+	prefix := "tmp/"
+	lessThan := int64(1 << 20)
+	rules := []lifecycleRule{
+		{
+			ID:         "tmp",
+			Status:     "Enabled",
+			Filter:     &lifecycleFilter{And: &lifecycleFilter{Prefix: &prefix, ObjectSizeLessThan: &lessThan}},
+			Expiration: &lifecycleExpiration{Days: &days},
+		},
+		{
+			ID:                             "uploads",
+			Status:                         "Disabled",
+			Filter:                         &lifecycleFilter{},
+			AbortIncompleteMultipartUpload: &lifecycleAbortIncompleteMultipartUpload{DaysAfterInitiation: 7},
+		},
+	}
+	if err := client.PutBucketLifecycle(ctx, "logs", rules); err != nil {
+		t.Fatal(err)
+	}
+	read, err := client.GetBucketLifecycle(ctx, "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, rules) {
+		t.Errorf("expected %+v, got %+v", rules, read)
+	}
+
+	if err := client.DeleteBucketLifecycle(ctx, "logs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetBucketLifecycle(ctx, "logs"); !isS3NotFound(err) {
+		t.Errorf("expected the lifecycle configuration to be deleted, got %v", err)
+	}
+}
+
+func TestS3ClientBucketWebsite(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "website")
+	ctx := context.Background()
+
+	if _, err := client.GetBucketWebsite(ctx, "website"); !isS3NotFound(err) {
+		t.Errorf("expected website access to be disabled, got %v", err)
+	}
+
+	prefix := "docs/"
+	replacement := "documentation/"
+	code := 302
+	configuration := &websiteConfiguration{
+		IndexDocument: &websiteIndexDocument{Suffix: "index.html"},
+		ErrorDocument: &websiteErrorDocument{Key: "404.html"},
+		RoutingRules: []websiteRoutingRule{
+			{
+				Condition: &websiteRoutingCondition{KeyPrefixEquals: &prefix},
+				Redirect:  websiteRedirect{ReplaceKeyPrefixWith: &replacement, HTTPRedirectCode: &code},
+			},
+		},
+	}
+	if err := client.PutBucketWebsite(ctx, "website", configuration); err != nil {
+		t.Fatal(err)
+	}
+	read, err := client.GetBucketWebsite(ctx, "website")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.RoutingRules, configuration.RoutingRules) || read.IndexDocument.Suffix != "index.html" || read.ErrorDocument.Key != "404.html" {
+		t.Errorf("expected %+v, got %+v", configuration, read)
+	}
+
+	bucketInfo, err := fake.client().FindBucket(ctx, "website")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bucketInfo.WebsiteAccess || bucketInfo.WebsiteConfig.IndexDocument != "index.html" {
+		t.Errorf("expected the admin API to report website access, got %+v", bucketInfo)
+	}
+
+	// Like Garage, the fake refuses to redirect all requests
+	redirectAll := struct {
+		XMLName  xml.Name `xml:"WebsiteConfiguration"`
+		HostName string   `xml:"RedirectAllRequestsTo>HostName"`
+	}{HostName: "example.com"}
+	var apiErr *s3Error
+	if err := client.putBucketSubresource(ctx, "website", "website", redirectAll); !errors.As(err, &apiErr) || apiErr.Code != "NotImplemented" {
+		t.Errorf("expected RedirectAllRequestsTo to be refused, got %v", err)
+	}
+
+	if err := client.DeleteBucketWebsite(ctx, "website"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetBucketWebsite(ctx, "website"); !isS3NotFound(err) {
+		t.Errorf("expected website access to be disabled, got %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package garage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// defaultS3Region is the s3_region of the sample configurations of Garage.
+const defaultS3Region = "garage"
+
+// s3Config is how the provider reaches the S3 API, which resources managing
+// bucket contents and settings the admin API doesn't cover go through.
+type s3Config struct {
+	endpoint   *url.URL
+	region     string
+	pathStyle  bool
+	httpClient *http.Client
+	// credentials are the static ones set on the provider, nil when requests
+	// are signed with keys managed in the configuration.
+	credentials *s3Credentials
+}
+
+// s3Client calls the S3 API with the credentials of one key.
+type s3Client struct {
+	config      *s3Config
+	credentials s3Credentials
+}
+
+// s3Request is a request to the S3 API. Bucket is the global alias, or a
+// local alias of the signing key, of the bucket, empty for service requests.
+type s3Request struct {
+	Method string
+	Bucket string
+	Key    string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// s3Error is an error answered by the S3 API.
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("S3 API answered %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("S3 API answered %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// newS3Config returns the S3 settings of the provider, taking the endpoint
+// and region from config_file when they are not set, or nil when no S3
+// endpoint is configured.
+func newS3Config(d *schema.ResourceData, httpClient *http.Client) (*s3Config, error) {
+	endpoint := d.Get("s3_endpoint").(string)
+	region := d.Get("s3_region").(string)
+	if configFile := d.Get("config_file").(string); configFile != "" && (endpoint == "" || region == "") {
+		config, err := readGarageConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if endpoint == "" {
+			endpoint, err = config.s3Endpoint()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if region == "" {
+			region = config.S3API.S3Region
+		}
+	}
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	accessKeyID := d.Get("s3_access_key_id").(string)
+	secretAccessKey := d.Get("s3_secret_access_key").(string)
+	if (accessKeyID == "") != (secretAccessKey == "") {
+		return nil, fmt.Errorf("s3_access_key_id and s3_secret_access_key must be set together")
+	}
+
+	if endpoint == "" {
+		if accessKeyID != "" {
+			return nil, fmt.Errorf("s3_access_key_id is set but no S3 endpoint is: set s3_endpoint")
+		}
+		return nil, nil
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3_endpoint %q: %w", endpoint, err)
+	}
+	if (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid s3_endpoint %q: expected an http or https URL", endpoint)
+	}
+	endpointURL.Path = strings.TrimSuffix(endpointURL.Path, "/")
+	endpointURL.RawPath = ""
+
+	config := &s3Config{
+		endpoint:   endpointURL,
+		region:     region,
+		pathStyle:  d.Get("s3_use_path_style").(bool),
+		httpClient: httpClient,
+	}
+	if accessKeyID != "" {
+		config.credentials = &s3Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+		}
+	}
+	return config, nil
+}
+
+// s3Client returns a client of the S3 API signing requests with the key
+// accessKeyID, whose secret is read through the admin API, or with the
+// static credentials of the provider when accessKeyID is empty.
+func (p *garageProvider) s3Client(ctx context.Context, accessKeyID string) (*s3Client, error) {
+	if p == nil || p.s3 == nil {
+		return nil, fmt.Errorf("the S3 API endpoint is unknown: set s3_endpoint on the provider")
+	}
+
+	static := p.s3.credentials
+	switch {
+	case accessKeyID == "" && static == nil:
+		return nil, fmt.Errorf("no key to sign S3 requests with: set access_key_id, or s3_access_key_id and s3_secret_access_key on the provider")
+	case accessKeyID == "" || (static != nil && static.AccessKeyID == accessKeyID):
+		return &s3Client{config: p.s3, credentials: *static}, nil
+	}
+
+	credentials, err := p.keyCredentials(ctx, accessKeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Client{config: p.s3, credentials: credentials}, nil
+}
+
+// keyCredentials returns the credentials of the key accessKeyID, whose
+// secret is read through the admin API.
+func (p *garageProvider) keyCredentials(ctx context.Context, accessKeyID string) (s3Credentials, error) {
+	keyInfo, err := p.client.GetKey(ctx, accessKeyID)
+	if err != nil {
+		return s3Credentials{}, fmt.Errorf("failed to read the secret of key %s: %w", accessKeyID, err)
+	}
+	if keyInfo.SecretAccessKey == "" {
+		return s3Credentials{}, fmt.Errorf("the admin API didn't return the secret of key %s", accessKeyID)
+	}
+
+	return s3Credentials{
+		AccessKeyID:     keyInfo.AccessKeyID,
+		SecretAccessKey: keyInfo.SecretAccessKey,
+	}, nil
+}
+
+// requestURL returns the URL of key in bucket, addressing the bucket in the
+// path or as a subdomain of the endpoint.
+func (c *s3Client) requestURL(bucket string, key string, query url.Values) *url.URL {
+	requestURL := *c.config.endpoint
+	path := requestURL.Path
+	if bucket != "" {
+		if c.config.pathStyle {
+			path += "/" + bucket
+		} else {
+			requestURL.Host = bucket + "." + requestURL.Host
+		}
+	}
+	if key != "" {
+		path += "/" + key
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	requestURL.Path = path
+	requestURL.RawPath = sigV4Escape(path, false)
+	requestURL.RawQuery = canonicalQuery(query)
+	return &requestURL
+}
+
+// do sends r, signed, and returns the answer along with its body.
+func (c *s3Client) do(ctx context.Context, r s3Request) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, r.Method, c.requestURL(r.Bucket, r.Key, r.Query).String(), bytes.NewReader(r.Body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range r.Header {
+		req.Header[name] = values
+	}
+	req.ContentLength = int64(len(r.Body))
+	payloadHash := sha256Hex(r.Body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := signV4(ctx, req, c.credentials, c.config.region, "s3", payloadHash, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	httpResp, err := c.config.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer httpResp.Body.Close()
+
+	content, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return httpResp, nil, err
+	}
+
+	if httpResp.StatusCode >= 300 {
+		apiErr := &s3Error{}
+		_ = xml.Unmarshal(content, apiErr)
+		apiErr.StatusCode = httpResp.StatusCode
+		if apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(content))
+		}
+		return httpResp, content, apiErr
+	}
+
+	return httpResp, content, nil
+}
+
+// call sends r and decodes the XML answer into out, if any.
+func (c *s3Client) call(ctx context.Context, r s3Request, out interface{}) (*http.Response, error) {
+	httpResp, content, err := c.do(ctx, r)
+	if err != nil {
+		return httpResp, err
+	}
+
+	if out != nil && len(bytes.TrimSpace(content)) > 0 {
+		if err := xml.Unmarshal(content, out); err != nil {
+			return httpResp, fmt.Errorf("failed to decode S3 API answer: %w", err)
+		}
+	}
+
+	return httpResp, nil
+}
+
+// isS3NotFound reports whether err is the S3 API answering that the bucket,
+// object or configuration doesn't exist.
+func isS3NotFound(err error) bool {
+	var apiErr *s3Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// withS3Bucket adds to the schema of a resource going through the S3 API the
+// bucket it manages and the key signing its requests.
+func withS3Bucket(resourceSchema map[string]*schema.Schema) map[string]*schema.Schema {
+	resourceSchema["bucket"] = &schema.Schema{
+		Description: "ID or global alias of the bucket. Buckets without global alias are addressed by a local alias of the signing key.",
+		Type:        schema.TypeString,
+		Required:    true,
+		ForceNew:    true,
+	}
+	resourceSchema["access_key_id"] = &schema.Schema{
+		Description: "ID of the key signing requests to the S3 API, typically of a `garage_key` managed in the same configuration, which needs permissions on the bucket. Defaults to `s3_access_key_id` of the provider.",
+		Type:        schema.TypeString,
+		Optional:    true,
+	}
+	return resourceSchema
+}
+
+var bucketIDRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// s3Bucket returns a client of the S3 API signing requests with the key
+// accessKeyID, as s3Client does, along with the name addressing bucket, a
+// bucket ID or alias, through it.
+func (p *garageProvider) s3Bucket(ctx context.Context, bucket string, accessKeyID string) (*s3Client, string, error) {
+	client, err := p.s3Client(ctx, accessKeyID)
+	if err != nil {
+		return nil, "", err
+	}
+	bucketName, err := p.bucketName(ctx, bucket, client.credentials.AccessKeyID)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, bucketName, nil
+}
+
+// bucketName returns the name addressing bucket, a bucket ID or alias, in
+// requests signed with the key accessKeyID. Buckets given by ID are
+// addressed by their first global alias, or else by a local alias of the key.
+func (p *garageProvider) bucketName(ctx context.Context, bucket string, accessKeyID string) (string, error) {
+	if !bucketIDRegexp.MatchString(bucket) {
+		return bucket, nil
+	}
+
+	bucketInfo, err := p.client.GetBucket(ctx, bucket)
+	if err != nil {
+		return "", err
+	}
+	if len(bucketInfo.GlobalAliases) > 0 {
+		return bucketInfo.GlobalAliases[0], nil
+	}
+	if bucketKey := findBucketKey(bucketInfo, accessKeyID); bucketKey != nil && len(bucketKey.BucketLocalAliases) > 0 {
+		return bucketKey.BucketLocalAliases[0], nil
+	}
+
+	return "", fmt.Errorf("bucket %s has neither a global alias nor a local alias of key %s to address it", bucket, accessKeyID)
+}
+
+// isBucketOrObjectNotFound reports whether err is either API answering that
+// the bucket or object doesn't exist.
+func isBucketOrObjectNotFound(err error) bool {
+	return isNotFound(err) || isS3NotFound(err)
+}
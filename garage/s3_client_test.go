@@ -0,0 +1,269 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const sigV4TimeFormat = "20060102T150405Z"
+
+var sigV4AuthorizationRegexp = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/\d{8}/([^/]+)/([^/]+)/aws4_request, SignedHeaders=([^,]+), Signature=[0-9a-f]{64}$`)
+
+// verifySigV4 checks the signature of r, received by a fake server, against
+// the secrets of the keys, returning the access key ID it was signed with.
+// The body of r is read and replaced.
+func verifySigV4(r *http.Request, service string, secrets func(accessKeyID string) (string, bool)) (string, error) {
+	authorization := r.Header.Get("Authorization")
+	match := sigV4AuthorizationRegexp.FindStringSubmatch(authorization)
+	if match == nil {
+		return "", fmt.Errorf("malformed Authorization header %q", authorization)
+	}
+	accessKeyID, region, signedService := match[1], match[2], match[3]
+	if signedService != service {
+		return "", fmt.Errorf("request signed for %s instead of %s", signedService, service)
+	}
+	secretAccessKey, ok := secrets(accessKeyID)
+	if !ok {
+		return "", fmt.Errorf("unknown access key %s", accessKeyID)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != sha256Hex(body) {
+		return "", fmt.Errorf("payload hash %q doesn't match the body", payloadHash)
+	}
+
+	date, err := time.Parse(sigV4TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return "", fmt.Errorf("invalid X-Amz-Date: %w", err)
+	}
+
+	signed := &http.Request{Method: r.Method, URL: r.URL, Host: r.Host, Header: http.Header{}}
+	for _, name := range strings.Split(match[4], ";") {
+		switch name {
+		case "host":
+		case "content-length":
+			signed.ContentLength = r.ContentLength
+		default:
+			signed.Header[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+		}
+	}
+	if err := signV4(r.Context(), signed, s3Credentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey}, region, service, payloadHash, date); err != nil {
+		return "", err
+	}
+	if signed.Header.Get("Authorization") != authorization {
+		return "", fmt.Errorf("signature mismatch")
+	}
+
+	return accessKeyID, nil
+}
+
+func TestS3ClientRequestURL(t *testing.T) {
+	endpoint, _ := url.Parse("https://s3.example/prefix")
+	client := &s3Client{config: &s3Config{endpoint: endpoint, pathStyle: true}}
+
+	for _, c := range []struct {
+		pathStyle bool
+		bucket    string
+		key       string
+		query     url.Values
+		expected  string
+	}{
+		{true, "", "", nil, "https://s3.example/prefix"},
+		{true, "website", "", url.Values{"list-type": {"2"}, "prefix": {"a b"}}, "https://s3.example/prefix/website?list-type=2&prefix=a%20b"},
+		{true, "website", "dir/a b+c.txt", nil, "https://s3.example/prefix/website/dir/a%20b%2Bc.txt"},
+		{false, "website", "index.html", url.Values{"cors": {""}}, "https://website.s3.example/prefix/index.html?cors="},
+	} {
+		client.config.pathStyle = c.pathStyle
+		if requestURL := client.requestURL(c.bucket, c.key, c.query).String(); requestURL != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, requestURL)
+		}
+	}
+}
+
+func TestS3ClientCall(t *testing.T) {
+	credentials := s3Credentials{AccessKeyID: "GK" + randomHex(24), SecretAccessKey: randomHex(64)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := verifySigV4(r, "s3", func(accessKeyID string) (string, bool) {
+			return credentials.SecretAccessKey, accessKeyID == credentials.AccessKeyID
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, "<Error><Code>AccessDenied</Code><Message>%s</Message></Error>", err)
+			return
+		}
+		if r.URL.Path == "/website/missing key" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>Key not found</Message></Error>")
+			return
+		}
+		fmt.Fprint(w, "<ListAllMyBucketsResult><Buckets><Bucket><Name>website</Name></Bucket></Buckets></ListAllMyBucketsResult>")
+	}))
+	t.Cleanup(server.Close)
+
+	endpoint, _ := url.Parse(server.URL)
+	config := &s3Config{endpoint: endpoint, region: defaultS3Region, pathStyle: true, httpClient: server.Client()}
+	client := &s3Client{config: config, credentials: credentials}
+	ctx := context.Background()
+
+	var out struct {
+		Buckets []string `xml:"Buckets>Bucket>Name"`
+	}
+	if _, err := client.call(ctx, s3Request{Method: http.MethodGet}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Buckets) != 1 || out.Buckets[0] != "website" {
+		t.Errorf("unexpected buckets: %+v", out.Buckets)
+	}
+
+	_, err := client.call(ctx, s3Request{
+		Method: http.MethodPut,
+		Bucket: "website",
+		Key:    "robots.txt",
+		Header: http.Header{"Content-Type": {"text/plain"}, "X-Amz-Meta-Owner": {"ops"}},
+		Body:   []byte("User-agent: *\n"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.call(ctx, s3Request{Method: http.MethodGet, Bucket: "website", Key: "missing key"}, nil)
+	if !isS3NotFound(err) || err.(*s3Error).Code != "NoSuchKey" {
+		t.Errorf("expected a NoSuchKey error, got %v", err)
+	}
+
+	client.credentials.SecretAccessKey = randomHex(64)
+	_, err = client.call(ctx, s3Request{Method: http.MethodGet}, nil)
+	if apiErr, ok := err.(*s3Error); !ok || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected the wrong secret to be rejected, got %v", err)
+	}
+}
+
+func TestNewS3Config(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "garage.toml")
+	err := os.WriteFile(configFile, []byte("[s3_api]\ns3_region = \"eu-west\"\napi_bind_addr = \"[::]:3900\"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		raw      map[string]interface{}
+		endpoint string
+		region   string
+		err      string
+	}{
+		{raw: map[string]interface{}{}},
+		{raw: map[string]interface{}{"s3_endpoint": "https://s3.example/"}, endpoint: "https://s3.example", region: defaultS3Region},
+		{raw: map[string]interface{}{"config_file": configFile}, endpoint: "http://127.0.0.1:3900", region: "eu-west"},
+		{raw: map[string]interface{}{"config_file": configFile, "s3_region": "garage"}, endpoint: "http://127.0.0.1:3900", region: "garage"},
+		{raw: map[string]interface{}{"s3_endpoint": "https://s3.example", "s3_access_key_id": "GK1"}, err: "must be set together"},
+		{raw: map[string]interface{}{"s3_access_key_id": "GK1", "s3_secret_access_key": "secret"}, err: "set s3_endpoint"},
+	} {
+		d := schema.TestResourceDataRaw(t, Provider().Schema, c.raw)
+		config, err := newS3Config(d, http.DefaultClient)
+		switch {
+		case c.err != "":
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%v: expected an error containing %q, got %v", c.raw, c.err, err)
+			}
+		case err != nil:
+			t.Errorf("%v: %v", c.raw, err)
+		case c.endpoint == "":
+			if config != nil {
+				t.Errorf("%v: expected no S3 configuration, got %+v", c.raw, config)
+			}
+		case config == nil:
+			t.Errorf("%v: expected an S3 configuration", c.raw)
+		default:
+			if config.endpoint.String() != c.endpoint || config.region != c.region || !config.pathStyle {
+				t.Errorf("%v: unexpected S3 configuration %s %s %t", c.raw, config.endpoint, config.region, config.pathStyle)
+			}
+		}
+	}
+}
+
+func TestProviderS3Transport(t *testing.T) {
+	provider := Provider()
+	diags := provider.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"endpoint":                "https://admin.example",
+		"token":                   fakeAdminToken,
+		"skip_connectivity_check": true,
+		"proxy_url":               "http://proxy.example:3128",
+		"insecure_skip_verify":    true,
+		"s3_endpoint":             "https://s3.example",
+		"k2v_endpoint":            "https://k2v.example",
+	}))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	p := provider.Meta().(*garageProvider)
+
+	for name, httpClient := range map[string]*http.Client{"S3": p.s3.httpClient, "K2V": p.k2v.httpClient} {
+		transport := httpClient.Transport.(*http.Transport)
+		if transport.TLSClientConfig != nil && transport.TLSClientConfig.InsecureSkipVerify {
+			t.Errorf("%s: expected the TLS settings of the admin API to be left out", name)
+		}
+		proxy, err := transport.Proxy(httptest.NewRequest(http.MethodGet, "https://s3.example/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proxy != nil && proxy.Host == "proxy.example:3128" {
+			t.Errorf("%s: expected proxy_url to be left out", name)
+		}
+	}
+}
+
+func TestProviderS3Client(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	endpoint, _ := url.Parse("https://s3.example")
+	p := &garageProvider{client: fake.client(), s3: &s3Config{endpoint: endpoint}}
+
+	if _, err := (&garageProvider{client: fake.client()}).s3Client(ctx, ""); err == nil {
+		t.Errorf("expected an error without S3 endpoint")
+	}
+	if _, err := p.s3Client(ctx, ""); err == nil {
+		t.Errorf("expected an error without key")
+	}
+
+	key, err := p.client.CreateKey(ctx, "s3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := p.s3Client(ctx, key.AccessKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.credentials != (s3Credentials{AccessKeyID: key.AccessKeyID, SecretAccessKey: key.SecretAccessKey}) {
+		t.Errorf("expected the credentials of the key, got %+v", client.credentials)
+	}
+	if _, err := p.s3Client(ctx, "GK000000000000000000000000"); err == nil {
+		t.Errorf("expected an error with a missing key")
+	}
+
+	p.s3.credentials = &s3Credentials{AccessKeyID: "GK" + randomHex(24), SecretAccessKey: randomHex(64)}
+	client, err = p.s3Client(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.credentials != *p.s3.credentials {
+		t.Errorf("expected the static credentials, got %+v", client.credentials)
+	}
+}
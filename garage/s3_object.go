@@ -0,0 +1,322 @@
+package garage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// s3PartSize is the size of the parts of multipart uploads. Larger sources
+// are uploaded in parts.
+const s3PartSize = 8 << 20
+
+// s3ObjectMetadata are the headers stored along with an object.
+type s3ObjectMetadata struct {
+	ContentType     string
+	CacheControl    string
+	ContentEncoding string
+	// Metadata is the user metadata, sent as X-Amz-Meta-* headers.
+	Metadata map[string]string
+}
+
+// s3Object is an object as described by a HEAD or GET request.
+type s3Object struct {
+	s3ObjectMetadata
+	ETag          string
+	ContentLength int64
+	LastModified  string
+}
+
+func (m s3ObjectMetadata) header() http.Header {
+	header := http.Header{}
+	if m.ContentType != "" {
+		header.Set("Content-Type", m.ContentType)
+	}
+	if m.CacheControl != "" {
+		header.Set("Cache-Control", m.CacheControl)
+	}
+	if m.ContentEncoding != "" {
+		header.Set("Content-Encoding", m.ContentEncoding)
+	}
+	for name, value := range m.Metadata {
+		header.Set("X-Amz-Meta-"+name, value)
+	}
+	return header
+}
+
+func objectFromHeader(header http.Header) *s3Object {
+	object := &s3Object{
+		s3ObjectMetadata: s3ObjectMetadata{
+			ContentType:     header.Get("Content-Type"),
+			CacheControl:    header.Get("Cache-Control"),
+			ContentEncoding: header.Get("Content-Encoding"),
+			Metadata:        map[string]string{},
+		},
+		ETag:         unquoteETag(header.Get("ETag")),
+		LastModified: header.Get("Last-Modified"),
+	}
+	object.ContentLength, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	for name, values := range header {
+		if metadataName := strings.TrimPrefix(strings.ToLower(name), "x-amz-meta-"); metadataName != strings.ToLower(name) && len(values) > 0 {
+			object.Metadata[metadataName] = values[0]
+		}
+	}
+	return object
+}
+
+func unquoteETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+// contentETag returns the ETag Garage gives an object uploaded at once.
+func contentETag(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileDigest returns the hex-encoded SHA-256 of the file at path, and the
+// ETag Garage gives it once uploaded by PutObjectFile when it is uploaded at
+// once. The ETag of files uploaded in parts depends on how they are split, so
+// it is left empty for them.
+func fileDigest(path string) (string, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	sum := md5.New()
+	size, err := io.Copy(io.MultiWriter(hash, sum), file)
+	if err != nil {
+		return "", "", err
+	}
+
+	etag := ""
+	if size <= s3PartSize {
+		etag = hex.EncodeToString(sum.Sum(nil))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), etag, nil
+}
+
+// HeadObject describes key without fetching its content.
+func (c *s3Client) HeadObject(ctx context.Context, bucket string, key string) (*s3Object, error) {
+	httpResp, _, err := c.do(ctx, s3Request{Method: http.MethodHead, Bucket: bucket, Key: key})
+	if err != nil {
+		return nil, err
+	}
+	return objectFromHeader(httpResp.Header), nil
+}
+
+// GetObject fetches key along with its content.
+func (c *s3Client) GetObject(ctx context.Context, bucket string, key string) (*s3Object, []byte, error) {
+	// Asking for the identity encoding stops Go from asking for gzip, then
+	// decompressing objects stored gzip-encoded and dropping their
+	// Content-Encoding and Content-Length.
+	header := http.Header{"Accept-Encoding": {"identity"}}
+	httpResp, content, err := c.do(ctx, s3Request{Method: http.MethodGet, Bucket: bucket, Key: key, Header: header})
+	if err != nil {
+		return nil, nil, err
+	}
+	return objectFromHeader(httpResp.Header), content, nil
+}
+
+// s3ObjectListItem is an object as listed by ListObjectsV2.
+type s3ObjectListItem struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+}
+
+type listBucketResult struct {
+	Contents       []s3ObjectListItem `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListObjects lists the objects of bucket under prefix, rolled up into common
+// prefixes up to the first delimiter after it when delimiter is set. At most
+// maxKeys objects and common prefixes are returned, or all of them when
+// maxKeys is 0.
+func (c *s3Client) ListObjects(ctx context.Context, bucket string, prefix string, delimiter string, maxKeys int) ([]s3ObjectListItem, []string, error) {
+	var objects []s3ObjectListItem
+	var commonPrefixes []string
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if maxKeys > 0 {
+			query.Set("max-keys", strconv.Itoa(maxKeys-len(objects)-len(commonPrefixes)))
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		var page listBucketResult
+		_, err := c.call(ctx, s3Request{Method: http.MethodGet, Bucket: bucket, Query: query}, &page)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, object := range page.Contents {
+			object.ETag = unquoteETag(object.ETag)
+			objects = append(objects, object)
+		}
+		for _, commonPrefix := range page.CommonPrefixes {
+			commonPrefixes = append(commonPrefixes, commonPrefix.Prefix)
+		}
+
+		if !page.IsTruncated || (maxKeys > 0 && len(objects)+len(commonPrefixes) >= maxKeys) {
+			return objects, commonPrefixes, nil
+		}
+		continuationToken = page.NextContinuationToken
+	}
+}
+
+// PutObject uploads content at once, returning its ETag.
+func (c *s3Client) PutObject(ctx context.Context, bucket string, key string, metadata s3ObjectMetadata, content []byte) (string, error) {
+	httpResp, err := c.call(ctx, s3Request{
+		Method: http.MethodPut,
+		Bucket: bucket,
+		Key:    key,
+		Header: metadata.header(),
+		Body:   content,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return unquoteETag(httpResp.Header.Get("ETag")), nil
+}
+
+// PutObjectFile uploads the file at path, in parts when it is larger than
+// s3PartSize, returning its ETag.
+func (c *s3Client) PutObjectFile(ctx context.Context, bucket string, key string, metadata s3ObjectMetadata, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() <= s3PartSize {
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return "", err
+		}
+		return c.PutObject(ctx, bucket, key, metadata, content)
+	}
+
+	return c.putObjectMultipart(ctx, bucket, key, metadata, file)
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// putObjectMultipart uploads content in parts of s3PartSize, aborting the
+// upload when a part fails so that it doesn't linger as an unfinished upload.
+func (c *s3Client) putObjectMultipart(ctx context.Context, bucket string, key string, metadata s3ObjectMetadata, content io.Reader) (string, error) {
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	_, err := c.call(ctx, s3Request{
+		Method: http.MethodPost,
+		Bucket: bucket,
+		Key:    key,
+		Query:  url.Values{"uploads": {""}},
+		Header: metadata.header(),
+	}, &initiated)
+	if err != nil {
+		return "", err
+	}
+
+	etag, err := c.uploadParts(ctx, bucket, key, initiated.UploadID, content)
+	if err != nil {
+		_, _ = c.call(ctx, s3Request{
+			Method: http.MethodDelete,
+			Bucket: bucket,
+			Key:    key,
+			Query:  url.Values{"uploadId": {initiated.UploadID}},
+		}, nil)
+		return "", err
+	}
+	return etag, nil
+}
+
+func (c *s3Client) uploadParts(ctx context.Context, bucket string, key string, uploadID string, content io.Reader) (string, error) {
+	complete := completeMultipartUpload{}
+	part := make([]byte, s3PartSize)
+	for partNumber := 1; ; partNumber++ {
+		n, err := io.ReadFull(content, part)
+		if n == 0 && partNumber > 1 {
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+
+		httpResp, err := c.call(ctx, s3Request{
+			Method: http.MethodPut,
+			Bucket: bucket,
+			Key:    key,
+			Query:  url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}},
+			Body:   part[:n],
+		}, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+		}
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: partNumber, ETag: httpResp.Header.Get("ETag")})
+
+		if n < s3PartSize {
+			break
+		}
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return "", err
+	}
+	var completed struct {
+		ETag string `xml:"ETag"`
+	}
+	_, err = c.call(ctx, s3Request{
+		Method: http.MethodPost,
+		Bucket: bucket,
+		Key:    key,
+		Query:  url.Values{"uploadId": {uploadID}},
+		Body:   body,
+	}, &completed)
+	if err != nil {
+		return "", err
+	}
+	return unquoteETag(completed.ETag), nil
+}
+
+// DeleteObject deletes key, which succeeds when it doesn't exist.
+func (c *s3Client) DeleteObject(ctx context.Context, bucket string, key string) error {
+	_, err := c.call(ctx, s3Request{Method: http.MethodDelete, Bucket: bucket, Key: key}, nil)
+	return err
+}
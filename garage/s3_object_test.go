@@ -0,0 +1,249 @@
+package garage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFakeS3Bucket creates a bucket with a global alias on the fake node and
+// returns a client of its S3 API signing with a key allowed to read and write
+// to it.
+func newFakeS3Bucket(t *testing.T, fake *fakeGarage, alias string) *s3Client {
+	t.Helper()
+	ctx := context.Background()
+	client := fake.client()
+
+	bucketInfo, err := client.CreateBucket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddBucketGlobalAlias(ctx, bucketInfo.ID, alias); err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.CreateKey(ctx, alias)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.GrantKey(ctx, bucketInfo.ID, key.AccessKeyID, bucketKeyPermissions{Read: true, Write: true, Owner: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, _ := url.Parse(fake.s3.URL)
+	return &s3Client{
+		config: &s3Config{
+			endpoint:   endpoint,
+			region:     defaultS3Region,
+			pathStyle:  true,
+			httpClient: fake.s3.Client(),
+		},
+		credentials: s3Credentials{AccessKeyID: key.AccessKeyID, SecretAccessKey: key.SecretAccessKey},
+	}
+}
+
+func TestS3ClientObject(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "objects")
+	ctx := context.Background()
+
+	metadata := s3ObjectMetadata{
+		ContentType:  "text/plain",
+		CacheControl: "no-cache",
+		Metadata:     map[string]string{"owner": "ops"},
+	}
+	etag, err := client.PutObject(ctx, "objects", "dir/hello world.txt", metadata, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag != contentETag([]byte("hello")) {
+		t.Errorf("unexpected ETag %s", etag)
+	}
+
+	object, err := client.HeadObject(ctx, "objects", "dir/hello world.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.ETag != etag || object.ContentLength != 5 || object.ContentType != "text/plain" || object.CacheControl != "no-cache" || object.Metadata["owner"] != "ops" {
+		t.Errorf("unexpected object %+v", object)
+	}
+
+	if err := client.DeleteObject(ctx, "objects", "dir/hello world.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.HeadObject(ctx, "objects", "dir/hello world.txt"); !isS3NotFound(err) {
+		t.Errorf("expected the object to be deleted, got %v", err)
+	}
+	if err := client.DeleteObject(ctx, "objects", "dir/hello world.txt"); err != nil {
+		t.Errorf("expected deleting a missing object to succeed, got %v", err)
+	}
+	if _, err := client.HeadObject(ctx, "missing", "hello.txt"); !isS3NotFound(err) {
+		t.Errorf("expected a missing bucket to be not found, got %v", err)
+	}
+}
+
+func TestS3ClientListObjects(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "objects")
+	ctx := context.Background()
+
+	keys := []string{"a.txt", "dir/b.txt", "dir/c.txt", "dir/sub/d.txt", "dir/sub/e.txt", "z.txt"}
+	for _, key := range keys {
+		if _, err := client.PutObject(ctx, "objects", key, s3ObjectMetadata{}, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct {
+		prefix         string
+		delimiter      string
+		maxKeys        int
+		keys           []string
+		commonPrefixes []string
+	}{
+		{"", "", 0, keys, nil},
+		{"dir/", "", 0, keys[1:5], nil},
+		{"dir/", "/", 0, keys[1:3], []string{"dir/sub/"}},
+		{"", "/", 0, []string{"a.txt", "z.txt"}, []string{"dir/"}},
+		{"", "", 2, keys[:2], nil},
+		{"dir/", "/", 2, keys[1:3], nil},
+		{"missing/", "", 0, nil, nil},
+	} {
+		objects, commonPrefixes, err := client.ListObjects(ctx, "objects", c.prefix, c.delimiter, c.maxKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listed := []string{}
+		for _, object := range objects {
+			listed = append(listed, object.Key)
+			if object.ETag != contentETag([]byte(object.Key)) || object.Size != int64(len(object.Key)) {
+				t.Errorf("unexpected object %+v", object)
+			}
+		}
+		if strings.Join(listed, ",") != strings.Join(c.keys, ",") || strings.Join(commonPrefixes, ",") != strings.Join(c.commonPrefixes, ",") {
+			t.Errorf("%q %q %d: unexpected keys %v and common prefixes %v", c.prefix, c.delimiter, c.maxKeys, listed, commonPrefixes)
+		}
+	}
+
+	// More objects than fit in a page
+	fake.mu.Lock()
+	for _, b := range fake.buckets {
+		for i := 0; i < 1500; i++ {
+			fake.storeObject(b, fmt.Sprintf("many/%04d", i), s3ObjectMetadata{}, nil, contentETag(nil))
+		}
+	}
+	fake.mu.Unlock()
+	for maxKeys, expected := range map[int]int{0: 1500, 1200: 1200} {
+		objects, _, err := client.ListObjects(ctx, "objects", "many/", "", maxKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != expected || objects[len(objects)-1].Key != fmt.Sprintf("many/%04d", expected-1) {
+			t.Errorf("max %d keys: expected %d objects, got %d", maxKeys, expected, len(objects))
+		}
+	}
+
+	_, content, err := client.GetObject(ctx, "objects", "dir/sub/d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "dir/sub/d.txt" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+func TestS3ClientObjectFile(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	client := newFakeS3Bucket(t, fake, "objects")
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	for _, size := range []int{0, 10, s3PartSize, s3PartSize + 1, 2 * s3PartSize} {
+		path := filepath.Join(dir, "source")
+		content := bytes.Repeat([]byte("x"), size)
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := contentETag(content)
+		if size > s3PartSize {
+			expected = contentETag([]byte(contentETag(content[:s3PartSize])+contentETag(content[s3PartSize:]))) + "-2"
+		}
+		hash, singlePartETag, err := fileDigest(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != sha256Hex(content) {
+			t.Errorf("%d bytes: unexpected hash %s", size, hash)
+		}
+		if size <= s3PartSize && singlePartETag != expected || size > s3PartSize && singlePartETag != "" {
+			t.Errorf("%d bytes: unexpected ETag %q", size, singlePartETag)
+		}
+		etag, err := client.PutObjectFile(ctx, "objects", "source", s3ObjectMetadata{}, path)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if etag != expected {
+			t.Errorf("%d bytes: expected ETag %s, got %s", size, expected, etag)
+		}
+		if multipart := strings.Contains(etag, "-"); multipart != (size > s3PartSize) {
+			t.Errorf("%d bytes: unexpected ETag %s", size, etag)
+		}
+
+		object, err := client.HeadObject(ctx, "objects", "source")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if object.ContentLength != int64(size) || object.ETag != etag {
+			t.Errorf("%d bytes: unexpected object %+v", size, object)
+		}
+	}
+	if len(fake.uploads) != 0 {
+		t.Errorf("expected no unfinished upload, got %d", len(fake.uploads))
+	}
+}
+
+func TestS3Bucket(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	ctx := context.Background()
+	client := fake.client()
+	endpoint, _ := url.Parse(fake.s3.URL)
+	p := &garageProvider{client: client, s3: &s3Config{endpoint: endpoint, httpClient: http.DefaultClient}}
+
+	bucketInfo, err := client.CreateBucket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.CreateKey(ctx, "s3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, name, err := p.s3Bucket(ctx, "website", key.AccessKeyID); err != nil || name != "website" {
+		t.Errorf("expected aliases to be used as is, got %q, %v", name, err)
+	}
+	if _, _, err := p.s3Bucket(ctx, bucketInfo.ID, key.AccessKeyID); err == nil {
+		t.Errorf("expected a bucket without alias to fail")
+	}
+	if _, _, err := p.s3Bucket(ctx, strings.Repeat("0", 64), key.AccessKeyID); !isNotFound(err) {
+		t.Errorf("expected a missing bucket to be not found, got %v", err)
+	}
+
+	if err := client.AddBucketLocalAlias(ctx, bucketInfo.ID, key.AccessKeyID, "mine"); err != nil {
+		t.Fatal(err)
+	}
+	if _, name, err := p.s3Bucket(ctx, bucketInfo.ID, key.AccessKeyID); err != nil || name != "mine" {
+		t.Errorf("expected the local alias of the key, got %q, %v", name, err)
+	}
+	if err := client.AddBucketGlobalAlias(ctx, bucketInfo.ID, "website"); err != nil {
+		t.Fatal(err)
+	}
+	if _, name, err := p.s3Bucket(ctx, bucketInfo.ID, key.AccessKeyID); err != nil || name != "website" {
+		t.Errorf("expected the global alias, got %q, %v", name, err)
+	}
+}
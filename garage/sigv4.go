@@ -0,0 +1,84 @@
+package garage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// s3Credentials are the access key ID and secret of a Garage key, used to
+// sign requests to the S3 and K2V APIs.
+type s3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of content, as used for payload
+// hashes.
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// signV4 signs req with AWS Signature Version 4 for service in region, at
+// time now. payloadHash is the hex-encoded SHA-256 of the body. The host and
+// the headers set beforehand are signed. The path of req is escaped already,
+// as requestURL does. Signers cache signing keys by access key ID regardless
+// of the secret, so each request gets its own.
+func signV4(ctx context.Context, req *http.Request, credentials s3Credentials, region string, service string, payloadHash string, now time.Time) error {
+	signer := v4.NewSigner(func(options *v4.SignerOptions) {
+		options.DisableURIPathEscaping = true
+	})
+	return signer.SignHTTP(ctx, aws.Credentials{
+		AccessKeyID:     credentials.AccessKeyID,
+		SecretAccessKey: credentials.SecretAccessKey,
+	}, req, payloadHash, service, region, now)
+}
+
+// canonicalQuery encodes query sorted by name then value, escaping spaces as
+// %20 rather than +.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, sigV4Escape(name, true)+"="+sigV4Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// sigV4Escape percent-encodes every byte of s but unreserved characters and,
+// unless escapeSlash is set, slashes.
+func sigV4Escape(s string, escapeSlash bool) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			escaped.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			escaped.WriteByte(c)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
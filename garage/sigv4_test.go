@@ -0,0 +1,45 @@
+package garage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	// get-vanilla of the AWS Signature Version 4 test suite.
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := s3Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	if err := signV4(context.Background(), req, credentials, "us-east-1", "service", sha256Hex(nil), time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if authorization := req.Header.Get("Authorization"); authorization != expected {
+		t.Errorf("unexpected Authorization header:\n%s\nexpected:\n%s", authorization, expected)
+	}
+	if date := req.Header.Get("X-Amz-Date"); date != "20150830T123600Z" {
+		t.Errorf("unexpected X-Amz-Date header %q", date)
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	query := url.Values{
+		"prefix":    {"a b/c"},
+		"list-type": {"2"},
+		"uploads":   {""},
+		"marker":    {"z", "a+b"},
+	}
+	expected := "list-type=2&marker=a%2Bb&marker=z&prefix=a%20b%2Fc&uploads="
+	if canonical := canonicalQuery(query); canonical != expected {
+		t.Errorf("expected %q, got %q", expected, canonical)
+	}
+}
@@ -0,0 +1,49 @@
+package garage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resolveToken returns the admin token from token, token_file or
+// token_command, in that order of precedence.
+func resolveToken(ctx context.Context, d *schema.ResourceData) (string, error) {
+	if token := d.Get("token").(string); token != "" {
+		return token, nil
+	}
+
+	if tokenFile := d.Get("token_file").(string); tokenFile != "" {
+		content, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token_file: %w", err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	if tokenCommand := d.Get("token_command").([]interface{}); len(tokenCommand) > 0 {
+		args := make([]string, len(tokenCommand))
+		for i, arg := range tokenCommand {
+			args[i], _ = arg.(string)
+		}
+		if args[0] == "" {
+			return "", fmt.Errorf("token_command must start with the program to run")
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("token_command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimSpace(stdout.String()), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,96 @@
+package garage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// bucketAliasErrors returns why Garage would reject alias, following the S3
+// rules for bucket names.
+func bucketAliasErrors(alias string) []string {
+	var errors []string
+
+	if len(alias) < 3 || len(alias) > 63 {
+		errors = append(errors, "must be between 3 and 63 characters long")
+	}
+	for _, c := range alias {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-') {
+			errors = append(errors, "must only contain lowercase letters, numbers, dots and dashes")
+			break
+		}
+	}
+	if strings.HasPrefix(alias, ".") || strings.HasPrefix(alias, "-") || strings.HasSuffix(alias, ".") || strings.HasSuffix(alias, "-") {
+		errors = append(errors, "must start and end with a letter or a number")
+	}
+	if net.ParseIP(alias) != nil {
+		errors = append(errors, "must not be formatted as an IP address")
+	}
+	if strings.HasPrefix(alias, "xn--") {
+		errors = append(errors, "must not start with `xn--`")
+	}
+	if strings.HasSuffix(alias, "-s3alias") {
+		errors = append(errors, "must not end with `-s3alias`")
+	}
+
+	return errors
+}
+
+// dnsCompatibleAliasErrors returns why alias can't be served as a website
+// at `<alias>.<root_domain>` under a wildcard certificate, on top of
+// bucketAliasErrors.
+func dnsCompatibleAliasErrors(alias string) []string {
+	errors := bucketAliasErrors(alias)
+	if strings.Contains(alias, ".") {
+		errors = append(errors, "must not contain dots, as it must be a single DNS label")
+	}
+	return errors
+}
+
+func aliasDiagnostics(alias string, errors []string, path cty.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, err := range errors {
+		diags = append(diags, diag.Diagnostic{
+			Severity:      diag.Error,
+			Summary:       "Invalid bucket alias",
+			Detail:        fmt.Sprintf("The bucket alias %q %s.", alias, err),
+			AttributePath: path,
+		})
+	}
+	return diags
+}
+
+// validateBucketAlias is a ValidateDiagFunc enforcing the rules of Garage on
+// bucket aliases.
+func validateBucketAlias(v interface{}, path cty.Path) diag.Diagnostics {
+	alias := v.(string)
+	return aliasDiagnostics(alias, bucketAliasErrors(alias), path)
+}
+
+// validateDNSCompatibleBucketAlias is a ValidateDiagFunc also requiring bucket
+// aliases to be single DNS labels, for buckets served through the domain of
+// the `[s3_web]` endpoint.
+func validateDNSCompatibleBucketAlias(v interface{}, path cty.Path) diag.Diagnostics {
+	alias := v.(string)
+	return aliasDiagnostics(alias, dnsCompatibleAliasErrors(alias), path)
+}
+
+// customizeDiffDNSCompatibleAlias fails the plan when alias isn't a single
+// DNS label while the provider requires it.
+func customizeDiffDNSCompatibleAlias(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	p, _ := m.(*garageProvider)
+	if p == nil || !p.dnsCompatibleAliases || !d.NewValueKnown("alias") {
+		return nil
+	}
+
+	diags := validateDNSCompatibleBucketAlias(d.Get("alias"), cty.GetAttrPath("alias"))
+	if diags.HasError() {
+		return fmt.Errorf("%s (dns_compatible_aliases is set on the provider)", diags[0].Detail)
+	}
+	return nil
+}
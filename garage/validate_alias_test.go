@@ -0,0 +1,60 @@
+package garage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestValidateBucketAlias(t *testing.T) {
+	cases := []struct {
+		alias         string
+		valid         bool
+		dnsCompatible bool
+	}{
+		{alias: "website", valid: true, dnsCompatible: true},
+		{alias: "my-bucket-2", valid: true, dnsCompatible: true},
+		{alias: "www.example.com", valid: true, dnsCompatible: false},
+		{alias: "ab", valid: false},
+		{alias: "a123456789012345678901234567890123456789012345678901234567890123", valid: false},
+		{alias: "MyBucket", valid: false},
+		{alias: "my_bucket", valid: false},
+		{alias: "-bucket", valid: false},
+		{alias: "bucket.", valid: false},
+		{alias: "192.168.1.1", valid: false},
+		{alias: "xn--bucket", valid: false},
+		{alias: "bucket-s3alias", valid: false},
+	}
+
+	for _, c := range cases {
+		diags := validateBucketAlias(c.alias, cty.GetAttrPath("alias"))
+		if diags.HasError() == c.valid {
+			t.Errorf("%q: expected valid to be %t, got %v", c.alias, c.valid, diags)
+		}
+
+		diags = validateDNSCompatibleBucketAlias(c.alias, cty.GetAttrPath("alias"))
+		if diags.HasError() == c.dnsCompatible {
+			t.Errorf("%q: expected DNS-compatible to be %t, got %v", c.alias, c.dnsCompatible, diags)
+		}
+	}
+}
+
+func TestCustomizeDiffDNSCompatibleAlias(t *testing.T) {
+	fake := newFakeGarage(t, 2)
+	p := &garageProvider{client: fake.client(), apiVersion: fake.apiVersion}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"bucket_id": "bucket",
+		"alias":     "www.example.com",
+	})
+
+	if _, err := resourceBucketGlobalAlias().Diff(context.Background(), nil, config, p); err != nil {
+		t.Errorf("expected dotted aliases to be planned by default, got %v", err)
+	}
+
+	p.dnsCompatibleAliases = true
+	if _, err := resourceBucketGlobalAlias().Diff(context.Background(), nil, config, p); err == nil {
+		t.Errorf("expected dotted aliases to fail the plan with dns_compatible_aliases")
+	}
+}
@@ -0,0 +1,158 @@
+package garage
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// Garage is eventually consistent: behind a load balancer, a read following a
+// write can reach a node that has not seen it yet. Reads done right after
+// creates and updates are therefore polled until the expected state shows up.
+
+const (
+	consistencyStatePending = "pending"
+	consistencyStateVisible = "visible"
+)
+
+func waitForConsistency(ctx context.Context, p *garageProvider, refresh resource.StateRefreshFunc) (interface{}, error) {
+	// Never wait past the deadline of the resource's timeouts.
+	timeout := p.consistencyTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{consistencyStatePending},
+		Target:     []string{consistencyStateVisible},
+		Refresh:    refresh,
+		Timeout:    timeout,
+		MinTimeout: 500 * time.Millisecond,
+	}
+
+	return stateConf.WaitForStateContext(ctx)
+}
+
+// waitForBucket polls the bucket until it exists and check accepts it.
+func waitForBucket(ctx context.Context, p *garageProvider, bucketID string, check func(*bucket) bool) (*bucket, error) {
+	result, err := waitForConsistency(ctx, p, func() (interface{}, string, error) {
+		bucketInfo, err := p.client.GetBucket(ctx, bucketID)
+		if isNotFound(err) {
+			return &bucket{}, consistencyStatePending, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if !check(bucketInfo) {
+			return bucketInfo, consistencyStatePending, nil
+		}
+		return bucketInfo, consistencyStateVisible, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*bucket), nil
+}
+
+// waitForKey polls the key until it exists and check accepts it.
+func waitForKey(ctx context.Context, p *garageProvider, accessKeyID string, check func(*accessKey) bool) (*accessKey, error) {
+	result, err := waitForConsistency(ctx, p, func() (interface{}, string, error) {
+		keyInfo, err := p.client.GetKey(ctx, accessKeyID)
+		if isNotFound(err) {
+			return &accessKey{}, consistencyStatePending, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if !check(keyInfo) {
+			return keyInfo, consistencyStatePending, nil
+		}
+		return keyInfo, consistencyStateVisible, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*accessKey), nil
+}
+
+func bucketExists(*bucket) bool {
+	return true
+}
+
+func keyExists(*accessKey) bool {
+	return true
+}
+
+func equalInt64Ptr(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// bucketUpdateApplied returns a check accepting a bucket once website, if
+// it was updated, and quotas are visible on it.
+func bucketUpdateApplied(website *bucketWebsite, quotas bucketQuotas) func(*bucket) bool {
+	return func(bucketInfo *bucket) bool {
+		if website != nil && bucketInfo.WebsiteAccess != website.Enabled {
+			return false
+		}
+		if website != nil && website.Enabled {
+			websiteConfig := bucketInfo.WebsiteConfig
+			if websiteConfig == nil {
+				return false
+			}
+			if website.IndexDocument != nil && websiteConfig.IndexDocument != *website.IndexDocument {
+				return false
+			}
+			if !equalStringPtr(websiteConfig.ErrorDocument, website.ErrorDocument) {
+				return false
+			}
+		}
+
+		if !equalInt64Ptr(bucketInfo.Quotas.MaxSize, quotas.MaxSize) {
+			return false
+		}
+		if !equalInt64Ptr(bucketInfo.Quotas.MaxObjects, quotas.MaxObjects) {
+			return false
+		}
+
+		return true
+	}
+}
+
+// keyUpdateApplied returns a check accepting a key once the name,
+// expiration and permissions of update are visible on it.
+func keyUpdateApplied(update keyUpdate) func(*accessKey) bool {
+	return func(keyInfo *accessKey) bool {
+		if update.Name != nil && keyInfo.Name != *update.Name {
+			return false
+		}
+		if update.Expiration != nil && (keyInfo.Expiration == nil || !sameInstant(*keyInfo.Expiration, *update.Expiration)) {
+			return false
+		}
+		if update.NeverExpires && keyInfo.Expiration != nil {
+			return false
+		}
+
+		createBucket := keyInfo.Permissions.CreateBucket
+		if update.Allow != nil && update.Allow.CreateBucket && !createBucket {
+			return false
+		}
+		if update.Deny != nil && update.Deny.CreateBucket && createBucket {
+			return false
+		}
+
+		return true
+	}
+}